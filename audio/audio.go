@@ -0,0 +1,245 @@
+// Package audio measures synthesized audio without decoding it. Durations are exact for every output format of
+// the text-to-speech service except the proprietary truesilk codec: sample based formats are measured from their
+// length, MP3 from its frame headers, Ogg and WebM Opus from their timestamps and packets, and AMR-WB from its
+// frame count. Opus audio can be moved between the Ogg and WebM containers with Remux, audio converted between
+// the formats that allow it locally with Convert, and PCM audio split at the bookmarks of its document with Split.
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/mp3"
+	"github.com/linexjlin/azuretexttospeech/audio/ogg"
+	"github.com/linexjlin/azuretexttospeech/audio/webm"
+)
+
+// counter measures the duration of a stream written to it incrementally.
+type counter interface {
+	io.Writer
+	Duration() time.Duration
+}
+
+func newCounter(format tts.AudioOutput) (counter, error) {
+	info := format.Info()
+	switch info.Container {
+	case tts.ContainerMP3:
+		return &mp3.Counter{}, nil
+	case tts.ContainerOgg:
+		return &ogg.Counter{}, nil
+	case tts.ContainerWebM:
+		return &webm.Counter{}, nil
+	case tts.ContainerAMR:
+		return &amrCounter{}, nil
+	case tts.ContainerRaw, tts.ContainerRIFF:
+		if info.Codec == tts.CodecTruesilk || info.Bitrate == 0 {
+			break
+		}
+		return &byteCounter{byteRate: int64(info.Bitrate / 8), riff: info.Container == tts.ContainerRIFF, limit: -1}, nil
+	}
+	return nil, fmt.Errorf("duration of %s audio cannot be determined", format)
+}
+
+// Duration returns the play time of audio synthesized in format.
+func Duration(audio []byte, format tts.AudioOutput) (time.Duration, error) {
+	switch format.Info().Container {
+	case tts.ContainerMP3:
+		return mp3.Duration(audio)
+	case tts.ContainerOgg:
+		return ogg.Duration(audio)
+	case tts.ContainerWebM:
+		return webm.Duration(audio)
+	}
+	c, err := newCounter(format)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := c.Write(audio); err != nil {
+		return 0, err
+	}
+	if bc, ok := c.(*byteCounter); ok && bc.riff {
+		return 0, fmt.Errorf("no data chunk")
+	}
+	return c.Duration(), nil
+}
+
+// DurationReader passes a stream of synthesized audio through while measuring its duration, so the length of a
+// streamed response is known once it has been consumed.
+type DurationReader struct {
+	r   io.Reader
+	c   counter
+	err error
+}
+
+// NewDurationReader returns a DurationReader reading audio in format from r.
+func NewDurationReader(r io.Reader, format tts.AudioOutput) (*DurationReader, error) {
+	c, err := newCounter(format)
+	if err != nil {
+		return nil, err
+	}
+	return &DurationReader{r: r, c: c}, nil
+}
+
+// Read reads from the underlying reader. Audio that cannot be parsed does not fail the read, see Err.
+func (d *DurationReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if n > 0 && d.err == nil {
+		_, d.err = d.c.Write(p[:n])
+	}
+	return n, err
+}
+
+// Duration returns the play time of the audio read so far.
+func (d *DurationReader) Duration() time.Duration {
+	return d.c.Duration()
+}
+
+// Err returns the error that stopped measuring the stream, if any.
+func (d *DurationReader) Err() error {
+	return d.err
+}
+
+// byteCounter measures constant bitrate audio, raw or wrapped in RIFF.
+type byteCounter struct {
+	byteRate int64
+	riff     bool   // the RIFF header has not been consumed yet
+	started  bool   // the RIFF/WAVE signature has been checked
+	buf      []byte // buffered RIFF chunk headers
+	skip     int64  // bytes of a chunk preceding the data chunk still to be skipped
+	limit    int64  // remaining size of the data chunk, -1 when it extends to the end of the stream
+	n        int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		if !c.riff {
+			c.count(len(p))
+			break
+		}
+		if c.skip > 0 {
+			k := int64(len(p))
+			if k > c.skip {
+				k = c.skip
+			}
+			p, c.skip = p[k:], c.skip-k
+			continue
+		}
+		c.buf = append(c.buf, p...)
+		p = nil
+		rest, found, err := c.parse()
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			c.riff, c.buf = false, nil
+			c.count(len(rest))
+		}
+	}
+	return total, nil
+}
+
+// parse consumes the buffered RIFF header and chunks preceding the data chunk. Once the data chunk header is
+// found it returns the sample data buffered after it.
+func (c *byteCounter) parse() (rest []byte, found bool, err error) {
+	if !c.started {
+		if len(c.buf) < 12 {
+			return nil, false, nil
+		}
+		if string(c.buf[:4]) != "RIFF" || string(c.buf[8:12]) != "WAVE" {
+			return nil, false, fmt.Errorf("not a RIFF/WAVE stream")
+		}
+		c.buf, c.started = c.buf[12:], true
+	}
+	for len(c.buf) >= 8 {
+		id := string(c.buf[:4])
+		size := int64(binary.LittleEndian.Uint32(c.buf[4:8]))
+		if id == "data" {
+			c.limit = size
+			if size == 0 || size == 0xFFFFFFFF {
+				c.limit = -1
+			}
+			return c.buf[8:], true, nil
+		}
+		padded := size + size%2
+		if int64(len(c.buf)-8) >= padded {
+			if id == "fmt " && size >= 12 {
+				if rate := int64(binary.LittleEndian.Uint32(c.buf[16:20])); rate > 0 {
+					c.byteRate = rate
+				}
+			}
+			c.buf = c.buf[8+padded:]
+			continue
+		}
+		if id == "fmt " && size <= 1024 {
+			// wait for the whole fmt chunk to read its average byte rate.
+			return nil, false, nil
+		}
+		c.skip = padded - int64(len(c.buf)-8)
+		c.buf = c.buf[:0]
+		return nil, false, nil
+	}
+	return nil, false, nil
+}
+
+func (c *byteCounter) count(n int) {
+	if c.limit >= 0 {
+		if int64(n) > c.limit {
+			n = int(c.limit)
+		}
+		c.limit -= int64(n)
+	}
+	c.n += int64(n)
+}
+
+func (c *byteCounter) Duration() time.Duration {
+	return time.Duration(c.n * int64(time.Second) / c.byteRate)
+}
+
+// amrFrameSizes is the size of an AMR-WB storage format frame, header byte included, for each frame type.
+// Reserved frame types are zero.
+var amrFrameSizes = [16]int{18, 24, 33, 37, 41, 47, 51, 59, 61, 6, 0, 0, 0, 0, 1, 1}
+
+// amrMagic starts every AMR-WB file.
+const amrMagic = "#!AMR-WB\n"
+
+// amrCounter measures AMR-WB audio in the storage format of RFC 4867, where every frame holds 20 ms.
+type amrCounter struct {
+	buf    []byte
+	magic  bool
+	frames int64
+}
+
+func (c *amrCounter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	if !c.magic {
+		if len(c.buf) < len(amrMagic) {
+			return len(p), nil
+		}
+		if string(c.buf[:len(amrMagic)]) != amrMagic {
+			return 0, fmt.Errorf("not an AMR-WB stream")
+		}
+		c.buf, c.magic = c.buf[len(amrMagic):], true
+	}
+	off := 0
+	for off < len(c.buf) {
+		size := amrFrameSizes[(c.buf[off]>>3)&0xf]
+		if size == 0 {
+			return 0, fmt.Errorf("invalid AMR-WB frame type %d", (c.buf[off]>>3)&0xf)
+		}
+		if len(c.buf)-off < size {
+			break
+		}
+		off += size
+		c.frames++
+	}
+	c.buf = append(c.buf[:0], c.buf[off:]...)
+	return len(p), nil
+}
+
+func (c *amrCounter) Duration() time.Duration {
+	return time.Duration(c.frames) * 20 * time.Millisecond
+}
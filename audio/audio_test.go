@@ -0,0 +1,167 @@
+package audio
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/ogg"
+	"github.com/linexjlin/azuretexttospeech/audio/opus"
+	"github.com/linexjlin/azuretexttospeech/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuration(t *testing.T) {
+	d, err := Duration(make([]byte, 48000), tts.RAW24khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, d)
+
+	d, err = Duration(make([]byte, 4000), tts.RAW8khz8bitMonoMulaw)
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, d)
+
+	d, err = Duration(make([]byte, 8000), tts.G722_16khz64kbps)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, d)
+
+	// the data chunk size is honoured when other chunks follow it.
+	riff, err := wav.Wrap(make([]byte, 32000), tts.RAW16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	d, err = Duration(append(riff, "LIST\x04\x00\x00\x00INFO"...), tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, d)
+
+	// the byte rate of the fmt chunk wins over the format passed in.
+	d, err = Duration(riff, tts.RIFF48khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, d)
+
+	amr := []byte("#!AMR-WB\n")
+	for i := 0; i < 10; i++ {
+		amr = append(amr, append([]byte{0x44}, make([]byte, 60)...)...) // 23.85 kbps frame
+		amr = append(amr, 0x7c)                                         // no data
+	}
+	d, err = Duration(amr, tts.AMRWB16000hz)
+	assert.NoError(t, err)
+	assert.Equal(t, 400*time.Millisecond, d)
+
+	_, err = Duration(make([]byte, 100), tts.RAW24khz16bitMonoTruesilk)
+	assert.Error(t, err)
+	_, err = Duration([]byte("RIFF\x00\x00\x00\x00WAVE"), tts.RIFF16khz16bitMonoPCM)
+	assert.Error(t, err, "no data chunk")
+	_, err = Duration(make([]byte, 100), tts.AUDIO24khz48kbitrateMonoMP3)
+	assert.Error(t, err)
+}
+
+func TestDurationReader(t *testing.T) {
+	// a streamed RIFF response, read one byte at a time.
+	riff, err := wav.Wrap(make([]byte, 24000), tts.RAW24khz16bitMonoPCM)
+	assert.NoError(t, err)
+	copy(riff[4:8], "\xff\xff\xff\xff")
+	copy(riff[40:44], "\xff\xff\xff\xff")
+
+	r, err := NewDurationReader(iotest.OneByteReader(bytes.NewReader(riff)), tts.RIFF24khz16bitMonoPCM)
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, riff, b)
+	assert.NoError(t, r.Err())
+	assert.Equal(t, 500*time.Millisecond, r.Duration())
+
+	// bytes that cannot be parsed are passed through regardless.
+	r, err = NewDurationReader(bytes.NewReader([]byte("not an ogg stream at all, really")), tts.OGG24khz16bitMonoOpus)
+	assert.NoError(t, err)
+	b, err = ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Len(t, b, 32)
+	assert.Error(t, r.Err())
+
+	_, err = NewDurationReader(bytes.NewReader(nil), tts.RAW16khz16bitMonoTruesilk)
+	assert.Error(t, err)
+}
+
+func TestRemux(t *testing.T) {
+	s := &opus.Stream{Head: &opus.Head{Version: 1, Channels: 1, PreSkip: 312, InputRate: 24000}, Discard: 100}
+	for i := 0; i < 100; i++ {
+		s.Packets = append(s.Packets, []byte{0xFC, byte(i)})
+	}
+	oggAudio := ogg.Encode(s, 7)
+
+	webmAudio, err := Remux(oggAudio, tts.OGG24khz16bitMonoOpus, tts.WEBM24khz16bitMonoOpus)
+	assert.NoError(t, err)
+	d, err := Duration(webmAudio, tts.WEBM24khz16bitMonoOpus)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Second-6500*time.Microsecond, d)
+
+	back, err := Remux(webmAudio, tts.WEBM24khz16bitMonoOpus, tts.OGG24khz16bitMonoOpus)
+	assert.NoError(t, err)
+	decoded, _, err := ogg.Decode(back)
+	assert.NoError(t, err)
+	assert.Equal(t, s.Packets, decoded.Packets)
+	assert.Equal(t, s.Discard, decoded.Discard)
+
+	_, err = Remux(oggAudio, tts.OGG24khz16bitMonoOpus, tts.AUDIO24khz96kbitrateMonoMP3)
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	riff, err := wav.Wrap(make([]byte, 32000), tts.RAW16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	mulaw, err := Convert(riff, tts.RIFF16khz16bitMonoPCM, tts.RAW8khz8bitMonoMulaw)
+	assert.NoError(t, err)
+	d, err := Duration(mulaw, tts.RAW8khz8bitMonoMulaw)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, d)
+
+	same, err := Convert(riff, tts.RIFF16khz16bitMonoPCM, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, riff, same)
+
+	s := &opus.Stream{Head: &opus.Head{Version: 1, Channels: 1, PreSkip: 312, InputRate: 24000}, Packets: [][]byte{{0xFC, 0}}}
+	_, err = Convert(ogg.Encode(s, 1), tts.OGG24khz16bitMonoOpus, tts.WEBM24khz16bitMonoOpus)
+	assert.NoError(t, err)
+
+	_, err = Convert(riff, tts.RIFF16khz16bitMonoPCM, tts.AUDIO16khz32kbitrateMonoMP3)
+	assert.Error(t, err, "no MP3 encoder")
+
+	assert.True(t, CanConvert(tts.RIFF16khz16bitMonoPCM, tts.RAW8khz8bitMonoMulaw))
+	assert.True(t, CanConvert(tts.OGG24khz16bitMonoOpus, tts.WEBM24khz16bitMonoOpus))
+	assert.True(t, CanConvert(tts.AUDIO16khz32kbitrateMonoMP3, tts.AUDIO16khz32kbitrateMonoMP3))
+	assert.False(t, CanConvert(tts.AUDIO16khz32kbitrateMonoMP3, tts.RIFF16khz16bitMonoPCM), "no MP3 decoder")
+}
+
+func TestSplit(t *testing.T) {
+	// one second of 16 kHz 16-bit audio, each sample holding its frame number.
+	raw := make([]byte, 32000)
+	for i := 0; i < 16000; i++ {
+		raw[2*i], raw[2*i+1] = byte(i), byte(i>>8)
+	}
+	events := []tts.Event{
+		{Type: tts.EventBookmark, Offset: 750 * time.Millisecond, Bookmark: "outro"},
+		{Type: tts.EventWordBoundary, Offset: 100 * time.Millisecond, Text: "Hello"},
+		{Type: tts.EventBookmark, Offset: 250 * time.Millisecond, Bookmark: "intro"},
+	}
+
+	segments, err := Split(raw, tts.RAW16khz16bitMonoPCM, events)
+	assert.NoError(t, err)
+	assert.Len(t, segments, 3)
+	assert.Equal(t, Segment{Audio: raw[:8000]}, segments[0])
+	assert.Equal(t, Segment{Name: "intro", Offset: 250 * time.Millisecond, Audio: raw[8000:24000]}, segments[1])
+	assert.Equal(t, Segment{Name: "outro", Offset: 750 * time.Millisecond, Audio: raw[24000:]}, segments[2])
+
+	riff, err := wav.Wrap(raw, tts.RAW16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	segments, err = Split(riff, tts.RIFF16khz16bitMonoPCM, events[1:])
+	assert.NoError(t, err)
+	assert.Len(t, segments, 2)
+	a, err := wav.Decode(segments[1].Audio)
+	assert.NoError(t, err)
+	assert.Equal(t, 750*time.Millisecond, a.Duration())
+	assert.Equal(t, raw[8000:], a.Data)
+
+	_, err = Split(raw, tts.AUDIO16khz32kbitrateMonoMP3, events)
+	assert.Error(t, err)
+}
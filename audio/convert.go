@@ -0,0 +1,37 @@
+package audio
+
+import (
+	"fmt"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/pcm"
+)
+
+// Convert renders audio synthesized in format from in format to where that is possible locally: between PCM,
+// mu-law and A-law formats with pcm.Convert, and between Ogg and WebM Opus with Remux. Audio already in format to is
+// returned as is. It may serve as tts.FallbackSynthesizer.Convert.
+func Convert(audio []byte, from, to tts.AudioOutput) ([]byte, error) {
+	switch {
+	case from == to:
+		return audio, nil
+	case !CanConvert(from, to):
+		return nil, fmt.Errorf("cannot convert %s audio to %s", from, to)
+	case sampled(from.Info().Codec):
+		return pcm.Convert(audio, from, to)
+	}
+	return Remux(audio, from, to)
+}
+
+// CanConvert reports whether Convert renders audio in format from in format to, without looking at the audio. It may
+// serve as tts.FallbackSynthesizer.CanConvert.
+func CanConvert(from, to tts.AudioOutput) bool {
+	src, dst := from.Info(), to.Info()
+	return from == to ||
+		sampled(src.Codec) && sampled(dst.Codec) ||
+		src.Codec == tts.CodecOpus && dst.Codec == tts.CodecOpus
+}
+
+// sampled reports whether audio of codec holds plain samples, which pcm decodes.
+func sampled(codec string) bool {
+	return codec == tts.CodecPCM || codec == tts.CodecMulaw || codec == tts.CodecAlaw
+}
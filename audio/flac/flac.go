@@ -0,0 +1,296 @@
+// Package flac encodes linear PCM as FLAC, which the text-to-speech service does not produce. The encoder is
+// simple rather than compact: every block is coded with the best of the fixed predictors of orders 0 to 4 and a
+// single Rice partition, or verbatim when that is smaller. The output is lossless and decodes with any FLAC
+// decoder, though larger than that of encoders searching linear predictors.
+package flac
+
+import (
+	"crypto/md5"
+	"fmt"
+
+	"github.com/linexjlin/azuretexttospeech/audio/wav"
+)
+
+// BlockSize is the number of frames coded in every FLAC frame but the last.
+const BlockSize = 4096
+
+// maxRiceParameter is the largest Rice parameter of the 4 bit coding method; 15 is the escape code.
+const maxRiceParameter = 14
+
+// Encode codes 8, 16 or 24 bit linear PCM audio as a FLAC stream.
+func Encode(a *wav.Audio) ([]byte, error) {
+	h := a.Header
+	if h.AudioFormat != wav.FormatPCM {
+		return nil, fmt.Errorf("cannot encode WAVE format %#x as FLAC, only linear PCM", h.AudioFormat)
+	}
+	bps := int(h.BitsPerSample)
+	if bps != 8 && bps != 16 && bps != 24 {
+		return nil, fmt.Errorf("cannot encode %d bit audio as FLAC", bps)
+	}
+	if h.Channels < 1 || h.Channels > 8 || h.SampleRate == 0 || h.SampleRate >= 1<<20 {
+		return nil, fmt.Errorf("cannot encode %d channels at %d Hz as FLAC", h.Channels, h.SampleRate)
+	}
+	channels := int(h.Channels)
+	frames := len(a.Data) / h.BlockAlign()
+	samples := decode(a.Data[:frames*h.BlockAlign()], bps)
+
+	w := &bitWriter{}
+	w.bytes([]byte("fLaC"))
+	streamInfo(w, h, frames, md5.Sum(md5Data(samples, bps)))
+
+	block := make([][]int64, channels)
+	for n, start := 0, 0; start < frames; n, start = n+1, start+BlockSize {
+		end := start + BlockSize
+		if end > frames {
+			end = frames
+		}
+		for c := range block {
+			block[c] = block[c][:0]
+			for i := start; i < end; i++ {
+				block[c] = append(block[c], samples[i*channels+c])
+			}
+		}
+		frame(w, block, bps, n)
+	}
+	return w.buf, nil
+}
+
+// decode returns the interleaved samples of PCM data.
+func decode(data []byte, bps int) []int64 {
+	width := bps / 8
+	out := make([]int64, len(data)/width)
+	for i := range out {
+		b := data[i*width:]
+		switch bps {
+		case 8:
+			out[i] = int64(b[0]) - 128
+		case 16:
+			out[i] = int64(int16(uint16(b[0]) | uint16(b[1])<<8))
+		case 24:
+			out[i] = int64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8)
+		}
+	}
+	return out
+}
+
+// md5Data returns the samples as signed little-endian integers, the input of the MD5 signature of STREAMINFO.
+func md5Data(samples []int64, bps int) []byte {
+	width := bps / 8
+	out := make([]byte, 0, len(samples)*width)
+	for _, s := range samples {
+		for i := 0; i < width; i++ {
+			out = append(out, byte(s>>(8*uint(i))))
+		}
+	}
+	return out
+}
+
+// streamInfo writes the STREAMINFO metadata block, the only one of the stream.
+func streamInfo(w *bitWriter, h wav.Header, frames int, sum [16]byte) {
+	w.bits(1, 1) // last metadata block
+	w.bits(0, 7) // STREAMINFO
+	w.bits(34, 24)
+	w.bits(BlockSize, 16) // all blocks but the last have the same size
+	w.bits(BlockSize, 16)
+	w.bits(0, 24) // minimum and maximum frame sizes are unknown
+	w.bits(0, 24)
+	w.bits(uint64(h.SampleRate), 20)
+	w.bits(uint64(h.Channels-1), 3)
+	w.bits(uint64(h.BitsPerSample-1), 5)
+	w.bits(uint64(frames), 36)
+	w.bytes(sum[:])
+}
+
+// frame writes a frame holding a block of samples for every channel, coded independently.
+func frame(w *bitWriter, block [][]int64, bps, number int) {
+	start := len(w.buf)
+	w.bits(0x3FFE, 14) // sync code
+	w.bits(0, 1)
+	w.bits(0, 1) // fixed block size
+	w.bits(7, 4) // block size - 1 follows as 16 bits
+	w.bits(0, 4) // sample rate of STREAMINFO
+	w.bits(uint64(len(block)-1), 4)
+	w.bits(map[int]uint64{8: 1, 16: 4, 24: 6}[bps], 3)
+	w.bits(0, 1)
+	w.bytes(utf8Number(uint64(number)))
+	w.bits(uint64(len(block[0])-1), 16)
+	w.bits(uint64(crc8(w.buf[start:])), 8)
+
+	for _, samples := range block {
+		subframe(w, samples, bps)
+	}
+	w.align()
+	w.bits(uint64(crc16(w.buf[start:])), 16)
+}
+
+// subframe writes the samples of a channel with the fixed predictor coding them in the fewest bits, or verbatim.
+func subframe(w *bitWriter, samples []int64, bps int) {
+	best, bestBits := -1, len(samples)*bps
+	var bestResidual []int64
+	var bestParam uint
+	for order := 0; order <= 4 && order < len(samples); order++ {
+		residual := fixedResidual(samples, order)
+		param, n := riceSize(residual)
+		if n += order * bps; n < bestBits {
+			best, bestBits, bestResidual, bestParam = order, n, residual, param
+		}
+	}
+
+	w.bits(0, 1)
+	if best < 0 {
+		w.bits(1, 6) // verbatim
+		w.bits(0, 1)
+		for _, s := range samples {
+			w.signed(s, uint(bps))
+		}
+		return
+	}
+	w.bits(uint64(8|best), 6) // fixed predictor of order best
+	w.bits(0, 1)
+	for _, s := range samples[:best] {
+		w.signed(s, uint(bps))
+	}
+	w.bits(0, 2) // Rice coding with 4 bit parameters
+	w.bits(0, 4) // a single partition
+	w.bits(uint64(bestParam), 4)
+	for _, r := range bestResidual {
+		u := uint64(r<<1) ^ uint64(r>>63)
+		w.unary(u >> bestParam)
+		w.bits(u&(1<<bestParam-1), bestParam)
+	}
+}
+
+// fixedResidual returns the residual of the fixed predictor of order for the samples following its warm-up.
+func fixedResidual(s []int64, order int) []int64 {
+	out := make([]int64, 0, len(s)-order)
+	for i := order; i < len(s); i++ {
+		var p int64
+		switch order {
+		case 1:
+			p = s[i-1]
+		case 2:
+			p = 2*s[i-1] - s[i-2]
+		case 3:
+			p = 3*s[i-1] - 3*s[i-2] + s[i-3]
+		case 4:
+			p = 4*s[i-1] - 6*s[i-2] + 4*s[i-3] - s[i-4]
+		}
+		out = append(out, s[i]-p)
+	}
+	return out
+}
+
+// riceSize returns the Rice parameter coding residual in the fewest bits and the size of the coded residual,
+// including the partition header.
+func riceSize(residual []int64) (uint, int) {
+	best, bestBits := uint(0), -1
+	for k := uint(0); k <= maxRiceParameter; k++ {
+		n := 6 + 4 // coding method, partition order and parameter
+		for _, r := range residual {
+			u := uint64(r<<1) ^ uint64(r>>63)
+			n += int(u>>k) + 1 + int(k)
+		}
+		if bestBits < 0 || n < bestBits {
+			best, bestBits = k, n
+		}
+	}
+	return best, bestBits
+}
+
+// utf8Number encodes a frame number the way FLAC does, as an extended UTF-8 sequence.
+func utf8Number(n uint64) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var tail []byte
+	lead, limit := byte(0x80), uint64(0x40)
+	for {
+		tail = append([]byte{0x80 | byte(n&0x3F)}, tail...)
+		n >>= 6
+		lead, limit = 0x80|lead>>1, limit>>1
+		if n < limit {
+			return append([]byte{lead | byte(n)}, tail...)
+		}
+	}
+}
+
+func crc8(b []byte) byte {
+	var c byte
+	for _, v := range b {
+		c ^= v
+		for i := 0; i < 8; i++ {
+			if c&0x80 != 0 {
+				c = c<<1 ^ 0x07
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
+
+func crc16(b []byte) uint16 {
+	var c uint16
+	for _, v := range b {
+		c ^= uint16(v) << 8
+		for i := 0; i < 8; i++ {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ 0x8005
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
+
+// bitWriter writes big endian bit fields.
+type bitWriter struct {
+	buf  []byte
+	free uint // unused low bits of the last byte of buf
+}
+
+func (w *bitWriter) bits(v uint64, n uint) {
+	for n > 0 {
+		if w.free == 0 {
+			w.buf = append(w.buf, 0)
+			w.free = 8
+		}
+		take := n
+		if take > w.free {
+			take = w.free
+		}
+		chunk := byte(v>>(n-take)) & byte(1<<take-1)
+		w.buf[len(w.buf)-1] |= chunk << (w.free - take)
+		w.free -= take
+		n -= take
+	}
+}
+
+// signed writes the two's complement of v in n bits.
+func (w *bitWriter) signed(v int64, n uint) {
+	w.bits(uint64(v)&(1<<n-1), n)
+}
+
+// unary writes q zero bits and a one.
+func (w *bitWriter) unary(q uint64) {
+	for ; q >= 32; q -= 32 {
+		w.bits(0, 32)
+	}
+	w.bits(1, uint(q)+1)
+}
+
+func (w *bitWriter) bytes(b []byte) {
+	if w.free == 0 {
+		w.buf = append(w.buf, b...)
+		return
+	}
+	for _, v := range b {
+		w.bits(uint64(v), 8)
+	}
+}
+
+// align pads the last byte with zero bits.
+func (w *bitWriter) align() {
+	w.free = 0
+}
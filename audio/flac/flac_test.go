@@ -0,0 +1,176 @@
+package flac
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/linexjlin/azuretexttospeech/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// bitReader reads the big endian bit fields written by bitWriter.
+type bitReader struct {
+	buf []byte
+	pos uint // in bits
+}
+
+func (r *bitReader) bits(n uint) uint64 {
+	var v uint64
+	for ; n > 0; n-- {
+		v = v<<1 | uint64(r.buf[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) signed(n uint) int64 {
+	return int64(r.bits(n)<<(64-n)) >> (64 - n)
+}
+
+// decodeFLAC decodes the streams written by Encode: verbatim and fixed subframes of independent channels.
+func decodeFLAC(t *testing.T, b []byte) (wav.Header, [16]byte, []int64) {
+	assert.Equal(t, "fLaC", string(b[:4]))
+	r := &bitReader{buf: b, pos: 32}
+	assert.Equal(t, uint64(1), r.bits(1), "last metadata block")
+	assert.Equal(t, uint64(0), r.bits(7))
+	assert.Equal(t, uint64(34), r.bits(24))
+	r.bits(16 + 16 + 24 + 24)
+	h := wav.Header{AudioFormat: wav.FormatPCM, SampleRate: uint32(r.bits(20)), Channels: uint16(r.bits(3) + 1), BitsPerSample: uint16(r.bits(5) + 1)}
+	total := int(r.bits(36))
+	var sum [16]byte
+	copy(sum[:], b[r.pos/8:])
+	r.pos += 128
+
+	var samples []int64
+	for frames := 0; frames < total; {
+		start := r.pos / 8
+		assert.Equal(t, uint64(0xFFF8), r.bits(16))
+		assert.Equal(t, uint64(7), r.bits(4))
+		r.bits(4)
+		assert.Equal(t, uint64(h.Channels-1), r.bits(4))
+		r.bits(4)
+		for r.buf[r.pos/8]&0xC0 == 0x80 || r.bits(1) == 1 { // skip the frame number
+			r.pos = (r.pos/8 + 1) * 8
+		}
+		r.pos = (r.pos/8 + 1) * 8
+		for r.buf[r.pos/8]&0xC0 == 0x80 {
+			r.pos += 8
+		}
+		n := int(r.bits(16)) + 1
+		assert.Equal(t, crc8(b[start:r.pos/8]), byte(r.bits(8)))
+
+		block := make([][]int64, h.Channels)
+		for c := range block {
+			assert.Equal(t, uint64(0), r.bits(1))
+			kind := r.bits(6)
+			r.bits(1)
+			bps := uint(h.BitsPerSample)
+			if kind == 1 {
+				for i := 0; i < n; i++ {
+					block[c] = append(block[c], r.signed(bps))
+				}
+				continue
+			}
+			order := int(kind &^ 8)
+			for i := 0; i < order; i++ {
+				block[c] = append(block[c], r.signed(bps))
+			}
+			assert.Equal(t, uint64(0), r.bits(6))
+			k := uint(r.bits(4))
+			for i := order; i < n; i++ {
+				q := uint64(0)
+				for r.bits(1) == 0 {
+					q++
+				}
+				u := q<<k | r.bits(k)
+				res := int64(u>>1) ^ -int64(u&1)
+				s := block[c]
+				var p int64
+				switch order {
+				case 1:
+					p = s[i-1]
+				case 2:
+					p = 2*s[i-1] - s[i-2]
+				case 3:
+					p = 3*s[i-1] - 3*s[i-2] + s[i-3]
+				case 4:
+					p = 4*s[i-1] - 6*s[i-2] + 4*s[i-3] - s[i-4]
+				}
+				block[c] = append(block[c], p+res)
+			}
+		}
+		if r.pos%8 != 0 {
+			r.pos += 8 - r.pos%8
+		}
+		assert.Equal(t, crc16(b[start:r.pos/8]), uint16(r.bits(16)))
+		for i := 0; i < n; i++ {
+			for c := range block {
+				samples = append(samples, block[c][i])
+			}
+		}
+		frames += n
+	}
+	assert.Equal(t, len(b)*8, int(r.pos), "trailing data")
+	return h, sum, samples
+}
+
+func TestEncode(t *testing.T) {
+	for _, c := range []struct {
+		channels, bits, frames int
+	}{
+		{1, 16, 3*BlockSize + 17},
+		{2, 16, 1000},
+		{1, 8, 300},
+		{1, 24, 5000},
+		{1, 16, 2},
+	} {
+		h := wav.Header{AudioFormat: wav.FormatPCM, Channels: uint16(c.channels), SampleRate: 24000, BitsPerSample: uint16(c.bits)}
+		var want []int64
+		var data []byte
+		for i := 0; i < c.frames; i++ {
+			for ch := 0; ch < c.channels; ch++ {
+				v := 0.5*math.Sin(float64(i)*0.05*float64(ch+1)) + 0.05*math.Sin(float64(i*i))
+				s := int64(v * float64(int64(1)<<uint(c.bits-1)-1))
+				want = append(want, s)
+				switch c.bits {
+				case 8:
+					data = append(data, byte(s+128))
+				case 16:
+					data = append(data, 0, 0)
+					binary.LittleEndian.PutUint16(data[len(data)-2:], uint16(s))
+				case 24:
+					data = append(data, byte(s), byte(s>>8), byte(s>>16))
+				}
+			}
+		}
+		b, err := Encode(&wav.Audio{Header: h, Data: data})
+		if !assert.NoError(t, err) {
+			continue
+		}
+		got, sum, samples := decodeFLAC(t, b)
+		assert.Equal(t, h, got)
+		assert.Equal(t, want, samples)
+		assert.Equal(t, md5.Sum(md5Data(want, c.bits)), sum)
+		if c.frames > 3*BlockSize {
+			assert.Less(t, len(b), len(data), "compressed")
+		}
+	}
+
+	_, err := Encode(&wav.Audio{Header: wav.Header{AudioFormat: wav.FormatMuLaw, Channels: 1, SampleRate: 8000, BitsPerSample: 8}})
+	assert.Error(t, err)
+}
+
+func TestUTF8Number(t *testing.T) {
+	assert.Equal(t, []byte{0x7F}, utf8Number(0x7F))
+	assert.Equal(t, []byte{0xC2, 0x80}, utf8Number(0x80))
+	assert.Equal(t, []byte{0xDF, 0xBF}, utf8Number(0x7FF))
+	assert.Equal(t, []byte{0xE0, 0xA0, 0x80}, utf8Number(0x800))
+	assert.Equal(t, []byte{0xF0, 0x90, 0x80, 0x80}, utf8Number(0x10000))
+}
+
+func TestCRC(t *testing.T) {
+	assert.Equal(t, byte(0xF4), crc8([]byte("123456789")))
+	assert.Equal(t, uint16(0xFEE8), crc16([]byte("123456789")))
+}
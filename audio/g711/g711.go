@@ -0,0 +1,178 @@
+// Package g711 converts between ITU-T G.711 mu-law, A-law and 16 bit signed little-endian linear PCM. Besides the
+// per-sample and whole-buffer functions, Reader and Writer wrappers convert streams as they pass through.
+package g711
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Law selects the G.711 companding variant.
+type Law int
+
+const (
+	MuLaw Law = iota // North America and Japan, AudioOutput codec "mulaw"
+	ALaw             // Europe and most other regions, AudioOutput codec "alaw"
+)
+
+func (l Law) String() string {
+	switch l {
+	case MuLaw:
+		return "mu-law"
+	case ALaw:
+		return "A-law"
+	}
+	return "Law(?)"
+}
+
+const (
+	mulawBias = 0x84
+	mulawClip = 32635
+)
+
+// decoding tables, indexed by the encoded byte.
+var (
+	mulawTable [256]int16
+	alawTable  [256]int16
+)
+
+func init() {
+	for i := 0; i < 256; i++ {
+		mulawTable[i] = decodeMulaw(byte(i))
+		alawTable[i] = decodeAlaw(byte(i))
+	}
+}
+
+// EncodeMulaw compresses a linear sample to mu-law.
+func EncodeMulaw(sample int16) byte {
+	s := int(sample)
+	var sign byte
+	if s < 0 {
+		s = -s
+		sign = 0x80
+	}
+	if s > mulawClip {
+		s = mulawClip
+	}
+	s += mulawBias
+	exp := bits.Len(uint(s)) - 8
+	mant := (s >> uint(exp+3)) & 0x0F
+	return ^(sign | byte(exp<<4) | byte(mant))
+}
+
+// DecodeMulaw expands a mu-law byte to a linear sample.
+func DecodeMulaw(b byte) int16 {
+	return mulawTable[b]
+}
+
+func decodeMulaw(b byte) int16 {
+	u := ^b
+	t := (int(u&0x0F) << 3) + mulawBias
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return int16(mulawBias - t)
+	}
+	return int16(t - mulawBias)
+}
+
+// alawSegmentEnd holds the upper bound of each A-law segment for 13 bit magnitudes.
+var alawSegmentEnd = [8]int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+
+// EncodeAlaw compresses a linear sample to A-law.
+func EncodeAlaw(sample int16) byte {
+	s := int(sample) >> 3
+	mask := byte(0xD5)
+	if s < 0 {
+		mask = 0x55
+		s = -s - 1
+	}
+
+	seg := 0
+	for seg < 8 && s > alawSegmentEnd[seg] {
+		seg++
+	}
+	if seg >= 8 {
+		return 0x7F ^ mask
+	}
+	aval := seg << 4
+	if seg < 2 {
+		aval |= (s >> 1) & 0x0F
+	} else {
+		aval |= (s >> uint(seg)) & 0x0F
+	}
+	return byte(aval) ^ mask
+}
+
+// DecodeAlaw expands an A-law byte to a linear sample.
+func DecodeAlaw(b byte) int16 {
+	return alawTable[b]
+}
+
+func decodeAlaw(b byte) int16 {
+	a := b ^ 0x55
+	t := int(a&0x0F) << 4
+	switch seg := (a & 0x70) >> 4; seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+// Encode compresses a linear sample using l.
+func (l Law) Encode(sample int16) byte {
+	if l == ALaw {
+		return EncodeAlaw(sample)
+	}
+	return EncodeMulaw(sample)
+}
+
+// Decode expands a byte encoded with l to a linear sample.
+func (l Law) Decode(b byte) int16 {
+	if l == ALaw {
+		return alawTable[b]
+	}
+	return mulawTable[b]
+}
+
+// DecodeBytes expands G.711 data to 16 bit little-endian PCM, the output is twice the length of the input.
+func DecodeBytes(src []byte, l Law) []byte {
+	dst := make([]byte, 2*len(src))
+	decodeInto(dst, src, l)
+	return dst
+}
+
+// EncodeBytes compresses 16 bit little-endian PCM to G.711. A trailing odd byte is ignored.
+func EncodeBytes(pcm []byte, l Law) []byte {
+	dst := make([]byte, len(pcm)/2)
+	encodeInto(dst, pcm, l)
+	return dst
+}
+
+// Transcode converts G.711 data between the two laws by way of the linear value.
+func Transcode(src []byte, from, to Law) []byte {
+	dst := make([]byte, len(src))
+	for i, b := range src {
+		dst[i] = to.Encode(from.Decode(b))
+	}
+	return dst
+}
+
+func decodeInto(dst, src []byte, l Law) {
+	for i, b := range src {
+		binary.LittleEndian.PutUint16(dst[2*i:], uint16(l.Decode(b)))
+	}
+}
+
+func encodeInto(dst, pcm []byte, l Law) {
+	for i := range dst {
+		dst[i] = l.Encode(int16(binary.LittleEndian.Uint16(pcm[2*i:])))
+	}
+}
@@ -0,0 +1,105 @@
+package g711
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKnownValues(t *testing.T) {
+	assert.Equal(t, byte(0xFF), EncodeMulaw(0))
+	assert.Equal(t, byte(0xD5), EncodeAlaw(0))
+	assert.Equal(t, int16(0), DecodeMulaw(0xFF))
+	assert.Equal(t, int16(8), DecodeAlaw(0xD5))
+	assert.Equal(t, byte(0x80), EncodeMulaw(32767))
+	assert.Equal(t, byte(0x00), EncodeMulaw(-32768))
+	assert.Equal(t, byte(0xAA), EncodeAlaw(32767))
+	assert.Equal(t, byte(0x2A), EncodeAlaw(-32768))
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, l := range []Law{MuLaw, ALaw} {
+		// every code word must survive decode followed by encode. mu-law has two codes for zero.
+		for i := 0; i < 256; i++ {
+			b := byte(i)
+			if l == MuLaw && b == 0x7F {
+				continue
+			}
+			assert.Equal(t, b, l.Encode(l.Decode(b)), "%s code 0x%02x", l, b)
+		}
+		// quantization error is bounded relative to the magnitude.
+		for s := -32768; s <= 32767; s += 7 {
+			d := int(l.Decode(l.Encode(int16(s))))
+			err := d - s
+			if err < 0 {
+				err = -err
+			}
+			limit := abs(s)/16 + 16
+			assert.True(t, err <= limit, "%s sample %d decoded as %d", l, s, d)
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestTranscode(t *testing.T) {
+	ulaw := EncodeBytes(pcm(0, 1000, -1000, 30000), MuLaw)
+	alaw := Transcode(ulaw, MuLaw, ALaw)
+	assert.Equal(t, EncodeBytes(DecodeBytes(ulaw, MuLaw), ALaw), alaw)
+}
+
+func TestStreams(t *testing.T) {
+	samples := make([]int16, 1001)
+	for i := range samples {
+		samples[i] = int16(i*61 - 30000)
+	}
+	in := pcm(samples...)
+	want := EncodeBytes(in, ALaw)
+
+	// PCM reader to G.711, one byte at a time so samples straddle reads.
+	got, err := ioutil.ReadAll(NewEncodeReader(iotest.OneByteReader(bytes.NewReader(in)), ALaw))
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// G.711 reader to PCM.
+	dec, err := ioutil.ReadAll(NewDecodeReader(iotest.HalfReader(bytes.NewReader(want)), ALaw))
+	assert.NoError(t, err)
+	assert.Equal(t, DecodeBytes(want, ALaw), dec)
+
+	// PCM writes split at odd offsets.
+	var buf bytes.Buffer
+	w := NewEncodeWriter(&buf, ALaw)
+	w.Write(in[:3])
+	w.Write(in[3:1001])
+	w.Write(in[1001:])
+	assert.NoError(t, w.Close())
+	assert.Equal(t, want, buf.Bytes())
+
+	buf.Reset()
+	NewDecodeWriter(&buf, ALaw).Write(want)
+	assert.Equal(t, dec, buf.Bytes())
+
+	// a dangling odd byte is an error.
+	w = NewEncodeWriter(&bytes.Buffer{}, MuLaw)
+	w.Write([]byte{1, 2, 3})
+	assert.Error(t, w.Close())
+	_, err = ioutil.ReadAll(NewEncodeReader(bytes.NewReader([]byte{1, 2, 3}), MuLaw))
+	assert.Error(t, err)
+}
+
+func pcm(samples ...int16) []byte {
+	b := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(s))
+	}
+	return b
+}
@@ -0,0 +1,137 @@
+package g711
+
+import (
+	"fmt"
+	"io"
+)
+
+// decodeReader expands G.711 bytes read from r into PCM.
+type decodeReader struct {
+	r       io.Reader
+	law     Law
+	buf     []byte
+	pending []byte // decoded bytes not yet returned
+}
+
+// NewDecodeReader returns a reader yielding 16 bit little-endian PCM decoded from the G.711 stream r.
+func NewDecodeReader(r io.Reader, l Law) io.Reader {
+	return &decodeReader{r: r, law: l}
+}
+
+func (d *decodeReader) Read(p []byte) (int, error) {
+	if len(d.pending) > 0 {
+		n := copy(p, d.pending)
+		d.pending = d.pending[n:]
+		return n, nil
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	want := (len(p) + 1) / 2
+	if cap(d.buf) < want {
+		d.buf = make([]byte, want)
+	}
+	n, err := d.r.Read(d.buf[:want])
+	out := DecodeBytes(d.buf[:n], d.law)
+	c := copy(p, out)
+	d.pending = out[c:]
+	if err == io.EOF && len(d.pending) > 0 {
+		err = nil
+	}
+	return c, err
+}
+
+// encodeReader compresses PCM read from r into G.711.
+type encodeReader struct {
+	r     io.Reader
+	law   Law
+	buf   []byte
+	carry []byte // an odd byte left from the previous read
+}
+
+// NewEncodeReader returns a reader yielding G.711 compressed from the 16 bit little-endian PCM stream r.
+func NewEncodeReader(r io.Reader, l Law) io.Reader {
+	return &encodeReader{r: r, law: l}
+}
+
+func (e *encodeReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	want := 2 * len(p)
+	if cap(e.buf) < want {
+		e.buf = make([]byte, want)
+	}
+	for {
+		k := copy(e.buf, e.carry)
+		n, err := e.r.Read(e.buf[k:want])
+		n += k
+		e.carry = append(e.carry[:0], e.buf[n-n%2:n]...)
+
+		if c := n / 2; c > 0 {
+			encodeInto(p[:c], e.buf[:n], e.law)
+			if err == io.EOF && len(e.carry) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return c, err
+		}
+		if err != nil {
+			if err == io.EOF && len(e.carry) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+	}
+}
+
+// encodeWriter compresses PCM written to it and forwards G.711 to w.
+type encodeWriter struct {
+	w     io.Writer
+	law   Law
+	carry []byte
+}
+
+// NewEncodeWriter returns a writer accepting 16 bit little-endian PCM and writing G.711 to w. Close reports a
+// dangling odd byte; it does not close w.
+func NewEncodeWriter(w io.Writer, l Law) io.WriteCloser {
+	return &encodeWriter{w: w, law: l}
+}
+
+func (e *encodeWriter) Write(p []byte) (int, error) {
+	in := p
+	if len(e.carry) > 0 && len(p) > 0 {
+		in = append(e.carry, p...)
+	}
+	out := EncodeBytes(in, e.law)
+	e.carry = append([]byte(nil), in[len(in)-len(in)%2:]...)
+	if _, err := e.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (e *encodeWriter) Close() error {
+	if len(e.carry) > 0 {
+		return fmt.Errorf("g711: incomplete sample, %d byte left over", len(e.carry))
+	}
+	return nil
+}
+
+// decodeWriter expands G.711 written to it and forwards PCM to w.
+type decodeWriter struct {
+	w   io.Writer
+	law Law
+}
+
+// NewDecodeWriter returns a writer accepting G.711 and writing 16 bit little-endian PCM to w.
+func NewDecodeWriter(w io.Writer, l Law) io.Writer {
+	return &decodeWriter{w: w, law: l}
+}
+
+func (d *decodeWriter) Write(p []byte) (int, error) {
+	if _, err := d.w.Write(DecodeBytes(p, d.law)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
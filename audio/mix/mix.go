@@ -0,0 +1,201 @@
+// Package mix lays synthesized narration over background music, for example to produce on-hold messages. Beds are
+// WAV files which may be looped, faded in and out, and ducked while the narration speaks. Everything is mixed at
+// the sample rate and channel count of the output format.
+package mix
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/pcm"
+	"github.com/linexjlin/azuretexttospeech/audio/wav"
+)
+
+// Defaults for the zero fields of Options.
+const (
+	DefaultThreshold = -45.0 // dBFS
+	DefaultAttack    = 100 * time.Millisecond
+	DefaultRelease   = 500 * time.Millisecond
+	DefaultHold      = 300 * time.Millisecond
+)
+
+// detectWindow is the length of the windows in which the narration level is measured.
+const detectWindow = 10 * time.Millisecond
+
+// Bed is a background track.
+type Bed struct {
+	Audio   []byte  // WAV file holding PCM, mu-law or A-law audio
+	Gain    float64 // dB applied to the whole track, e.g. -18 to keep music under the voice
+	Duck    float64 // dB of further attenuation while the narration speaks, e.g. -12; zero disables ducking
+	Loop    bool    // repeat the track until the end of the output; otherwise it plays once
+	FadeIn  time.Duration
+	FadeOut time.Duration // before the end of the output, or of the track when it ends earlier
+}
+
+// Options controls the timing of the mix.
+type Options struct {
+	Lead time.Duration // beds playing alone before the narration starts
+	Tail time.Duration // beds playing alone after the narration ends
+
+	// Ducking: the narration speaks where its level exceeds Threshold in dBFS. Beds are faded down over Attack
+	// ahead of the speech and back up over Release once it has been silent for Hold, so pauses between words
+	// do not make the music pump.
+	Threshold float64
+	Attack    time.Duration
+	Release   time.Duration
+	Hold      time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Threshold == 0 {
+		o.Threshold = DefaultThreshold
+	}
+	if o.Attack == 0 {
+		o.Attack = DefaultAttack
+	}
+	if o.Release == 0 {
+		o.Release = DefaultRelease
+	}
+	if o.Hold == 0 {
+		o.Hold = DefaultHold
+	}
+	return o
+}
+
+// Mix lays speech, synthesized in format, over beds and renders the result in out, typically a RIFF PCM format.
+// Samples summing beyond full scale are clipped, so beds should be given enough negative gain.
+func Mix(speech []byte, format, out tts.AudioOutput, beds []Bed, opts Options) ([]byte, error) {
+	h, err := wav.HeaderFor(out)
+	if err != nil {
+		return nil, err
+	}
+	rate, channels := int(h.SampleRate), int(h.Channels)
+	s, err := pcm.Decode(speech, format)
+	if err != nil {
+		return nil, err
+	}
+	s = pcm.Resample(s.Remix(channels), rate)
+
+	tracks := make([]*pcm.Buffer, len(beds))
+	for i, bed := range beds {
+		if !bytes.HasPrefix(bed.Audio, []byte("RIFF")) {
+			return nil, fmt.Errorf("bed %d is not a WAV file", i)
+		}
+		b, err := pcm.Decode(bed.Audio, out)
+		if err != nil {
+			return nil, fmt.Errorf("bed %d, %v", i, err)
+		}
+		tracks[i] = pcm.Resample(b.Remix(channels), rate)
+	}
+	return pcm.Encode(mix(s, tracks, beds, opts.withDefaults()), out)
+}
+
+// mix sums speech and the tracks of beds, which all share the sample rate and channel count of speech.
+func mix(speech *pcm.Buffer, tracks []*pcm.Buffer, beds []Bed, opts Options) *pcm.Buffer {
+	rate, channels := speech.SampleRate, speech.Channels
+	lead := frames(opts.Lead, rate)
+	total := lead + speech.Frames() + frames(opts.Tail, rate)
+	out := &pcm.Buffer{SampleRate: rate, Channels: channels, Samples: make([]float64, total*channels)}
+	copy(out.Samples[lead*channels:], speech.Samples)
+
+	var duck []float64
+	for i, t := range tracks {
+		bed := beds[i]
+		length := total
+		if !bed.Loop && t.Frames() < length {
+			length = t.Frames()
+		}
+		if length == 0 {
+			continue
+		}
+		if bed.Duck != 0 && duck == nil {
+			duck = ducking(speech, lead, total, opts)
+		}
+		gain := math.Pow(10, bed.Gain/20)
+		fadeIn, fadeOut := frames(bed.FadeIn, rate), frames(bed.FadeOut, rate)
+		for f := 0; f < length; f++ {
+			g := gain
+			if f < fadeIn {
+				g *= float64(f) / float64(fadeIn)
+			}
+			if rest := length - f; rest < fadeOut {
+				g *= float64(rest) / float64(fadeOut)
+			}
+			if bed.Duck != 0 {
+				g *= math.Pow(10, bed.Duck*duck[f]/20)
+			}
+			src := (f % t.Frames()) * channels
+			for c := 0; c < channels; c++ {
+				out.Samples[f*channels+c] += g * t.Samples[src+c]
+			}
+		}
+	}
+	return out
+}
+
+// ducking returns for every output frame how far beds are ducked, from 0 (not at all) to 1 (fully). Speech is
+// detected in windows of detectWindow; each window that speaks ducks the beds from Attack before it until Hold
+// after it, and the envelope ramps linearly over Attack and Release.
+func ducking(speech *pcm.Buffer, lead, total int, opts Options) []float64 {
+	rate, channels := speech.SampleRate, speech.Channels
+	window := frames(detectWindow, rate)
+	if window == 0 {
+		window = 1
+	}
+	threshold := math.Pow(10, opts.Threshold/20)
+	attack, release, hold := frames(opts.Attack, rate), frames(opts.Release, rate), frames(opts.Hold, rate)
+
+	target := make([]bool, total)
+	for start := 0; start < speech.Frames(); start += window {
+		end := start + window
+		if end > speech.Frames() {
+			end = speech.Frames()
+		}
+		var sum float64
+		for _, v := range speech.Samples[start*channels : end*channels] {
+			sum += v * v
+		}
+		if math.Sqrt(sum/float64((end-start)*channels)) < threshold {
+			continue
+		}
+		from, to := lead+start-attack, lead+end+hold
+		if from < 0 {
+			from = 0
+		}
+		if to > total {
+			to = total
+		}
+		for f := from; f < to; f++ {
+			target[f] = true
+		}
+	}
+
+	env := make([]float64, total)
+	var level float64
+	for f, on := range target {
+		switch {
+		case on && attack == 0, !on && release == 0:
+			level = 0
+			if on {
+				level = 1
+			}
+		case on:
+			level = math.Min(1, level+1/float64(attack))
+		default:
+			level = math.Max(0, level-1/float64(release))
+		}
+		env[f] = level
+	}
+	return env
+}
+
+// frames converts a duration to a number of frames at rate, rounding to the nearest frame.
+func frames(d time.Duration, rate int) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((int64(d)*int64(rate) + int64(time.Second)/2) / int64(time.Second))
+}
@@ -0,0 +1,61 @@
+package mix
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/pcm"
+	"github.com/stretchr/testify/assert"
+)
+
+// constant returns a mono buffer at 1 kHz holding d of a constant value.
+func constant(v float64, d time.Duration) *pcm.Buffer {
+	b := &pcm.Buffer{SampleRate: 1000, Channels: 1, Samples: make([]float64, d/time.Millisecond)}
+	for i := range b.Samples {
+		b.Samples[i] = v
+	}
+	return b
+}
+
+func TestMix(t *testing.T) {
+	speech := constant(0.5, time.Second)
+	opts := Options{Lead: 500 * time.Millisecond, Tail: 1500 * time.Millisecond}.withDefaults()
+
+	out := mix(speech, []*pcm.Buffer{constant(0.1, 300*time.Millisecond)}, []Bed{{Loop: true, Duck: -12}}, opts)
+	assert.Equal(t, 3*time.Second, out.Duration())
+	at := func(ms int) float64 { return out.Samples[ms] }
+	assert.InDelta(t, 0.1, at(100), 1e-9, "bed alone in the lead")
+	assert.InDelta(t, 0.1, at(399), 0.002, "ducking starts an attack ahead of the speech")
+	assert.InDelta(t, 0.5+0.1*math.Pow(10, -12.0/20), at(500), 1e-9)
+	assert.InDelta(t, 0.5+0.1*math.Pow(10, -12.0/20), at(1499), 1e-9)
+	assert.InDelta(t, 0.1*math.Pow(10, -12.0/20), at(1700), 1e-9, "held after the speech")
+	assert.InDelta(t, 0.1, at(2900), 1e-9, "released")
+
+	// a bed played once, faded in and out at its own end.
+	out = mix(speech, []*pcm.Buffer{constant(0.1, 2*time.Second)}, []Bed{{Gain: -6, FadeIn: 200 * time.Millisecond, FadeOut: 100 * time.Millisecond}}, opts)
+	gain := 0.1 * math.Pow(10, -6.0/20)
+	assert.Equal(t, 0.0, at(0))
+	assert.InDelta(t, gain/2, at(100), 1e-9)
+	assert.InDelta(t, 0.5+gain, at(1000), 1e-9, "no ducking")
+	assert.InDelta(t, gain/2, at(1950), 1e-9)
+	assert.Equal(t, 0.0, at(2500))
+}
+
+func TestMixAudio(t *testing.T) {
+	speech, err := pcm.Encode(constant(0.5, time.Second), tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	bed, err := pcm.Encode(constant(0.1, 250*time.Millisecond), tts.RIFF8khz16bitMonoPCM)
+	assert.NoError(t, err)
+
+	out, err := Mix(speech, tts.RIFF16khz16bitMonoPCM, tts.RIFF24khz16bitMonoPCM, []Bed{{Audio: bed, Loop: true, Gain: -20}}, Options{Lead: time.Second})
+	assert.NoError(t, err)
+	b, err := pcm.Decode(out, tts.RIFF24khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, 24000, b.SampleRate)
+	assert.Equal(t, 2*time.Second, b.Duration())
+
+	_, err = Mix(speech, tts.RIFF16khz16bitMonoPCM, tts.RIFF24khz16bitMonoPCM, []Bed{{Audio: []byte("ID3")}}, Options{})
+	assert.Error(t, err)
+}
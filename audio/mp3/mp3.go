@@ -0,0 +1,296 @@
+// Package mp3 parses MPEG audio frame headers and works on the MP3 outputs of the text-to-speech service without
+// decoding them: it measures their duration, joins and trims them at frame boundaries, inserts silence and writes
+// Xing/LAME headers.
+package mp3
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MPEG versions.
+const (
+	MPEG1  = 1
+	MPEG2  = 2
+	MPEG25 = 25 // the unofficial MPEG 2.5 extension
+)
+
+// HeaderSize is the size of an MPEG audio frame header.
+const HeaderSize = 4
+
+// ErrNoFrames is returned when no MPEG audio frame is found in the input.
+var ErrNoFrames = errors.New("no MPEG audio frames found")
+
+// Header is a decoded MPEG audio frame header.
+type Header struct {
+	Version    int // one of MPEG1, MPEG2 and MPEG25
+	Layer      int // 1, 2 or 3
+	Bitrate    int // bits per second
+	SampleRate int
+	Padding    bool
+	Mono       bool
+}
+
+var bitrates = [...][16]int{
+	{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448}, // MPEG1 layer 1
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},    // MPEG1 layer 2
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},     // MPEG1 layer 3
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},    // MPEG2/2.5 layer 1
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},         // MPEG2/2.5 layers 2 and 3
+}
+
+var sampleRates = map[int][3]int{
+	MPEG1:  {44100, 48000, 32000},
+	MPEG2:  {22050, 24000, 16000},
+	MPEG25: {11025, 12000, 8000},
+}
+
+// ParseHeader decodes the frame header at the start of b. Free format frames, whose length cannot be derived from
+// the header, are rejected.
+func ParseHeader(b []byte) (Header, error) {
+	var h Header
+	if len(b) < HeaderSize {
+		return h, fmt.Errorf("short frame header")
+	}
+	v := binary.BigEndian.Uint32(b)
+	if v>>21 != 0x7ff {
+		return h, fmt.Errorf("no frame sync")
+	}
+	switch (v >> 19) & 3 {
+	case 0:
+		h.Version = MPEG25
+	case 2:
+		h.Version = MPEG2
+	case 3:
+		h.Version = MPEG1
+	default:
+		return h, fmt.Errorf("reserved MPEG version")
+	}
+	h.Layer = 4 - int((v>>17)&3)
+	if h.Layer == 4 {
+		return h, fmt.Errorf("reserved MPEG layer")
+	}
+
+	bi := int((v >> 12) & 0xf)
+	ri := int((v >> 10) & 3)
+	if bi == 0 || bi == 15 || ri == 3 {
+		return h, fmt.Errorf("unsupported bitrate or sample rate index")
+	}
+	h.Bitrate = bitrates[h.bitrateTable()][bi] * 1000
+	h.SampleRate = sampleRates[h.Version][ri]
+	h.Padding = (v>>9)&1 == 1
+	h.Mono = (v>>6)&3 == 3
+	return h, nil
+}
+
+// bitrateTable returns the index into bitrates for the version and layer of h.
+func (h Header) bitrateTable() int {
+	switch {
+	case h.Version == MPEG1:
+		return h.Layer - 1
+	case h.Layer == 1:
+		return 3
+	}
+	return 4
+}
+
+// Samples returns the number of samples per channel in the frame.
+func (h Header) Samples() int {
+	switch {
+	case h.Layer == 1:
+		return 384
+	case h.Layer == 3 && h.Version != MPEG1:
+		return 576
+	}
+	return 1152
+}
+
+// Size returns the length of the frame in bytes, header included.
+func (h Header) Size() int {
+	pad := 0
+	if h.Padding {
+		pad = 1
+	}
+	if h.Layer == 1 {
+		return (12*h.Bitrate/h.SampleRate + pad) * 4
+	}
+	return h.Samples()/8*h.Bitrate/h.SampleRate + pad
+}
+
+// Duration returns the play time of the frame.
+func (h Header) Duration() time.Duration {
+	return time.Duration(int64(h.Samples()) * int64(time.Second) / int64(h.SampleRate))
+}
+
+// sideInfoSize returns the size of the layer 3 side information following the header, where a Xing header is
+// placed.
+func (h Header) sideInfoSize() int {
+	switch {
+	case h.Version == MPEG1 && !h.Mono:
+		return 32
+	case h.Version == MPEG1, !h.Mono:
+		return 17
+	}
+	return 9
+}
+
+// InfoFrames returns the frame count stored in a Xing, Info or VBRI header carried by frame, the first frame of a
+// stream. Such a frame holds no audio itself. ok is false when frame carries no such header; count is zero when
+// the header does not record the number of frames.
+func InfoFrames(h Header, frame []byte) (count int, ok bool) {
+	if off := HeaderSize + h.sideInfoSize(); len(frame) >= off+8 {
+		if tag := string(frame[off : off+4]); tag == "Xing" || tag == "Info" {
+			if flags := binary.BigEndian.Uint32(frame[off+4:]); flags&1 == 1 && len(frame) >= off+12 {
+				count = int(binary.BigEndian.Uint32(frame[off+8:]))
+			}
+			return count, true
+		}
+	}
+	// VBRI headers always follow 32 bytes of side information.
+	if off := HeaderSize + 32; len(frame) >= off+18 && string(frame[off:off+4]) == "VBRI" {
+		return int(binary.BigEndian.Uint32(frame[off+14:])), true
+	}
+	return 0, false
+}
+
+// tagSize returns the length of an ID3v2 tag at the start of b, zero when b does not start with one, or -1 when
+// b is too short to tell.
+func tagSize(b []byte) int {
+	if len(b) < 3 {
+		if string(b) == "ID3"[:len(b)] {
+			return -1
+		}
+		return 0
+	}
+	if string(b[:3]) != "ID3" {
+		return 0
+	}
+	if len(b) < 10 {
+		return -1
+	}
+	// the size is a 28 bit big-endian integer stored 7 bits per byte.
+	size := 10 + (int(b[6])<<21 | int(b[7])<<14 | int(b[8])<<7 | int(b[9]))
+	if b[5]&0x10 != 0 {
+		size += 10 // footer
+	}
+	return size
+}
+
+// scan walks the frames at the start of b and calls fn for every complete audio frame. ID3 tags, Xing, Info and
+// VBRI frames and data that is not a frame are skipped. It returns the number of bytes consumed and the length of
+// a tag extending beyond b that remains to be skipped.
+func scan(b []byte, fn func(h Header, frame []byte)) (off, skip int) {
+	for off < len(b) {
+		rest := b[off:]
+		if t := tagSize(rest); t != 0 {
+			if t < 0 {
+				break
+			}
+			if t > len(rest) {
+				return len(b), t - len(rest)
+			}
+			off += t
+			continue
+		}
+		if len(rest) < HeaderSize {
+			break
+		}
+		if string(rest[:3]) == "TAG" {
+			// an ID3v1 tag, 128 bytes at the end of a segment.
+			if len(rest) < 128 {
+				break
+			}
+			off += 128
+			continue
+		}
+		h, err := ParseHeader(rest)
+		if err != nil {
+			// resynchronize on the next byte.
+			off++
+			continue
+		}
+		if len(rest) < h.Size() {
+			break
+		}
+		off += h.Size()
+		if _, info := InfoFrames(h, rest[:h.Size()]); !info {
+			fn(h, rest[:h.Size()])
+		}
+	}
+	return off, 0
+}
+
+// Counter measures the duration of an MP3 stream written to it incrementally. ID3 tags, Xing, Info and VBRI
+// frames, including those left mid-stream by naive concatenation, and data that is not a frame are skipped.
+// Frames are counted once complete.
+type Counter struct {
+	buf    []byte
+	skip   int
+	frames int
+	d      time.Duration // play time of frames at earlier sample rates
+	rate   int
+	n      int64 // samples at rate
+}
+
+// Write consumes the next part of the stream. It never fails.
+func (c *Counter) Write(p []byte) (int, error) {
+	total := len(p)
+	if c.skip > 0 {
+		n := c.skip
+		if n > len(p) {
+			n = len(p)
+		}
+		c.skip -= n
+		p = p[n:]
+	}
+	c.buf = append(c.buf, p...)
+	off, skip := scan(c.buf, func(h Header, _ []byte) { c.add(h) })
+	c.skip += skip
+	c.buf = append(c.buf[:0], c.buf[off:]...)
+	return total, nil
+}
+
+func (c *Counter) add(h Header) {
+	if h.SampleRate != c.rate {
+		c.d = c.Duration()
+		c.rate, c.n = h.SampleRate, 0
+	}
+	c.n += int64(h.Samples())
+	c.frames++
+}
+
+// Frames returns the number of audio frames seen so far.
+func (c *Counter) Frames() int {
+	return c.frames
+}
+
+// Duration returns the play time of the frames seen so far.
+func (c *Counter) Duration() time.Duration {
+	if c.rate == 0 {
+		return c.d
+	}
+	return c.d + time.Duration(c.n*int64(time.Second)/int64(c.rate))
+}
+
+// Duration returns the play time of an MP3 stream held in memory. The frame count of a leading Xing, Info or VBRI
+// header is trusted when present, otherwise every frame is counted.
+func Duration(b []byte) (time.Duration, error) {
+	off := tagSize(b)
+	if off < 0 || off > len(b) {
+		return 0, ErrNoFrames
+	}
+	if h, err := ParseHeader(b[off:]); err == nil && off+h.Size() <= len(b) {
+		if n, ok := InfoFrames(h, b[off:off+h.Size()]); ok && n > 0 {
+			return time.Duration(int64(n) * int64(h.Samples()) * int64(time.Second) / int64(h.SampleRate)), nil
+		}
+	}
+
+	var c Counter
+	c.Write(b)
+	if c.Frames() == 0 {
+		return 0, ErrNoFrames
+	}
+	return c.Duration(), nil
+}
@@ -0,0 +1,191 @@
+package mp3
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mpeg2Mono48k is the header of a 24 kHz, 48 kbps, mono MPEG2 layer 3 frame, as produced for
+// AUDIO24khz48kbitrateMonoMP3.
+const mpeg2Mono48k = 0xFFF364C4
+
+// frame returns an empty frame with the given header.
+func frame(header uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, header)
+	h, _ := ParseHeader(b)
+	return append(b, make([]byte, h.Size()-4)...)
+}
+
+// xing returns an Info frame recording n frames.
+func xing(header uint32, n int) []byte {
+	b := frame(header)
+	off := HeaderSize + 9
+	copy(b[off:], "Info")
+	binary.BigEndian.PutUint32(b[off+4:], 1)
+	binary.BigEndian.PutUint32(b[off+8:], uint32(n))
+	return b
+}
+
+func TestParseHeader(t *testing.T) {
+	h, err := ParseHeader(frame(mpeg2Mono48k))
+	assert.NoError(t, err)
+	assert.Equal(t, Header{Version: MPEG2, Layer: 3, Bitrate: 48000, SampleRate: 24000, Mono: true}, h)
+	assert.Equal(t, 144, h.Size())
+	assert.Equal(t, 576, h.Samples())
+	assert.Equal(t, 24*time.Millisecond, h.Duration())
+
+	// MPEG1 layer 3, 128 kbps, 44.1 kHz, padded, joint stereo.
+	h, err = ParseHeader([]byte{0xFF, 0xFB, 0x92, 0x40})
+	assert.NoError(t, err)
+	assert.Equal(t, MPEG1, h.Version)
+	assert.Equal(t, 44100, h.SampleRate)
+	assert.Equal(t, 418, h.Size())
+
+	_, err = ParseHeader([]byte{0xFF, 0xFB, 0xF2, 0x40})
+	assert.Error(t, err, "bitrate index 15 is invalid")
+	_, err = ParseHeader([]byte("ID3\x03"))
+	assert.Error(t, err)
+}
+
+func TestDuration(t *testing.T) {
+	var b []byte
+	for i := 0; i < 50; i++ {
+		b = append(b, frame(mpeg2Mono48k)...)
+	}
+	d, err := Duration(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 1200*time.Millisecond, d)
+
+	// an ID3v2 tag and a Xing header are not audio, the Xing frame count is trusted.
+	tag := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x05"), "title"...)
+	withInfo := append(append(append([]byte(nil), tag...), xing(mpeg2Mono48k, 100)...), b...)
+	d, err = Duration(withInfo)
+	assert.NoError(t, err)
+	assert.Equal(t, 2400*time.Millisecond, d)
+
+	// concatenated segments each carrying a tag and an Info frame, written in odd sized pieces.
+	stream := append(append([]byte(nil), withInfo...), withInfo...)
+	var c Counter
+	for len(stream) > 0 {
+		n := 7
+		if n > len(stream) {
+			n = len(stream)
+		}
+		c.Write(stream[:n])
+		stream = stream[n:]
+	}
+	assert.Equal(t, 100, c.Frames())
+	assert.Equal(t, 2400*time.Millisecond, c.Duration())
+
+	_, err = Duration([]byte("RIFF not an mp3 stream"))
+	assert.Equal(t, ErrNoFrames, err)
+}
+
+// segment returns an MP3 file of n frames preceded by an ID3v2 tag and an Info frame.
+func segment(n int) []byte {
+	b := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x05"), "title"...)
+	b = append(b, xing(mpeg2Mono48k, n)...)
+	for i := 0; i < n; i++ {
+		b = append(b, frame(mpeg2Mono48k)...)
+	}
+	return b
+}
+
+func TestStream(t *testing.T) {
+	s, err := Parse(segment(50))
+	assert.NoError(t, err)
+	assert.Len(t, s.Frames, 50)
+	assert.Equal(t, 1200*time.Millisecond, s.Duration())
+	assert.Equal(t, "MPEG2 layer 3 24000 Hz mono", s.Frames[0].String())
+
+	assert.NoError(t, s.AppendSilence(100*time.Millisecond))
+	assert.Len(t, s.Frames, 54, "100 ms rounds to four 24 ms frames")
+	assert.Equal(t, frame(mpeg2Mono48k), s.Frames[53].Data)
+
+	s.Trim(100*time.Millisecond, 50*time.Millisecond)
+	assert.Len(t, s.Frames, 48)
+
+	// a first frame borrowing from the bit reservoir of dropped frames is replaced with silence.
+	s.Frames[1].Data = append([]byte(nil), s.Frames[1].Data...)
+	s.Frames[1].Data[HeaderSize] = 5
+	s.Trim(24*time.Millisecond, 0)
+	assert.Equal(t, frame(mpeg2Mono48k), s.Frames[0].Data)
+
+	other, err := Parse(frame(0xFFFB9040)) // MPEG1 44.1 kHz stereo
+	assert.NoError(t, err)
+	assert.Error(t, s.Append(other))
+}
+
+func TestFixReservoir(t *testing.T) {
+	// each frame holds 144 - 4 - 9 = 131 bytes of main data, so borrowing 200 bytes reaches two frames back.
+	begins := []byte{200, 200, 200, 255, 255}
+	s := &Stream{}
+	for i, begin := range begins {
+		b := frame(mpeg2Mono48k)
+		b[HeaderSize] = begin
+		b[HeaderSize+1] = 0x80    // part2_3_length
+		b[len(b)-1] = byte(i + 1) // main data
+		h, _ := ParseHeader(b)
+		s.Frames = append(s.Frames, Frame{h, b})
+	}
+	joined, err := Parse(frame(mpeg2Mono48k))
+	assert.NoError(t, err)
+	assert.NoError(t, joined.Append(s))
+
+	for i, f := range joined.Frames[1:] {
+		muted := i < 2
+		assert.Equal(t, muted, f.Data[HeaderSize] == 0 && f.Data[HeaderSize+1] == 0, "frame %d", i)
+		assert.Equal(t, byte(i+1), f.Data[len(f.Data)-1], "main data of frame %d is kept", i)
+	}
+
+	// the CRC of a protected frame is updated.
+	b := frame(mpeg2Mono48k &^ (1 << 16))
+	b[HeaderSize+2] = 1
+	h, _ := ParseHeader(b)
+	s = &Stream{Frames: []Frame{{h, b}}}
+	s.fixReservoir(0)
+	f := s.Frames[0].Data
+	assert.Equal(t, make([]byte, 9), f[HeaderSize+2:HeaderSize+11])
+	assert.Equal(t, frameCRC(append([]byte{f[2], f[3]}, f[HeaderSize+2:HeaderSize+11]...)), binary.BigEndian.Uint16(f[HeaderSize:]))
+	assert.Equal(t, uint16(0xaee7), frameCRC([]byte("123456789")))
+}
+
+func TestConcat(t *testing.T) {
+	b, err := Concat([][]byte{segment(50), segment(25)}, 240*time.Millisecond, false)
+	assert.NoError(t, err)
+	assert.Len(t, b, 85*144)
+	assert.NotContains(t, string(b), "ID3")
+	assert.NotContains(t, string(b), "Info")
+
+	b, err = Concat([][]byte{segment(50), segment(25)}, 240*time.Millisecond, true)
+	assert.NoError(t, err)
+	d, err := Duration(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 2040*time.Millisecond, d)
+
+	h, err := ParseHeader(b)
+	assert.NoError(t, err)
+	info := b[:h.Size()]
+	n, ok := InfoFrames(h, info)
+	assert.True(t, ok)
+	assert.Equal(t, 85, n)
+
+	off := HeaderSize + 9
+	assert.Equal(t, "Info", string(info[off:off+4]))
+	assert.Equal(t, uint32(len(b)), binary.BigEndian.Uint32(info[off+12:]))
+	toc := info[off+16 : off+116]
+	for i := 1; i < len(toc); i++ {
+		assert.True(t, toc[i] >= toc[i-1], "seek table must not decrease")
+	}
+	lame := info[off+xingSize:]
+	assert.Equal(t, "LAME", string(lame[:4]))
+	assert.Equal(t, crc16(info[:off+xingSize+34]), binary.BigEndian.Uint16(lame[34:]))
+	assert.Equal(t, crc16(b[h.Size():]), binary.BigEndian.Uint16(lame[32:]))
+
+	_, err = Concat([][]byte{segment(1), []byte("no audio")}, 0, false)
+	assert.Error(t, err)
+}
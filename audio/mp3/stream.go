@@ -0,0 +1,327 @@
+package mp3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Frame is a single MPEG audio frame.
+type Frame struct {
+	Header
+	Data []byte // the whole frame, header included
+}
+
+// String describes the stream format of h, e.g. "MPEG2 layer 3 24000 Hz mono".
+func (h Header) String() string {
+	version := map[int]string{MPEG1: "MPEG1", MPEG2: "MPEG2", MPEG25: "MPEG2.5"}[h.Version]
+	channels := "stereo"
+	if h.Mono {
+		channels = "mono"
+	}
+	return fmt.Sprintf("%s layer %d %d Hz %s", version, h.Layer, h.SampleRate, channels)
+}
+
+// Stream is a sequence of MPEG audio frames of the same format, without metadata.
+type Stream struct {
+	Frames []Frame
+}
+
+// Parse splits an MP3 file into its audio frames. ID3 tags, Xing, Info and VBRI frames and data between frames are
+// dropped, so the frames of several files can be joined. A truncated last frame is dropped as well.
+func Parse(b []byte) (*Stream, error) {
+	s := &Stream{}
+	var err error
+	scan(b, func(h Header, frame []byte) {
+		if len(s.Frames) > 0 && err == nil && !compatible(s.Frames[0].Header, h) {
+			err = fmt.Errorf("%s frame in %s stream", h, s.Frames[0].Header)
+		}
+		s.Frames = append(s.Frames, Frame{h, frame})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Frames) == 0 {
+		return nil, ErrNoFrames
+	}
+	return s, nil
+}
+
+// compatible reports whether frames with headers a and b can follow each other in a stream. The bitrate may vary.
+func compatible(a, b Header) bool {
+	return a.Version == b.Version && a.Layer == b.Layer && a.SampleRate == b.SampleRate && a.Mono == b.Mono
+}
+
+// Duration returns the play time of the stream.
+func (s *Stream) Duration() time.Duration {
+	if len(s.Frames) == 0 {
+		return 0
+	}
+	var n int64
+	for _, f := range s.Frames {
+		n += int64(f.Samples())
+	}
+	return time.Duration(n * int64(time.Second) / int64(s.Frames[0].SampleRate))
+}
+
+// Append adds the frames of o to the end of s. Both streams must share version, layer, sample rate and channel
+// mode.
+func (s *Stream) Append(o *Stream) error {
+	if len(o.Frames) == 0 {
+		return nil
+	}
+	if len(s.Frames) > 0 && !compatible(s.Frames[0].Header, o.Frames[0].Header) {
+		return fmt.Errorf("cannot append %s audio to %s audio", o.Frames[0].Header, s.Frames[0].Header)
+	}
+	i := len(s.Frames)
+	s.Frames = append(s.Frames, o.Frames...)
+	// the frames preceding o's first frame are not the ones it was encoded after.
+	s.fixReservoir(i)
+	return nil
+}
+
+// AppendSilence adds silent frames amounting to d, rounded to the nearest frame, to the end of s. The frames copy
+// the format and bitrate of the last frame, so a constant bitrate stream stays constant.
+func (s *Stream) AppendSilence(d time.Duration) error {
+	if len(s.Frames) == 0 {
+		return fmt.Errorf("cannot derive the format of silence for an empty stream")
+	}
+	f := silentFrame(s.Frames[len(s.Frames)-1], 0)
+	n := int((d + f.Duration()/2) / f.Duration())
+	for i := 0; i < n; i++ {
+		s.Frames = append(s.Frames, f)
+	}
+	return nil
+}
+
+// Trim removes whole frames amounting to at most head from the start and at most tail from the end of s.
+func (s *Stream) Trim(head, tail time.Duration) {
+	var d time.Duration
+	start := 0
+	for ; start < len(s.Frames) && d+s.Frames[start].Duration() <= head; start++ {
+		d += s.Frames[start].Duration()
+	}
+	d = 0
+	end := len(s.Frames)
+	for ; end > start && d+s.Frames[end-1].Duration() <= tail; end-- {
+		d += s.Frames[end-1].Duration()
+	}
+	s.Frames = s.Frames[start:end]
+	s.fixReservoir(0)
+}
+
+// Bytes returns the frames of s as an MP3 file.
+func (s *Stream) Bytes() []byte {
+	var n int
+	for _, f := range s.Frames {
+		n += len(f.Data)
+	}
+	b := make([]byte, 0, n)
+	for _, f := range s.Frames {
+		b = append(b, f.Data...)
+	}
+	return b
+}
+
+// fixReservoir mutes the layer 3 frames from i on that borrow data from the frames before i (the bit reservoir),
+// which are not the frames they were encoded after. A frame may borrow up to 511 bytes for MPEG1 and 255 otherwise,
+// which can span several frames at low bitrates, so frames are checked until the main data following the join
+// covers that much.
+func (s *Stream) fixReservoir(i int) {
+	avail := 0 // bytes of main data from frame i on
+	for ; i < len(s.Frames) && s.Frames[i].Layer == 3; i++ {
+		f := s.Frames[i]
+		off := HeaderSize
+		if f.Data[1]&1 == 0 {
+			off += 2 // CRC
+		}
+		// main_data_begin takes the first 9 bits of the side information for MPEG1, 8 bits otherwise.
+		begin, limit := int(f.Data[off]), 255
+		if f.Version == MPEG1 {
+			begin, limit = begin<<1|int(f.Data[off+1]>>7), 511
+		}
+		if avail >= limit {
+			return
+		}
+		if begin > avail {
+			s.Frames[i] = mutedFrame(f, off)
+		}
+		avail += len(f.Data) - off - f.sideInfoSize()
+	}
+}
+
+// mutedFrame returns f, whose side information starts at off, with that side information zeroed: the frame decodes
+// to silence and borrows no data, while its main data stays in place for the frames after it to borrow.
+func mutedFrame(f Frame, off int) Frame {
+	b := append([]byte(nil), f.Data...)
+	side := b[off : off+f.sideInfoSize()]
+	for j := range side {
+		side[j] = 0
+	}
+	if off > HeaderSize {
+		binary.BigEndian.PutUint16(b[HeaderSize:], frameCRC(append(b[2:4:4], side...)))
+	}
+	return Frame{f.Header, b}
+}
+
+// frameCRC returns the CRC-16 protecting a frame, computed over the last two header bytes and the side information
+// (polynomial 0x8005, initial value 0xffff).
+func frameCRC(b []byte) uint16 {
+	c := uint16(0xffff)
+	for _, v := range b {
+		c ^= uint16(v) << 8
+		for i := 0; i < 8; i++ {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ 0x8005
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
+
+// silentFrame returns a frame of silence in the format of f with the given bitrate, or the bitrate of f when zero.
+// All side information is zero, so no data is borrowed from or lent to neighbouring frames.
+func silentFrame(f Frame, bitrate int) Frame {
+	h := f.Header
+	h.Padding = false
+	if bitrate != 0 {
+		h.Bitrate = bitrate
+	}
+	b := make([]byte, h.Size())
+	copy(b, f.Data[:HeaderSize])
+	b[1] |= 1 // no CRC
+	b[2] = byte(bitrateIndex(h))<<4 | b[2]&0x0d
+	return Frame{h, b}
+}
+
+// bitrateIndex returns the header index of the bitrate of h.
+func bitrateIndex(h Header) int {
+	for i, kbps := range bitrates[h.bitrateTable()] {
+		if i > 0 && kbps*1000 == h.Bitrate {
+			return i
+		}
+	}
+	return 0
+}
+
+// Xing header layout: tag, flags, frame count, byte count, seek table and quality, followed by the LAME extension.
+const (
+	xingSize = 4 + 4 + 4 + 4 + 100 + 4
+	lameSize = 36
+)
+
+// InfoFrame returns a frame carrying a Xing header (Info for constant bitrate streams) with a LAME extension that
+// describes s, to be placed in front of its frames. It records the frame and byte counts and a seek table, which
+// lets players show the correct duration and seek accurately in variable bitrate streams.
+func (s *Stream) InfoFrame() []byte {
+	if len(s.Frames) == 0 {
+		return nil
+	}
+	first := s.Frames[0]
+	off := HeaderSize + first.sideInfoSize()
+
+	// the tag frame uses the lowest bitrate whose frames are large enough to hold it.
+	f := first
+	for _, kbps := range bitrates[first.bitrateTable()][1:15] {
+		f.Bitrate, f.Padding = kbps*1000, false
+		if f.Size() >= off+xingSize+lameSize {
+			break
+		}
+	}
+	b := silentFrame(f, f.Bitrate).Data
+
+	cbr := true
+	total := len(b)
+	var samples int64
+	for _, fr := range s.Frames {
+		cbr = cbr && fr.Bitrate == first.Bitrate
+		total += len(fr.Data)
+		samples += int64(fr.Samples())
+	}
+
+	x := b[off:]
+	copy(x, "Xing")
+	if cbr {
+		copy(x, "Info")
+	}
+	binary.BigEndian.PutUint32(x[4:], 0x0f) // frames, bytes, seek table and quality present
+	binary.BigEndian.PutUint32(x[8:], uint32(len(s.Frames)))
+	binary.BigEndian.PutUint32(x[12:], uint32(total))
+
+	// entry i of the seek table is the byte position, in 256ths of the file, at which i percent of the play time
+	// is reached.
+	pos, n := len(b), int64(0)
+	j := 0
+	for i := 0; i < 100; i++ {
+		for j < len(s.Frames) && n*100 < samples*int64(i) {
+			n += int64(s.Frames[j].Samples())
+			pos += len(s.Frames[j].Data)
+			j++
+		}
+		v := pos * 256 / total
+		if v > 255 {
+			v = 255
+		}
+		x[16+i] = byte(v)
+	}
+
+	var music []byte
+	for _, fr := range s.Frames {
+		music = append(music, fr.Data...)
+	}
+	l := x[xingSize:]
+	copy(l, "LAME3.100")
+	if cbr {
+		l[9] = 1 // VBR method: constant bitrate
+		if kbps := first.Bitrate / 1000; kbps < 255 {
+			l[20] = byte(kbps)
+		}
+	}
+	binary.BigEndian.PutUint32(l[28:], uint32(total))
+	binary.BigEndian.PutUint16(l[32:], crc16(music))
+	binary.BigEndian.PutUint16(l[34:], crc16(b[:off+xingSize+34]))
+	return b
+}
+
+// crc16 computes the CRC-16 (polynomial 0x8005, reflected) used by the LAME extension.
+func crc16(b []byte) uint16 {
+	var c uint16
+	for _, v := range b {
+		c ^= uint16(v)
+		for i := 0; i < 8; i++ {
+			if c&1 != 0 {
+				c = c>>1 ^ 0xa001
+			} else {
+				c >>= 1
+			}
+		}
+	}
+	return c
+}
+
+// Concat joins MP3 files into a single stream. The ID3 tags and Xing headers of every segment are dropped, and gap
+// of silence is inserted between consecutive segments. With info set a Xing/LAME frame describing the result is
+// put in front of it.
+func Concat(segments [][]byte, gap time.Duration, info bool) ([]byte, error) {
+	out := &Stream{}
+	for i, seg := range segments {
+		s, err := Parse(seg)
+		if err != nil {
+			return nil, fmt.Errorf("segment %d, %v", i, err)
+		}
+		if i > 0 && gap > 0 {
+			if err := out.AppendSilence(gap); err != nil {
+				return nil, err
+			}
+		}
+		if err := out.Append(s); err != nil {
+			return nil, fmt.Errorf("segment %d, %v", i, err)
+		}
+	}
+	if !info {
+		return out.Bytes(), nil
+	}
+	return append(out.InfoFrame(), out.Bytes()...), nil
+}
@@ -0,0 +1,173 @@
+// Package ogg reads and writes Ogg pages (RFC 3533). It measures the duration of the Ogg Opus outputs of the
+// text-to-speech service, and extracts their Opus streams to join them or move them to another container.
+package ogg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linexjlin/azuretexttospeech/audio/opus"
+)
+
+// Page header flags.
+const (
+	Continued byte = 0x01 // the first packet continues from the previous page
+	BOS       byte = 0x02 // first page of a logical stream
+	EOS       byte = 0x04 // last page of a logical stream
+)
+
+// headerSize is the size of a page header without its segment table.
+const headerSize = 27
+
+// ErrNotOgg is returned when the input does not start with an Ogg page.
+var ErrNotOgg = errors.New("not an Ogg stream")
+
+// errShort signals that more input is required to parse a page.
+var errShort = errors.New("short page")
+
+// Page is a single Ogg page.
+type Page struct {
+	Flags    byte
+	Granule  int64 // -1 when no packet finishes on this page
+	Serial   uint32
+	Sequence uint32
+	Segments []byte // lacing values
+	Body     []byte
+}
+
+// ParsePage parses the page at the start of b and returns it together with its encoded length. The checksum is
+// verified. The returned page refers to b.
+func ParsePage(b []byte) (*Page, int, error) {
+	p, n, err := parsePage(b)
+	if err == errShort {
+		if len(b) >= 4 && string(b[:4]) != "OggS" {
+			return nil, 0, ErrNotOgg
+		}
+		return nil, 0, fmt.Errorf("truncated ogg page")
+	}
+	return p, n, err
+}
+
+func parsePage(b []byte) (*Page, int, error) {
+	if len(b) < headerSize {
+		return nil, 0, errShort
+	}
+	if string(b[:4]) != "OggS" || b[4] != 0 {
+		return nil, 0, ErrNotOgg
+	}
+	nseg := int(b[26])
+	if len(b) < headerSize+nseg {
+		return nil, 0, errShort
+	}
+	size := headerSize + nseg
+	for _, l := range b[headerSize : headerSize+nseg] {
+		size += int(l)
+	}
+	if len(b) < size {
+		return nil, 0, errShort
+	}
+	if crc(b[:size]) != binary.LittleEndian.Uint32(b[22:26]) {
+		return nil, 0, fmt.Errorf("ogg page checksum mismatch")
+	}
+	return &Page{
+		Flags:    b[5],
+		Granule:  int64(binary.LittleEndian.Uint64(b[6:14])),
+		Serial:   binary.LittleEndian.Uint32(b[14:18]),
+		Sequence: binary.LittleEndian.Uint32(b[18:22]),
+		Segments: b[headerSize : headerSize+nseg],
+		Body:     b[headerSize+nseg : size],
+	}, size, nil
+}
+
+// crcTable is the lookup table of the Ogg checksum: polynomial 0x04c11db7, not reflected, zero initial value.
+var crcTable = func() (t [256]uint32) {
+	for i := range t {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		t[i] = r
+	}
+	return t
+}()
+
+// crc computes the checksum of an encoded page, treating its checksum field as zero.
+func crc(page []byte) uint32 {
+	var c uint32
+	for i, v := range page {
+		if i >= 22 && i < 26 {
+			v = 0
+		}
+		c = c<<8 ^ crcTable[byte(c>>24)^v]
+	}
+	return c
+}
+
+// Counter measures the duration of an Ogg Opus stream written to it incrementally, from the granule position of
+// the last complete page and the pre-skip of the identification header.
+type Counter struct {
+	buf     []byte
+	err     error
+	head    bool
+	preSkip int64
+	granule int64
+}
+
+// Write consumes the next part of the stream. It fails once the stream turns out not to be Ogg Opus.
+func (c *Counter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.buf = append(c.buf, p...)
+	off := 0
+	for {
+		page, n, err := parsePage(c.buf[off:])
+		if err == errShort {
+			break
+		}
+		if err != nil {
+			c.err = err
+			return 0, err
+		}
+		off += n
+		if !c.head {
+			h, err := opus.ParseHead(page.Body)
+			if err != nil {
+				c.err = err
+				return 0, err
+			}
+			c.head, c.preSkip = true, int64(h.PreSkip)
+		}
+		if page.Granule >= 0 {
+			c.granule = page.Granule
+		}
+	}
+	c.buf = append(c.buf[:0], c.buf[off:]...)
+	return len(p), nil
+}
+
+// Duration returns the play time of the complete pages written so far.
+func (c *Counter) Duration() time.Duration {
+	if c.granule <= c.preSkip {
+		return 0
+	}
+	return opus.Duration(c.granule - c.preSkip)
+}
+
+// Duration returns the play time of an Ogg Opus stream held in memory.
+func Duration(b []byte) (time.Duration, error) {
+	var c Counter
+	if _, err := c.Write(b); err != nil {
+		return 0, err
+	}
+	if !c.head {
+		return 0, ErrNotOgg
+	}
+	return c.Duration(), nil
+}
@@ -0,0 +1,132 @@
+package ogg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/linexjlin/azuretexttospeech/audio/opus"
+	"github.com/stretchr/testify/assert"
+)
+
+// page encodes a single packet page.
+func page(flags byte, granule int64, seq uint32, packet []byte) []byte {
+	p := single(packet, flags)
+	p.Granule, p.Serial, p.Sequence = granule, 0x1234, seq
+	return p.Bytes()
+}
+
+// opusStream returns an Ogg Opus stream with a pre-skip of 312 samples and n 20 ms packets, one per page.
+func opusStream(n int) []byte {
+	head := (&opus.Head{Version: 1, Channels: 1, PreSkip: 312, InputRate: 24000}).Bytes()
+	b := page(BOS, 0, 0, head)
+	b = append(b, page(0, 0, 1, []byte("OpusTags\x00\x00\x00\x00\x00\x00\x00\x00"))...)
+	for i := 1; i <= n; i++ {
+		flags := byte(0)
+		if i == n {
+			flags = EOS
+		}
+		b = append(b, page(flags, int64(960*i), uint32(i+1), append([]byte{0xFC}, make([]byte, 299)...))...)
+	}
+	return b
+}
+
+func TestParsePage(t *testing.T) {
+	b := opusStream(1)
+	p, n, err := ParsePage(b)
+	assert.NoError(t, err)
+	assert.Equal(t, BOS, p.Flags)
+	assert.Equal(t, uint32(0x1234), p.Serial)
+	h, err := opus.ParseHead(p.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(312), h.PreSkip)
+
+	// the audio page's 300 byte packet spans two lacing values.
+	_, n2, err := ParsePage(b[n:])
+	assert.NoError(t, err)
+	p, _, err = ParsePage(b[n+n2:])
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{255, 45}, p.Segments)
+
+	b[n+n2+40] ^= 1
+	_, _, err = ParsePage(b[n+n2:])
+	assert.Error(t, err, "checksum mismatch")
+	_, _, err = ParsePage(b[:20])
+	assert.Error(t, err)
+	_, _, err = ParsePage([]byte("RIFF\x00\x00\x00\x00WAVEfmt "))
+	assert.Equal(t, ErrNotOgg, err)
+}
+
+func TestDuration(t *testing.T) {
+	b := opusStream(50)
+	d, err := Duration(b)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second-6500*time.Microsecond, d)
+
+	var c Counter
+	for len(b) > 0 {
+		n := 100
+		if n > len(b) {
+			n = len(b)
+		}
+		c.Write(b[:n])
+		b = b[n:]
+	}
+	assert.Equal(t, time.Second-6500*time.Microsecond, c.Duration())
+
+	_, err = Duration([]byte("ID3 not an ogg stream, long enough"))
+	assert.Equal(t, ErrNotOgg, err)
+}
+
+// packets returns n 20 ms CELT packets.
+func packets(n int) [][]byte {
+	p := make([][]byte, n)
+	for i := range p {
+		p[i] = append([]byte{0xFC}, make([]byte, 60+i%200)...)
+	}
+	return p
+}
+
+func TestEncode(t *testing.T) {
+	s := &opus.Stream{Head: &opus.Head{Version: 1, Channels: 1, PreSkip: 312, InputRate: 24000}, Packets: packets(120), Discard: 100}
+	b := Encode(s, 7)
+	d, err := Duration(b)
+	assert.NoError(t, err)
+	assert.Equal(t, s.Duration(), d)
+
+	decoded, serial, err := Decode(b)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(7), serial)
+	assert.Equal(t, s.Packets, decoded.Packets)
+	assert.Equal(t, s.Head, decoded.Head)
+	assert.Equal(t, int64(100), decoded.Discard)
+	assert.Equal(t, "OpusTags", string(decoded.Tags[:8]))
+
+	// pages hold at most a second of audio and the last one ends the stream.
+	var pages []*Page
+	for len(b) > 0 {
+		p, n, err := ParsePage(b)
+		assert.NoError(t, err)
+		pages = append(pages, p)
+		b = b[n:]
+	}
+	assert.Len(t, pages, 5)
+	assert.Equal(t, int64(50*960), pages[2].Granule)
+	assert.Equal(t, EOS, pages[4].Flags)
+}
+
+func TestConcat(t *testing.T) {
+	b, err := Concat([][]byte{opusStream(50), opusStream(25)})
+	assert.NoError(t, err)
+	s, serial, err := Decode(b)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0x1234), serial)
+	assert.Len(t, s.Packets, 75)
+
+	// the pre-skip of the second segment is played, granule positions run on.
+	d, err := Duration(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond-6500*time.Microsecond, d)
+
+	_, err = Concat([][]byte{opusStream(1), []byte("RIFF")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,169 @@
+package ogg
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/linexjlin/azuretexttospeech/audio/opus"
+)
+
+// Page size limits used when writing. A page is flushed once it holds a second of audio.
+const (
+	maxSegments    = 255
+	maxPageSamples = opus.SampleRate
+)
+
+// Bytes encodes p, computing its checksum.
+func (p *Page) Bytes() []byte {
+	b := make([]byte, headerSize, headerSize+len(p.Segments)+len(p.Body))
+	copy(b, "OggS")
+	b[5] = p.Flags
+	binary.LittleEndian.PutUint64(b[6:], uint64(p.Granule))
+	binary.LittleEndian.PutUint32(b[14:], p.Serial)
+	binary.LittleEndian.PutUint32(b[18:], p.Sequence)
+	b[26] = byte(len(p.Segments))
+	b = append(append(b, p.Segments...), p.Body...)
+	binary.LittleEndian.PutUint32(b[22:], crc(b))
+	return b
+}
+
+// Decode extracts the Opus stream from an Ogg Opus file. Pages of other logical streams are ignored. The serial
+// number of the stream is returned so it can be preserved when the stream is written again.
+func Decode(b []byte) (*opus.Stream, uint32, error) {
+	s := &opus.Stream{}
+	var serial uint32
+	var packet []byte
+	var packets, granule int64
+	started := false
+
+	for len(b) > 0 {
+		p, n, err := ParsePage(b)
+		if err != nil {
+			return nil, 0, err
+		}
+		b = b[n:]
+		if !started {
+			if p.Flags&BOS == 0 {
+				return nil, 0, fmt.Errorf("ogg stream does not start with a BOS page")
+			}
+			serial, started = p.Serial, true
+		}
+		if p.Serial != serial {
+			continue
+		}
+
+		body := p.Body
+		for _, l := range p.Segments {
+			packet = append(packet, body[:l]...)
+			body = body[l:]
+			if l == 255 {
+				continue
+			}
+			// a lacing value below 255 ends the packet.
+			switch packets {
+			case 0:
+				if s.Head, err = opus.ParseHead(packet); err != nil {
+					return nil, 0, err
+				}
+			case 1:
+				s.Tags = packet
+			default:
+				s.Packets = append(s.Packets, packet)
+			}
+			packets++
+			packet = nil
+		}
+		if p.Granule >= 0 {
+			granule = p.Granule
+		}
+		if p.Flags&EOS != 0 {
+			break
+		}
+	}
+	if s.Head == nil {
+		return nil, 0, ErrNotOgg
+	}
+	// the final granule position is below the sample count when the end of the last packet is to be discarded.
+	if d := s.Samples() - granule; d > 0 && granule > 0 {
+		s.Discard = d
+	}
+	return s, serial, nil
+}
+
+// Encode writes an Opus stream as an Ogg Opus file: the identification and comment headers on pages of their own,
+// followed by pages of audio packets with granule positions counted from the start of the stream.
+func Encode(s *opus.Stream, serial uint32) []byte {
+	var b []byte
+	var seq uint32
+	flush := func(p *Page) {
+		p.Serial, p.Sequence = serial, seq
+		seq++
+		b = append(b, p.Bytes()...)
+	}
+	flush(single(s.Head.Bytes(), BOS))
+	flush(single(s.TagsPacket(), 0))
+
+	total := s.Samples()
+	page := &Page{}
+	var granule, pageSamples int64
+	for i, packet := range s.Packets {
+		lacing := laceValues(len(packet))
+		if len(page.Segments)+len(lacing) > maxSegments || pageSamples >= maxPageSamples {
+			page.Granule = granule
+			flush(page)
+			page, pageSamples = &Page{}, 0
+		}
+		n, _ := opus.PacketSamples(packet)
+		granule += int64(n)
+		pageSamples += int64(n)
+		page.Segments = append(page.Segments, lacing...)
+		page.Body = append(page.Body, packet...)
+		if i == len(s.Packets)-1 {
+			page.Flags |= EOS
+			page.Granule = total - s.Discard
+		}
+	}
+	if len(s.Packets) == 0 {
+		page.Flags |= EOS
+	}
+	flush(page)
+	return b
+}
+
+// single returns a page holding a single packet.
+func single(packet []byte, flags byte) *Page {
+	return &Page{Flags: flags, Segments: laceValues(len(packet)), Body: packet}
+}
+
+// laceValues returns the lacing values of a packet of n bytes.
+func laceValues(n int) []byte {
+	l := make([]byte, n/255+1)
+	for i := range l[:len(l)-1] {
+		l[i] = 255
+	}
+	l[len(l)-1] = byte(n % 255)
+	return l
+}
+
+// Concat joins Ogg Opus files into a single logical stream with the headers of the first file and continuous
+// granule positions, see opus.Stream.Append.
+func Concat(segments [][]byte) ([]byte, error) {
+	out := &opus.Stream{}
+	var serial uint32
+	for i, seg := range segments {
+		s, ser, err := Decode(seg)
+		if err != nil {
+			return nil, fmt.Errorf("segment %d, %v", i, err)
+		}
+		if i == 0 {
+			serial = ser
+		}
+		if err := out.Append(s); err != nil {
+			return nil, fmt.Errorf("segment %d, %v", i, err)
+		}
+	}
+	if out.Head == nil {
+		return nil, fmt.Errorf("no segments")
+	}
+	return Encode(out, serial), nil
+}
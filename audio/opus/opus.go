@@ -0,0 +1,158 @@
+// Package opus parses the parts of an Opus stream needed to handle it without decoding: the identification
+// header and the table-of-contents byte of each packet (RFC 6716 and RFC 7845). Stream carries the packets between
+// the Ogg and WebM containers.
+package opus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SampleRate is the rate at which Opus timestamps and packet durations are counted, whatever the input rate.
+const SampleRate = 48000
+
+// ErrNotOpusHead is returned when a packet is not an Opus identification header.
+var ErrNotOpusHead = errors.New("not an OpusHead packet")
+
+// Head is the identification header starting every Opus stream.
+type Head struct {
+	Version     byte
+	Channels    byte
+	PreSkip     uint16 // samples at 48 kHz to discard from the start of the decoded stream
+	InputRate   uint32 // sample rate of the original input, informational only
+	OutputGain  int16  // Q7.8 dB
+	MappingType byte
+	Mapping     []byte // channel mapping table, present when MappingType is not zero
+}
+
+// ParseHead parses an OpusHead packet.
+func ParseHead(b []byte) (*Head, error) {
+	if len(b) < 19 || string(b[:8]) != "OpusHead" {
+		return nil, ErrNotOpusHead
+	}
+	h := &Head{
+		Version:     b[8],
+		Channels:    b[9],
+		PreSkip:     binary.LittleEndian.Uint16(b[10:12]),
+		InputRate:   binary.LittleEndian.Uint32(b[12:16]),
+		OutputGain:  int16(binary.LittleEndian.Uint16(b[16:18])),
+		MappingType: b[18],
+	}
+	if h.Version>>4 != 0 {
+		return nil, fmt.Errorf("unsupported OpusHead version %d", h.Version)
+	}
+	if h.MappingType != 0 {
+		h.Mapping = append([]byte(nil), b[19:]...)
+	}
+	return h, nil
+}
+
+// Bytes encodes h as an OpusHead packet.
+func (h *Head) Bytes() []byte {
+	b := make([]byte, 19, 19+len(h.Mapping))
+	copy(b, "OpusHead")
+	b[8], b[9] = h.Version, h.Channels
+	binary.LittleEndian.PutUint16(b[10:12], h.PreSkip)
+	binary.LittleEndian.PutUint32(b[12:16], h.InputRate)
+	binary.LittleEndian.PutUint16(b[16:18], uint16(h.OutputGain))
+	b[18] = h.MappingType
+	return append(b, h.Mapping...)
+}
+
+// frameSamples is the frame duration in 48 kHz samples for each TOC configuration.
+var frameSamples = [32]int{
+	// SILK only: 10, 20, 40, 60 ms
+	480, 960, 1920, 2880, 480, 960, 1920, 2880, 480, 960, 1920, 2880,
+	// hybrid: 10, 20 ms
+	480, 960, 480, 960,
+	// CELT only: 2.5, 5, 10, 20 ms
+	120, 240, 480, 960, 120, 240, 480, 960, 120, 240, 480, 960, 120, 240, 480, 960,
+}
+
+// PacketSamples returns the number of 48 kHz samples an Opus packet decodes to.
+func PacketSamples(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, fmt.Errorf("empty opus packet")
+	}
+	frames := 1
+	switch p[0] & 3 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(p) < 2 {
+			return 0, fmt.Errorf("truncated opus packet")
+		}
+		frames = int(p[1] & 0x3f)
+	}
+	return frames * frameSamples[p[0]>>3], nil
+}
+
+// Duration converts a number of 48 kHz samples to a time.Duration.
+func Duration(samples int64) time.Duration {
+	return time.Duration(samples * int64(time.Second) / SampleRate)
+}
+
+// Stream is an Opus stream taken out of its container, which can be written to another container or joined with
+// other streams without re-encoding.
+type Stream struct {
+	Head    *Head
+	Tags    []byte   // OpusTags packet, nil when the container carries none
+	Packets [][]byte // audio packets
+	Discard int64    // samples to discard from the end of the decoded stream
+}
+
+// Samples returns the number of 48 kHz samples the packets decode to, pre-skip and discarded samples included.
+func (s *Stream) Samples() int64 {
+	var n int64
+	for _, p := range s.Packets {
+		if v, err := PacketSamples(p); err == nil {
+			n += int64(v)
+		}
+	}
+	return n
+}
+
+// Duration returns the play time of the stream, less pre-skip and discarded samples.
+func (s *Stream) Duration() time.Duration {
+	n := s.Samples() - s.Discard
+	if s.Head != nil {
+		n -= int64(s.Head.PreSkip)
+	}
+	if n < 0 {
+		return 0
+	}
+	return Duration(n)
+}
+
+// Append adds the packets of o to the end of s. The decoder runs on across the join, so the pre-skip of o, a few
+// milliseconds of encoder warm-up, is played rather than discarded, and only the end trimming of o is kept.
+func (s *Stream) Append(o *Stream) error {
+	if s.Head == nil {
+		*s = *o
+		s.Packets = append([][]byte(nil), o.Packets...)
+		return nil
+	}
+	if o.Head.Channels != s.Head.Channels || o.Head.MappingType != s.Head.MappingType {
+		return fmt.Errorf("cannot append %d channel opus stream to %d channel stream", o.Head.Channels, s.Head.Channels)
+	}
+	s.Packets = append(s.Packets, o.Packets...)
+	s.Discard = o.Discard
+	return nil
+}
+
+// vendor identifies this package in the OpusTags packets it writes.
+const vendor = "azuretexttospeech"
+
+// TagsPacket returns the OpusTags packet of s, or a minimal one without comments when s has none.
+func (s *Stream) TagsPacket() []byte {
+	if s.Tags != nil {
+		return s.Tags
+	}
+	b := make([]byte, 8+4, 8+4+len(vendor)+4)
+	copy(b, "OpusTags")
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(vendor)))
+	b = append(b, vendor...)
+	return append(b, 0, 0, 0, 0) // no user comments
+}
@@ -0,0 +1,38 @@
+package opus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHead(t *testing.T) {
+	h := &Head{Version: 1, Channels: 1, PreSkip: 312, InputRate: 24000, OutputGain: -256}
+	parsed, err := ParseHead(h.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, h, parsed)
+
+	_, err = ParseHead([]byte("OpusTags"))
+	assert.Equal(t, ErrNotOpusHead, err)
+}
+
+func TestPacketSamples(t *testing.T) {
+	for _, c := range []struct {
+		packet  []byte
+		samples int
+	}{
+		{[]byte{0xFC}, 960},        // CELT 20 ms, one frame
+		{[]byte{0x09}, 1920},       // SILK 20 ms, two frames
+		{[]byte{0x1B, 0x03}, 8640}, // SILK 60 ms, three frames
+		{[]byte{0x80}, 120},        // CELT 2.5 ms
+		{[]byte{0x60}, 480},        // hybrid 10 ms
+	} {
+		n, err := PacketSamples(c.packet)
+		assert.NoError(t, err)
+		assert.Equal(t, c.samples, n, "packet %x", c.packet)
+	}
+	_, err := PacketSamples(nil)
+	assert.Error(t, err)
+	assert.Equal(t, 20*time.Millisecond, Duration(960))
+}
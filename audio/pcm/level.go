@@ -0,0 +1,247 @@
+package pcm
+
+import (
+	"math"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+// Gating of the loudness measurement, ITU-R BS.1770-4.
+const (
+	blockDuration = 400 * time.Millisecond
+	blockStep     = 100 * time.Millisecond
+	absoluteGate  = -70.0 // LUFS
+	relativeGate  = -10.0 // LU below the loudness of the blocks above the absolute gate
+)
+
+// Options selects the post-processing done by Apply. The zero value leaves the audio unchanged.
+type Options struct {
+	// Trim removes leading and trailing frames quieter than this level in dBFS, e.g. -50. Zero disables trimming.
+	Trim float64
+	// Head and Tail are lengths of silence added at either end after trimming.
+	Head, Tail time.Duration
+	// Loudness is the integrated loudness to normalize to in LUFS, e.g. -16 or -23. Zero disables loudness
+	// normalization.
+	Loudness float64
+	// Peak is the highest sample peak allowed in dBFS. Without Loudness the audio is normalized to this peak;
+	// with Loudness the gain is reduced where needed to keep below it, 0 dBFS when Peak is zero.
+	Peak float64
+}
+
+// Apply decodes audio synthesized in one of the PCM, RIFF, mu-law or A-law formats, post-processes it as opts
+// selects and encodes it in the same format again. Silence is trimmed first, then the level is normalized and
+// finally padding is added.
+func Apply(audio []byte, format tts.AudioOutput, opts Options) ([]byte, error) {
+	b, err := Decode(audio, format)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Trim != 0 {
+		b = b.TrimSilence(opts.Trim)
+	}
+	switch {
+	case opts.Loudness != 0:
+		b = b.Normalize(opts.Loudness, opts.Peak)
+	case opts.Peak != 0:
+		if peak := b.Peak(); !math.IsInf(peak, -1) {
+			b = b.Gain(opts.Peak - peak)
+		}
+	}
+	if opts.Head > 0 || opts.Tail > 0 {
+		b = b.Pad(opts.Head, opts.Tail)
+	}
+	return Encode(b, format)
+}
+
+// decibels converts an amplitude ratio to dB.
+func decibels(v float64) float64 {
+	return 20 * math.Log10(v)
+}
+
+// amplitude converts dB to an amplitude ratio.
+func amplitude(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// TrimSilence returns the buffer without the leading and trailing frames in which no sample reaches threshold, a
+// level in dBFS. A buffer that is silent throughout is trimmed to nothing.
+func (b *Buffer) TrimSilence(threshold float64) *Buffer {
+	a := amplitude(threshold)
+	loud := func(frame int) bool {
+		for _, s := range b.Samples[frame*b.Channels : (frame+1)*b.Channels] {
+			if math.Abs(s) >= a {
+				return true
+			}
+		}
+		return false
+	}
+	start, end := 0, b.Frames()
+	for start < end && !loud(start) {
+		start++
+	}
+	for end > start && !loud(end-1) {
+		end--
+	}
+	return &Buffer{SampleRate: b.SampleRate, Channels: b.Channels, Samples: b.Samples[start*b.Channels : end*b.Channels]}
+}
+
+// Pad returns the buffer with head and tail of silence added at either end.
+func (b *Buffer) Pad(head, tail time.Duration) *Buffer {
+	frames := func(d time.Duration) int {
+		return int((int64(d)*int64(b.SampleRate) + int64(time.Second)/2) / int64(time.Second))
+	}
+	h, t := frames(head)*b.Channels, frames(tail)*b.Channels
+	out := &Buffer{SampleRate: b.SampleRate, Channels: b.Channels, Samples: make([]float64, h+len(b.Samples)+t)}
+	copy(out.Samples[h:], b.Samples)
+	return out
+}
+
+// Gain returns the buffer amplified by db. Samples pushed beyond full scale are clipped when encoded.
+func (b *Buffer) Gain(db float64) *Buffer {
+	g := amplitude(db)
+	out := &Buffer{SampleRate: b.SampleRate, Channels: b.Channels, Samples: make([]float64, len(b.Samples))}
+	for i, s := range b.Samples {
+		out.Samples[i] = s * g
+	}
+	return out
+}
+
+// Peak returns the highest absolute sample value in dBFS, or -Inf for silence. Inter-sample peaks are not
+// measured.
+func (b *Buffer) Peak() float64 {
+	var peak float64
+	for _, s := range b.Samples {
+		peak = math.Max(peak, math.Abs(s))
+	}
+	return decibels(peak)
+}
+
+// Loudness returns the integrated loudness in LUFS following ITU-R BS.1770-4: the K-weighted mean square over
+// gated 400 ms blocks overlapping by 75%. All channels are weighted equally. Audio shorter than a block is
+// measured as a single block. Silence measures -Inf.
+func (b *Buffer) Loudness() float64 {
+	frames := b.Frames()
+	if frames == 0 || b.SampleRate == 0 {
+		return math.Inf(-1)
+	}
+	// energy[i] is the K-weighted energy of the first i frames, summed over channels.
+	energy := make([]float64, frames+1)
+	for c := 0; c < b.Channels; c++ {
+		shelf, highPass := kWeighting(float64(b.SampleRate))
+		for i := 0; i < frames; i++ {
+			v := highPass.filter(shelf.filter(b.Samples[i*b.Channels+c]))
+			energy[i+1] += v * v
+		}
+	}
+	for i := 1; i <= frames; i++ {
+		energy[i] += energy[i-1]
+	}
+
+	size := int(int64(blockDuration) * int64(b.SampleRate) / int64(time.Second))
+	step := int(int64(blockStep) * int64(b.SampleRate) / int64(time.Second))
+	if size > frames {
+		size = frames
+	}
+	// at sample rates below 10 Hz a step, or even a block, is shorter than a frame.
+	if size < 1 {
+		size = 1
+	}
+	if step < 1 {
+		step = 1
+	}
+	var blocks []float64
+	for start := 0; start+size <= frames; start += step {
+		blocks = append(blocks, (energy[start+size]-energy[start])/float64(size))
+	}
+
+	gated := func(threshold float64) (float64, bool) {
+		var sum float64
+		var n int
+		for _, z := range blocks {
+			if loudness(z) > threshold {
+				sum += z
+				n++
+			}
+		}
+		if n == 0 {
+			return 0, false
+		}
+		return sum / float64(n), true
+	}
+	z, ok := gated(absoluteGate)
+	if !ok {
+		return math.Inf(-1)
+	}
+	if z, ok = gated(loudness(z) + relativeGate); !ok {
+		return math.Inf(-1)
+	}
+	return loudness(z)
+}
+
+// loudness converts a K-weighted mean square to LUFS.
+func loudness(z float64) float64 {
+	return -0.691 + 10*math.Log10(z)
+}
+
+// Normalize returns the buffer amplified to an integrated loudness of lufs, with the gain limited so the sample
+// peak stays at or below peak dBFS. Silence is returned unchanged.
+func (b *Buffer) Normalize(lufs, peak float64) *Buffer {
+	l := b.Loudness()
+	if math.IsInf(l, -1) {
+		return b
+	}
+	gain := lufs - l
+	if p := b.Peak(); p+gain > peak {
+		gain = peak - p
+	}
+	return b.Gain(gain)
+}
+
+// biquad is a second order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) filter(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x1, f.x2 = x, f.x1
+	f.y1, f.y2 = y, f.y1
+	return y
+}
+
+// kWeighting returns the two stages of the K-weighting filter for a sample rate: a high shelf modelling the
+// acoustic effect of the head, and a high pass. The analog prototypes are fitted to the 48 kHz coefficients given
+// in BS.1770 so that other rates get an equivalent response.
+func kWeighting(rate float64) (shelf, highPass *biquad) {
+	const (
+		shelfFreq = 1681.974450955533
+		shelfGain = 3.999843853973347
+		shelfQ    = 0.7071752369554196
+		passFreq  = 38.13547087602444
+		passQ     = 0.5003270373238773
+	)
+	k := math.Tan(math.Pi * shelfFreq / rate)
+	vh := math.Pow(10, shelfGain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+	shelf = &biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	k = math.Tan(math.Pi * passFreq / rate)
+	a0 = 1 + k/passQ + k*k
+	highPass = &biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/passQ + k*k) / a0,
+	}
+	return shelf, highPass
+}
@@ -0,0 +1,207 @@
+// Package pcm converts synthesized audio between linear PCM and G.711 formats locally: sample rate conversion
+// with an anti-aliasing filter, bit depth conversion and channel remixing, as well as silence trimming, padding and
+// loudness normalization. Audio is processed as interleaved float64 samples in the nominal range [-1, 1].
+package pcm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/g711"
+	"github.com/linexjlin/azuretexttospeech/audio/wav"
+)
+
+// Buffer holds decoded audio.
+type Buffer struct {
+	SampleRate int
+	Channels   int
+	Samples    []float64 // interleaved by channel
+}
+
+// Frames returns the number of sample frames, one sample for every channel.
+func (b *Buffer) Frames() int {
+	if b.Channels == 0 {
+		return 0
+	}
+	return len(b.Samples) / b.Channels
+}
+
+// Duration returns the play time of the buffer.
+func (b *Buffer) Duration() time.Duration {
+	if b.SampleRate == 0 {
+		return 0
+	}
+	return time.Duration(int64(b.Frames()) * int64(time.Second) / int64(b.SampleRate))
+}
+
+// Header returns the WAVE header describing the buffer with the given bits per sample.
+func (b *Buffer) Header(bits int) wav.Header {
+	return wav.Header{AudioFormat: wav.FormatPCM, Channels: uint16(b.Channels), SampleRate: uint32(b.SampleRate), BitsPerSample: uint16(bits)}
+}
+
+// Decode converts synthesized PCM, mu-law or A-law audio to a Buffer. Input starting with a RIFF header is parsed
+// as a WAVE file whatever format says, so any PCM or G.711 WAVE file may be decoded; other input is interpreted
+// as raw audio in format.
+func Decode(audio []byte, format tts.AudioOutput) (*Buffer, error) {
+	if bytes.HasPrefix(audio, []byte("RIFF")) {
+		a, err := wav.Decode(audio)
+		if err != nil {
+			return nil, err
+		}
+		return &Buffer{SampleRate: int(a.SampleRate), Channels: int(a.Channels), Samples: DecodeSamples(a.Data, a.Header)}, nil
+	}
+	h, err := wav.HeaderFor(format)
+	if err != nil {
+		return nil, err
+	}
+	return &Buffer{SampleRate: int(h.SampleRate), Channels: int(h.Channels), Samples: DecodeSamples(audio, h)}, nil
+}
+
+// Encode renders buf in format, resampling and remixing as required. RIFF formats include the WAVE header.
+func Encode(buf *Buffer, format tts.AudioOutput) ([]byte, error) {
+	h, err := wav.HeaderFor(format)
+	if err != nil {
+		return nil, err
+	}
+	out := Resample(buf.Remix(int(h.Channels)), int(h.SampleRate))
+	data := EncodeSamples(out.Samples, h)
+	if format.Info().Container == tts.ContainerRIFF {
+		return (&wav.Audio{Header: h, Data: data}).Bytes(), nil
+	}
+	return data, nil
+}
+
+// Convert renders audio synthesized in one PCM, mu-law or A-law format in another, see Decode and Encode.
+func Convert(audio []byte, from, to tts.AudioOutput) ([]byte, error) {
+	buf, err := Decode(audio, from)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(buf, to)
+}
+
+// Remix returns the buffer with n channels. Mono is duplicated to every output channel, anything else is mixed
+// down to mono first.
+func (b *Buffer) Remix(n int) *Buffer {
+	if n == b.Channels || b.Channels == 0 {
+		return b
+	}
+	frames := b.Frames()
+	mono := b.Samples
+	if b.Channels != 1 {
+		mono = make([]float64, frames)
+		for i := range mono {
+			var sum float64
+			for c := 0; c < b.Channels; c++ {
+				sum += b.Samples[i*b.Channels+c]
+			}
+			mono[i] = sum / float64(b.Channels)
+		}
+	}
+	out := &Buffer{SampleRate: b.SampleRate, Channels: n, Samples: make([]float64, frames*n)}
+	for i, s := range mono {
+		for c := 0; c < n; c++ {
+			out.Samples[i*n+c] = s
+		}
+	}
+	return out
+}
+
+// Resample returns the buffer converted to rate, see Resampler.
+func Resample(b *Buffer, rate int) *Buffer {
+	if rate == b.SampleRate {
+		return b
+	}
+	r := NewResampler(b.SampleRate, rate, b.Channels)
+	out := r.Process(b.Samples)
+	out = append(out, r.Flush()...)
+	return &Buffer{SampleRate: rate, Channels: b.Channels, Samples: out}
+}
+
+// DecodeSamples converts sample data described by h to floats. 8 bit PCM is unsigned, wider PCM signed little
+// endian. A trailing partial sample is ignored.
+func DecodeSamples(data []byte, h wav.Header) []float64 {
+	switch h.AudioFormat {
+	case wav.FormatMuLaw, wav.FormatALaw:
+		law := g711.MuLaw
+		if h.AudioFormat == wav.FormatALaw {
+			law = g711.ALaw
+		}
+		out := make([]float64, len(data))
+		for i, v := range data {
+			out[i] = float64(law.Decode(v)) / 32768
+		}
+		return out
+	}
+
+	width := int(h.BitsPerSample) / 8
+	if width == 0 {
+		return nil
+	}
+	out := make([]float64, len(data)/width)
+	for i := range out {
+		p := data[i*width:]
+		switch width {
+		case 1:
+			out[i] = (float64(p[0]) - 128) / 128
+		case 2:
+			out[i] = float64(int16(binary.LittleEndian.Uint16(p))) / 32768
+		case 3:
+			v := int32(uint32(p[0])<<8|uint32(p[1])<<16|uint32(p[2])<<24) >> 8
+			out[i] = float64(v) / (1 << 23)
+		case 4:
+			out[i] = float64(int32(binary.LittleEndian.Uint32(p))) / (1 << 31)
+		}
+	}
+	return out
+}
+
+// EncodeSamples converts floats to sample data described by h, clipping values outside [-1, 1].
+func EncodeSamples(samples []float64, h wav.Header) []byte {
+	switch h.AudioFormat {
+	case wav.FormatMuLaw, wav.FormatALaw:
+		law := g711.MuLaw
+		if h.AudioFormat == wav.FormatALaw {
+			law = g711.ALaw
+		}
+		out := make([]byte, len(samples))
+		for i, s := range samples {
+			out[i] = law.Encode(int16(quantize(s, 16)))
+		}
+		return out
+	}
+
+	width := int(h.BitsPerSample) / 8
+	out := make([]byte, len(samples)*width)
+	for i, s := range samples {
+		p := out[i*width:]
+		v := quantize(s, int(h.BitsPerSample))
+		switch width {
+		case 1:
+			p[0] = byte(v + 128)
+		case 2:
+			binary.LittleEndian.PutUint16(p, uint16(int16(v)))
+		case 3:
+			p[0], p[1], p[2] = byte(v), byte(v>>8), byte(v>>16)
+		case 4:
+			binary.LittleEndian.PutUint32(p, uint32(int32(v)))
+		}
+	}
+	return out
+}
+
+// quantize rounds s to a signed integer of the given bit width, clipping at full scale.
+func quantize(s float64, bits int) int64 {
+	full := float64(int64(1) << uint(bits-1))
+	v := math.Round(s * full)
+	if v > full-1 {
+		v = full - 1
+	}
+	if v < -full {
+		v = -full
+	}
+	return int64(v)
+}
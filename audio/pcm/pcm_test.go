@@ -0,0 +1,220 @@
+package pcm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// tone returns a mono buffer holding d of a sine wave.
+func tone(rate int, freq, amp float64, d time.Duration) *Buffer {
+	b := &Buffer{SampleRate: rate, Channels: 1, Samples: make([]float64, int(d.Seconds()*float64(rate)))}
+	for i := range b.Samples {
+		b.Samples[i] = amp * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))
+	}
+	return b
+}
+
+// rms returns the root mean square of the samples, skipping the filter's settling time at either end.
+func rms(s []float64) float64 {
+	s = s[len(s)/10 : len(s)-len(s)/10]
+	var sum float64
+	for _, v := range s {
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(s)))
+}
+
+func TestSamples(t *testing.T) {
+	in := []float64{0, 0.5, -0.5, 1, -1, 0.25}
+	for _, bits := range []int{8, 16, 24, 32} {
+		h := wav.Header{AudioFormat: wav.FormatPCM, Channels: 1, SampleRate: 8000, BitsPerSample: uint16(bits)}
+		b := EncodeSamples(in, h)
+		assert.Len(t, b, len(in)*bits/8)
+		out := DecodeSamples(b, h)
+		for i := range in {
+			assert.InDelta(t, in[i], out[i], 2/math.Pow(2, float64(bits-1)), "%d bit sample %d", bits, i)
+		}
+	}
+
+	h := wav.Header{AudioFormat: wav.FormatMuLaw, Channels: 1, SampleRate: 8000, BitsPerSample: 8}
+	out := DecodeSamples(EncodeSamples(in, h), h)
+	for i := range in {
+		assert.InDelta(t, in[i], out[i], 0.04)
+	}
+
+	// values beyond full scale are clipped.
+	h.AudioFormat, h.BitsPerSample = wav.FormatPCM, 16
+	assert.Equal(t, []byte{0xff, 0x7f, 0x00, 0x80}, EncodeSamples([]float64{1.5, -3}, h))
+}
+
+func TestResample(t *testing.T) {
+	// a 1 kHz tone passes unchanged.
+	out := Resample(tone(24000, 1000, 0.5, time.Second), 8000)
+	assert.Equal(t, 8000, out.SampleRate)
+	assert.Len(t, out.Samples, 8000)
+	assert.InDelta(t, 0.5/math.Sqrt2, rms(out.Samples), 0.005)
+	for i := 800; i < 7200; i++ {
+		assert.InDelta(t, 0.5*math.Sin(2*math.Pi*1000*float64(i)/8000), out.Samples[i], 0.01)
+	}
+
+	up := Resample(tone(8000, 1000, 0.5, time.Second), 48000)
+	assert.Len(t, up.Samples, 48000)
+	assert.InDelta(t, 0.5/math.Sqrt2, rms(up.Samples), 0.005)
+
+	// a 10 kHz tone is above the Nyquist frequency of 8 kHz audio and must not alias to 2 kHz.
+	out = Resample(tone(48000, 10000, 0.5, time.Second), 8000)
+	assert.Less(t, rms(out.Samples), 0.001)
+}
+
+func TestResamplerChunks(t *testing.T) {
+	in := tone(22050, 440, 0.8, 500*time.Millisecond).Samples
+	whole := Resample(&Buffer{SampleRate: 22050, Channels: 1, Samples: in}, 16000).Samples
+
+	r := NewResampler(22050, 16000, 1)
+	var chunked []float64
+	for i, n := 0, 1; i < len(in); i, n = i+n, n*3%997+1 {
+		end := i + n
+		if end > len(in) {
+			end = len(in)
+		}
+		chunked = append(chunked, r.Process(in[i:end])...)
+	}
+	chunked = append(chunked, r.Flush()...)
+	assert.Equal(t, whole, chunked)
+}
+
+func TestRemix(t *testing.T) {
+	mono := &Buffer{SampleRate: 8000, Channels: 1, Samples: []float64{0.1, 0.2}}
+	stereo := mono.Remix(2)
+	assert.Equal(t, []float64{0.1, 0.1, 0.2, 0.2}, stereo.Samples)
+	assert.Equal(t, 2, stereo.Frames())
+
+	stereo.Samples = []float64{1, 0, 0.5, -0.5}
+	assert.Equal(t, []float64{0.5, 0}, stereo.Remix(1).Samples)
+}
+
+func TestConvert(t *testing.T) {
+	src := EncodeSamples(tone(24000, 400, 0.5, time.Second).Samples, wav.Header{AudioFormat: wav.FormatPCM, Channels: 1, SampleRate: 24000, BitsPerSample: 16})
+
+	b, err := Convert(src, tts.RAW24khz16bitMonoPCM, tts.RIFF8khz8bitMonoMulaw)
+	assert.NoError(t, err)
+	a, err := wav.Decode(b)
+	assert.NoError(t, err)
+	assert.Equal(t, wav.FormatMuLaw, a.AudioFormat)
+	assert.Equal(t, time.Second, a.Duration())
+
+	// back to 16 kHz PCM from the RIFF file, whatever format the input is labelled with.
+	b, err = Convert(b, tts.RAW8khz8bitMonoMulaw, tts.RAW16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Len(t, b, 32000)
+	buf, err := Decode(b, tts.RAW16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5/math.Sqrt2, rms(buf.Samples), 0.01)
+
+	_, err = Convert(src, tts.RAW24khz16bitMonoPCM, tts.AUDIO24khz48kbitrateMonoMP3)
+	assert.Error(t, err)
+}
+
+func TestNewReader(t *testing.T) {
+	riff := (&wav.Audio{
+		Header: wav.Header{AudioFormat: wav.FormatPCM, Channels: 1, SampleRate: 48000, BitsPerSample: 16},
+		Data:   EncodeSamples(tone(48000, 300, 0.3, 300*time.Millisecond).Samples, wav.Header{AudioFormat: wav.FormatPCM, Channels: 1, SampleRate: 48000, BitsPerSample: 16}),
+	}).Bytes()
+	want, err := Convert(riff, tts.RIFF48khz16bitMonoPCM, tts.RAW16khz16bitMonoPCM)
+	assert.NoError(t, err)
+
+	r, err := NewReader(bytes.NewReader(riff), tts.RIFF48khz16bitMonoPCM, tts.RAW16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	got, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	r, err = NewReader(bytes.NewReader(riff), tts.RIFF48khz16bitMonoPCM, tts.RIFF8khz8bitMonoAlaw)
+	assert.NoError(t, err)
+	got, err = ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	a, err := wav.Decode(got)
+	assert.NoError(t, err)
+	assert.Equal(t, wav.FormatALaw, a.AudioFormat)
+	assert.Equal(t, 300*time.Millisecond, a.Duration())
+
+	_, err = NewReader(bytes.NewReader(riff), tts.WEBM24khz16bitMonoOpus, tts.RAW16khz16bitMonoPCM)
+	assert.Error(t, err)
+}
+
+func TestKWeighting(t *testing.T) {
+	// the coefficients published in BS.1770 for 48 kHz.
+	shelf, highPass := kWeighting(48000)
+	assert.InDelta(t, 1.53512485958697, shelf.b0, 1e-9)
+	assert.InDelta(t, -2.69169618940638, shelf.b1, 1e-9)
+	assert.InDelta(t, 1.19839281085285, shelf.b2, 1e-9)
+	assert.InDelta(t, -1.69065929318241, shelf.a1, 1e-9)
+	assert.InDelta(t, 0.73248077421585, shelf.a2, 1e-9)
+	assert.InDelta(t, -1.99004745483398, highPass.a1, 1e-9)
+	assert.InDelta(t, 0.99007225036621, highPass.a2, 1e-9)
+}
+
+func TestLoudness(t *testing.T) {
+	// a 997 Hz sine at -20 dBFS measures -23 LUFS at any rate.
+	for _, rate := range []int{8000, 16000, 24000, 48000} {
+		b := tone(rate, 997, 0.1, 3*time.Second)
+		assert.InDelta(t, -23.0, b.Loudness(), 0.05, "%d Hz", rate)
+		assert.InDelta(t, -20.0, b.Peak(), 0.01, "%d Hz", rate)
+	}
+	// silence is gated out, only the blocks overlapping the tone count.
+	b := tone(24000, 997, 0.1, 2*time.Second).Pad(2*time.Second, 2*time.Second)
+	assert.InDelta(t, b.Loudness(), tone(24000, 997, 0.1, 2*time.Second).Pad(10*time.Second, 10*time.Second).Loudness(), 1e-9)
+
+	assert.True(t, math.IsInf((&Buffer{SampleRate: 8000, Channels: 1, Samples: make([]float64, 8000)}).Loudness(), -1))
+	// blocks shorter than a frame still advance.
+	for _, rate := range []int{1, 4, 9} {
+		l := (&Buffer{SampleRate: rate, Channels: 1, Samples: []float64{0.5, -0.5, 0.5, -0.5, 0.5, -0.5}}).Loudness()
+		assert.False(t, math.IsNaN(l), "%d Hz", rate)
+	}
+
+	n := b.Normalize(-16, 0)
+	assert.InDelta(t, -16.0, n.Loudness(), 0.05)
+	n = b.Normalize(-10, -9)
+	assert.InDelta(t, -9.0, n.Peak(), 0.01, "gain is limited by the peak ceiling")
+}
+
+func TestTrimSilence(t *testing.T) {
+	b := tone(8000, 440, 0.5, time.Second).Pad(300*time.Millisecond, 700*time.Millisecond)
+	assert.Equal(t, 2*time.Second, b.Duration())
+	b.Samples[100] = 0.001 // below the threshold
+
+	trimmed := b.TrimSilence(-50)
+	assert.InDelta(t, float64(time.Second), float64(trimmed.Duration()), float64(time.Millisecond))
+	assert.Len(t, (&Buffer{SampleRate: 8000, Channels: 2, Samples: make([]float64, 100)}).TrimSilence(-50).Samples, 0)
+}
+
+func TestApply(t *testing.T) {
+	in := tone(16000, 997, 0.1, time.Second).Pad(500*time.Millisecond, 0)
+	raw, err := Encode(in, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+
+	out, err := Apply(raw, tts.RIFF16khz16bitMonoPCM, Options{Trim: -50, Head: 100 * time.Millisecond, Tail: 200 * time.Millisecond, Loudness: -16})
+	assert.NoError(t, err)
+	b, err := Decode(out, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.InDelta(t, float64(1300*time.Millisecond), float64(b.Duration()), float64(time.Millisecond))
+	assert.InDelta(t, -16.0, b.TrimSilence(-50).Loudness(), 0.05, "loudness is normalized before padding")
+
+	out, err = Apply(raw, tts.RIFF16khz16bitMonoPCM, Options{Peak: -1})
+	assert.NoError(t, err)
+	b, err = Decode(out, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.InDelta(t, -1.0, b.Peak(), 0.01)
+	assert.Equal(t, 1500*time.Millisecond, b.Duration())
+
+	unchanged, err := Apply(raw, tts.RIFF16khz16bitMonoPCM, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, raw, unchanged)
+}
@@ -0,0 +1,156 @@
+package pcm
+
+import (
+	"math"
+	"sync"
+)
+
+// Resampler filter parameters. Every output sample is computed from the input samples within zeroCrossings
+// periods of the filter's cut-off frequency on either side, weighted by a Kaiser windowed sinc.
+const (
+	zeroCrossings   = 16
+	kernelPrecision = 512 // table entries per zero crossing
+	kaiserBeta      = 8.6 // about 90 dB stop-band attenuation
+	passBand        = 0.95
+)
+
+var (
+	kernelOnce sync.Once
+	kernel     []float64 // windowed sinc sampled at 1/kernelPrecision steps from 0 to zeroCrossings
+)
+
+func initKernel() {
+	n := zeroCrossings*kernelPrecision + 1
+	kernel = make([]float64, n+1) // one extra zero entry for interpolation past the end
+	norm := bessel0(kaiserBeta)
+	for i := 0; i < n; i++ {
+		x := float64(i) / kernelPrecision
+		r := x / zeroCrossings
+		w := bessel0(kaiserBeta*math.Sqrt(1-r*r)) / norm
+		s := 1.0
+		if i > 0 {
+			s = math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+		kernel[i] = s * w
+	}
+}
+
+// bessel0 is the zeroth order modified Bessel function of the first kind.
+func bessel0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+// Resampler converts interleaved samples between sample rates. Input may be fed in chunks of any size; the filter
+// state is carried over so the output does not depend on how the input was split. When downsampling, the input is
+// low-pass filtered below the output's Nyquist frequency to prevent aliasing.
+type Resampler struct {
+	channels int
+	cutoff   float64 // filter cut-off relative to the input Nyquist frequency
+	width    int     // filter half-width in input frames
+
+	from, to int
+	hist     []float64 // input frames not yet consumed, interleaved
+	start    int64     // input frame index of hist[0]
+	in       int64     // input frames received
+	out      int64     // output frames produced
+}
+
+// NewResampler returns a Resampler converting audio with the given number of channels from one sample rate to
+// another.
+func NewResampler(from, to, channels int) *Resampler {
+	kernelOnce.Do(initKernel)
+	r := &Resampler{channels: channels, cutoff: passBand, from: from, to: to}
+	if to < from {
+		r.cutoff *= float64(to) / float64(from)
+	}
+	r.width = int(math.Ceil(zeroCrossings / r.cutoff))
+	// prime the history with silence so the first output frame is centred on the first input frame.
+	r.hist = make([]float64, r.width*channels)
+	r.start = -int64(r.width)
+	return r
+}
+
+// Process consumes interleaved input samples and returns the output samples that can be computed so far. A
+// trailing partial frame is ignored.
+func (r *Resampler) Process(in []float64) []float64 {
+	in = in[:len(in)-len(in)%r.channels]
+	r.hist = append(r.hist, in...)
+	r.in += int64(len(in) / r.channels)
+	return r.drain(r.in * int64(r.to) / int64(r.from))
+}
+
+// Flush returns the output samples still held back by the filter. The Resampler should not be used afterwards.
+func (r *Resampler) Flush() []float64 {
+	r.hist = append(r.hist, make([]float64, (r.width+1)*r.channels)...)
+	// the output length is the input length scaled by the rate ratio, rounded to the nearest frame.
+	return r.drain((r.in*int64(r.to) + int64(r.from)/2) / int64(r.from))
+}
+
+// drain computes output frames while the filter window is covered by the history, up to limit frames in total.
+func (r *Resampler) drain(limit int64) []float64 {
+	var out []float64
+	frames := int64(len(r.hist) / r.channels)
+	for r.out < limit {
+		// output frame k lies at input position k*from/to, kept as an exact fraction so the result does not depend
+		// on how the input was split.
+		num := r.out * int64(r.from)
+		base := num/int64(r.to) - r.start
+		if base+int64(r.width) >= frames {
+			break
+		}
+		out = append(out, r.frame(int(base), float64(num%int64(r.to))/float64(r.to))...)
+		r.out++
+	}
+
+	next := r.out*int64(r.from)/int64(r.to) - r.start
+	if drop := next - int64(r.width) + 1; drop > 0 {
+		if drop > frames {
+			drop = frames
+		}
+		r.hist = append(r.hist[:0], r.hist[drop*int64(r.channels):]...)
+		r.start += drop
+	}
+	return out
+}
+
+// frame computes the output frame at frac frames past hist frame base.
+func (r *Resampler) frame(base int, frac float64) []float64 {
+	out := make([]float64, r.channels)
+	var total float64
+	for i := base - r.width + 1; i <= base+r.width; i++ {
+		w := r.weight(math.Abs(float64(i-base)-frac) * r.cutoff)
+		if w == 0 {
+			continue
+		}
+		total += w
+		for c := 0; c < r.channels; c++ {
+			out[c] += w * r.hist[i*r.channels+c]
+		}
+	}
+	// normalizing by the sum of the weights keeps the gain at exactly one for DC.
+	if total != 0 {
+		for c := range out {
+			out[c] /= total
+		}
+	}
+	return out
+}
+
+// weight interpolates the filter kernel at x zero crossings from its centre.
+func (r *Resampler) weight(x float64) float64 {
+	if x >= zeroCrossings {
+		return 0
+	}
+	x *= kernelPrecision
+	i := int(x)
+	f := x - float64(i)
+	return kernel[i] + f*(kernel[i+1]-kernel[i])
+}
@@ -0,0 +1,106 @@
+package pcm
+
+import (
+	"bytes"
+	"io"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/wav"
+)
+
+// converter converts audio read from r as it arrives.
+type converter struct {
+	r       io.Reader
+	in, out wav.Header
+	rs      *Resampler // nil when the sample rates match
+	w       io.Writer  // receives the converted sample data
+	wav     *wav.Writer
+	pending bytes.Buffer // converted bytes not yet returned
+	buf     []byte
+	carry   []byte // a partial frame left from the previous read
+	err     error
+}
+
+// NewReader returns a reader yielding the audio read from r converted from one PCM, mu-law or A-law format to
+// another, see Convert. Conversion happens incrementally, so the reader can sit between a streamed synthesis
+// response and a player. RIFF output is written with the size fields marked as unknown, like a streamed RIFF
+// response.
+func NewReader(r io.Reader, from, to tts.AudioOutput) (io.Reader, error) {
+	in, err := wav.HeaderFor(from)
+	if err != nil {
+		return nil, err
+	}
+	out, err := wav.HeaderFor(to)
+	if err != nil {
+		return nil, err
+	}
+	if from.Info().Container == tts.ContainerRIFF {
+		wr, err := wav.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		r, in = wr, wr.Header
+	}
+
+	c := &converter{r: r, in: in, out: out, buf: make([]byte, 16*1024)}
+	c.w = &c.pending
+	if to.Info().Container == tts.ContainerRIFF {
+		if c.wav, err = wav.NewWriter(&c.pending, out); err != nil {
+			return nil, err
+		}
+		c.w = c.wav
+	}
+	if in.SampleRate != out.SampleRate {
+		c.rs = NewResampler(int(in.SampleRate), int(out.SampleRate), int(out.Channels))
+	}
+	return c, nil
+}
+
+func (c *converter) Read(p []byte) (int, error) {
+	for c.pending.Len() == 0 && c.err == nil {
+		c.fill()
+	}
+	if c.pending.Len() > 0 {
+		return c.pending.Read(p)
+	}
+	return 0, c.err
+}
+
+// fill converts the next chunk of input into c.pending.
+func (c *converter) fill() {
+	n, err := c.r.Read(c.buf)
+	data := append(c.carry, c.buf[:n]...)
+	whole := len(data) - len(data)%c.in.BlockAlign()
+	c.write(c.convert(DecodeSamples(data[:whole], c.in)))
+	c.carry = append(c.carry[:0], data[whole:]...)
+
+	switch {
+	case err == io.EOF:
+		// a trailing partial frame is dropped.
+		if c.rs != nil {
+			c.write(c.rs.Flush())
+		}
+		if c.wav != nil {
+			c.wav.Close()
+		}
+		c.err = io.EOF
+	case err != nil:
+		c.err = err
+	}
+}
+
+// convert remixes and resamples decoded input samples.
+func (c *converter) convert(samples []float64) []float64 {
+	b := (&Buffer{SampleRate: int(c.in.SampleRate), Channels: int(c.in.Channels), Samples: samples}).Remix(int(c.out.Channels))
+	if c.rs == nil {
+		return b.Samples
+	}
+	return c.rs.Process(b.Samples)
+}
+
+func (c *converter) write(samples []float64) {
+	if len(samples) > 0 {
+		// writes to a bytes.Buffer, directly or through the wav.Writer, do not fail.
+		c.w.Write(EncodeSamples(samples, c.out))
+	}
+}
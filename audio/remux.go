@@ -0,0 +1,42 @@
+package audio
+
+import (
+	"fmt"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/ogg"
+	"github.com/linexjlin/azuretexttospeech/audio/opus"
+	"github.com/linexjlin/azuretexttospeech/audio/webm"
+)
+
+// Remux moves Opus audio synthesized in format from to the container of format to without re-encoding it. Ogg and
+// WebM Opus are supported; audio already in the container of to is returned as is.
+func Remux(audio []byte, from, to tts.AudioOutput) ([]byte, error) {
+	src, dst := from.Info(), to.Info()
+	if src.Codec != tts.CodecOpus || dst.Codec != tts.CodecOpus {
+		return nil, fmt.Errorf("cannot remux %s audio to %s", from, to)
+	}
+	if src.Container == dst.Container {
+		return audio, nil
+	}
+	var s *opus.Stream
+	var err error
+	switch src.Container {
+	case tts.ContainerOgg:
+		s, _, err = ogg.Decode(audio)
+	case tts.ContainerWebM:
+		s, err = webm.Decode(audio)
+	default:
+		return nil, fmt.Errorf("cannot remux %s audio to %s", from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch dst.Container {
+	case tts.ContainerOgg:
+		return ogg.Encode(s, 1), nil
+	case tts.ContainerWebM:
+		return webm.Encode(s), nil
+	}
+	return nil, fmt.Errorf("cannot remux %s audio to %s", from, to)
+}
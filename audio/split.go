@@ -0,0 +1,86 @@
+package audio
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/wav"
+)
+
+// Segment is a named part of a synthesized document.
+type Segment struct {
+	Name   string // the bookmark starting the segment, empty for audio preceding the first bookmark
+	Offset time.Duration
+	Audio  []byte
+}
+
+// Split cuts PCM, mu-law or A-law audio synthesized in format at the bookmarks reported among events, typically
+// collected with SynthesizeEventsWithContext for a document holding <bookmark mark="..."/> elements. Each segment
+// runs from its bookmark to the next one and is encoded in format, so RIFF segments are complete WAVE files.
+// Audio before the first bookmark forms a segment without a name when it is not empty.
+func Split(audio []byte, format tts.AudioOutput, events []tts.Event) ([]Segment, error) {
+	h, err := wav.HeaderFor(format)
+	if err != nil {
+		return nil, err
+	}
+	riff := format.Info().Container == tts.ContainerRIFF
+	data := audio
+	if riff {
+		a, err := wav.Decode(audio)
+		if err != nil {
+			return nil, err
+		}
+		h, data = a.Header, a.Data
+	}
+	align := h.BlockAlign()
+	if align == 0 {
+		return nil, fmt.Errorf("invalid %s audio", format)
+	}
+
+	var marks []tts.Event
+	for _, e := range events {
+		if e.Type == tts.EventBookmark {
+			marks = append(marks, e)
+		}
+	}
+	sort.SliceStable(marks, func(i, j int) bool { return marks[i].Offset < marks[j].Offset })
+
+	// position returns the byte offset of the frame nearest to d.
+	position := func(d time.Duration) int {
+		frames := (int64(d)*int64(h.SampleRate) + int64(time.Second)/2) / int64(time.Second)
+		p := int(frames) * align
+		if p > len(data)-len(data)%align {
+			p = len(data) - len(data)%align
+		}
+		return p
+	}
+	encode := func(b []byte) []byte {
+		if riff {
+			return (&wav.Audio{Header: h, Data: b}).Bytes()
+		}
+		return b
+	}
+
+	var segments []Segment
+	start := 0
+	if len(marks) > 0 {
+		start = position(marks[0].Offset)
+	}
+	if start > 0 {
+		segments = append(segments, Segment{Audio: encode(data[:start])})
+	}
+	for i, m := range marks {
+		end := len(data)
+		if i+1 < len(marks) {
+			end = position(marks[i+1].Offset)
+		}
+		from := position(m.Offset)
+		segments = append(segments, Segment{Name: m.Bookmark, Offset: h.Duration(from), Audio: encode(data[from:end])})
+	}
+	if len(marks) == 0 {
+		segments = append(segments, Segment{Audio: encode(data)})
+	}
+	return segments, nil
+}
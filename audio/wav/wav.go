@@ -0,0 +1,283 @@
+// Package wav reads and writes RIFF/WAVE files holding linear PCM, mu-law or A-law audio. It wraps the headerless
+// raw outputs of the text-to-speech service in a RIFF header, and repairs the size fields of RIFF outputs that
+// were streamed before their length was known.
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+// WAVE format codes supported by this package.
+const (
+	FormatPCM        uint16 = 0x0001
+	FormatALaw       uint16 = 0x0006
+	FormatMuLaw      uint16 = 0x0007
+	formatExtensible uint16 = 0xFFFE
+)
+
+// unknownSize is written into the size fields while the length of the stream is not yet known.
+const unknownSize = 0xFFFFFFFF
+
+// ErrNotWAVE is returned when the input does not start with a RIFF/WAVE header.
+var ErrNotWAVE = errors.New("not a RIFF/WAVE stream")
+
+// Header describes the sample format of a WAVE file.
+type Header struct {
+	AudioFormat   uint16 // one of the Format constants
+	Channels      uint16
+	SampleRate    uint32
+	BitsPerSample uint16
+}
+
+// BlockAlign returns the number of bytes of a single frame, one sample for every channel.
+func (h Header) BlockAlign() int {
+	return int(h.Channels) * int(h.BitsPerSample) / 8
+}
+
+// ByteRate returns the number of bytes of audio per second.
+func (h Header) ByteRate() int {
+	return int(h.SampleRate) * h.BlockAlign()
+}
+
+// Duration returns the play time of n bytes of sample data.
+func (h Header) Duration(n int) time.Duration {
+	if h.ByteRate() == 0 {
+		return 0
+	}
+	return time.Duration(int64(n) * int64(time.Second) / int64(h.ByteRate()))
+}
+
+// HeaderFor returns the Header matching a PCM, mu-law or A-law AudioOutput, regardless of whether format is the
+// raw or the RIFF variant.
+func HeaderFor(format tts.AudioOutput) (Header, error) {
+	info := format.Info()
+	code, ok := map[string]uint16{
+		tts.CodecPCM:   FormatPCM,
+		tts.CodecMulaw: FormatMuLaw,
+		tts.CodecAlaw:  FormatALaw,
+	}[info.Codec]
+	if !ok || (info.Container != tts.ContainerRaw && info.Container != tts.ContainerRIFF) {
+		return Header{}, fmt.Errorf("%s is not a PCM, mu-law or A-law format", format)
+	}
+	return Header{
+		AudioFormat:   code,
+		Channels:      uint16(info.Channels),
+		SampleRate:    uint32(info.SampleRate),
+		BitsPerSample: uint16(info.BitDepth),
+	}, nil
+}
+
+// Audio is a decoded WAVE file.
+type Audio struct {
+	Header
+	Data []byte // sample data, little endian
+}
+
+// Duration returns the play time of the sample data.
+func (a *Audio) Duration() time.Duration {
+	return a.Header.Duration(len(a.Data))
+}
+
+// Bytes encodes a as a complete WAVE file.
+func (a *Audio) Bytes() []byte {
+	b := append(header(a.Header, uint32(len(a.Data))), a.Data...)
+	if len(a.Data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// Decode parses a WAVE file held in memory. Size fields that are zero, 0xFFFFFFFF or larger than the input, as
+// produced by streaming writers, are tolerated and the data chunk is taken to extend to the end of b.
+func Decode(b []byte) (*Audio, error) {
+	l, err := parse(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Audio{Header: l.Header, Data: b[l.dataOff : l.dataOff+l.dataSize]}, nil
+}
+
+// Read decodes a WAVE file from r, see Decode.
+func Read(r io.Reader) (*Audio, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(b)
+}
+
+// Reader reads the sample data of a WAVE stream once its header has been parsed.
+type Reader struct {
+	Header
+	r io.Reader
+}
+
+// NewReader consumes the chunks of r preceding the sample data and returns a Reader for the data itself. A data
+// chunk size of zero or 0xFFFFFFFF, as written by streaming writers, means the data extends to the end of r.
+func NewReader(r io.Reader) (*Reader, error) {
+	b := make([]byte, 12)
+	if _, err := io.ReadFull(r, b); err != nil || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" {
+		return nil, ErrNotWAVE
+	}
+
+	var h Header
+	var haveFmt bool
+	for {
+		if _, err := io.ReadFull(r, b[:8]); err != nil {
+			return nil, fmt.Errorf("no data chunk")
+		}
+		id := string(b[0:4])
+		size := binary.LittleEndian.Uint32(b[4:8])
+
+		if id == "data" {
+			if !haveFmt {
+				return nil, fmt.Errorf("data chunk precedes fmt chunk")
+			}
+			if size != 0 && size != unknownSize {
+				r = io.LimitReader(r, int64(size))
+			}
+			return &Reader{Header: h, r: r}, nil
+		}
+
+		padded := int64(size) + int64(size%2)
+		if id != "fmt " {
+			if _, err := io.CopyN(ioutil.Discard, r, padded); err != nil {
+				return nil, fmt.Errorf("truncated %q chunk", id)
+			}
+			continue
+		}
+		if size > 1024 {
+			return nil, fmt.Errorf("invalid fmt chunk size %d", size)
+		}
+		body := make([]byte, padded)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, fmt.Errorf("truncated %q chunk", id)
+		}
+		var err error
+		if h, err = parseFmt(body[:size]); err != nil {
+			return nil, err
+		}
+		haveFmt = true
+	}
+}
+
+// Read reads sample data.
+func (r *Reader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+// Wrap prefixes headerless audio in a raw PCM, mu-law or A-law format with a RIFF header. Input that already
+// carries a RIFF header is returned with its size fields repaired.
+func Wrap(raw []byte, format tts.AudioOutput) ([]byte, error) {
+	if bytes.HasPrefix(raw, []byte("RIFF")) {
+		b := append([]byte(nil), raw...)
+		return b, FixSizes(b)
+	}
+	h, err := HeaderFor(format)
+	if err != nil {
+		return nil, err
+	}
+	return (&Audio{Header: h, Data: raw}).Bytes(), nil
+}
+
+// FixSizes rewrites the RIFF, data and fact chunk sizes of a WAVE file held in memory to match its length,
+// repairing files written by a streaming writer. The data chunk is assumed to be the last chunk.
+func FixSizes(b []byte) error {
+	l, err := parse(b)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(b[4:8], uint32(len(b)-8))
+	binary.LittleEndian.PutUint32(b[l.dataOff-4:l.dataOff], uint32(l.dataSize))
+	if l.factOff > 0 {
+		binary.LittleEndian.PutUint32(b[l.factOff:l.factOff+4], uint32(l.dataSize/l.BlockAlign()))
+	}
+	return nil
+}
+
+// layout locates the chunks of a WAVE file.
+type layout struct {
+	Header
+	dataOff, dataSize int
+	factOff           int // offset of the fact chunk's sample count, zero when absent
+}
+
+// parse validates the RIFF header of b and returns the sample format together with the location of the sample
+// data.
+func parse(b []byte) (l layout, err error) {
+	if len(b) < 12 || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" {
+		return l, ErrNotWAVE
+	}
+
+	var haveFmt bool
+	off := 12
+	for off+8 <= len(b) {
+		id := string(b[off : off+4])
+		size := int(binary.LittleEndian.Uint32(b[off+4 : off+8]))
+		body := off + 8
+
+		if id == "data" {
+			if !haveFmt {
+				return l, fmt.Errorf("data chunk precedes fmt chunk")
+			}
+			if raw := binary.LittleEndian.Uint32(b[off+4 : off+8]); raw == 0 || raw == unknownSize || int64(body)+int64(raw) > int64(len(b)) {
+				size = len(b) - body
+			}
+			// drop a trailing partial frame, which streaming may leave behind.
+			size -= size % l.BlockAlign()
+			l.dataOff, l.dataSize = body, size
+			return l, nil
+		}
+
+		if size < 0 || body+size > len(b) {
+			return l, fmt.Errorf("truncated %q chunk", id)
+		}
+		switch id {
+		case "fmt ":
+			if l.Header, err = parseFmt(b[body : body+size]); err != nil {
+				return l, err
+			}
+			haveFmt = true
+		case "fact":
+			if size >= 4 {
+				l.factOff = body
+			}
+		}
+		// chunks are padded to an even length.
+		off = body + size + size%2
+	}
+	return l, fmt.Errorf("no data chunk")
+}
+
+func parseFmt(b []byte) (Header, error) {
+	if len(b) < 16 {
+		return Header{}, fmt.Errorf("fmt chunk too short")
+	}
+	h := Header{
+		AudioFormat:   binary.LittleEndian.Uint16(b[0:2]),
+		Channels:      binary.LittleEndian.Uint16(b[2:4]),
+		SampleRate:    binary.LittleEndian.Uint32(b[4:8]),
+		BitsPerSample: binary.LittleEndian.Uint16(b[14:16]),
+	}
+	// WAVE_FORMAT_EXTENSIBLE stores the actual format code in the first two bytes of the sub-format GUID.
+	if h.AudioFormat == formatExtensible && len(b) >= 26 {
+		h.AudioFormat = binary.LittleEndian.Uint16(b[24:26])
+	}
+	switch h.AudioFormat {
+	case FormatPCM, FormatALaw, FormatMuLaw:
+	default:
+		return Header{}, fmt.Errorf("unsupported WAVE format 0x%04x", h.AudioFormat)
+	}
+	if h.Channels == 0 || h.SampleRate == 0 || h.BitsPerSample == 0 || h.BitsPerSample%8 != 0 {
+		return Header{}, fmt.Errorf("invalid fmt chunk %+v", h)
+	}
+	return h, nil
+}
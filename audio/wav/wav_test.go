@@ -0,0 +1,134 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapRaw(t *testing.T) {
+	raw := make([]byte, 16000) // one second of 8 kHz 16 bit audio
+	b, err := Wrap(raw, tts.RAW8khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Len(t, b, 44+len(raw))
+	assert.Equal(t, uint32(len(b)-8), binary.LittleEndian.Uint32(b[4:8]))
+
+	a, err := Decode(b)
+	assert.NoError(t, err)
+	assert.Equal(t, Header{FormatPCM, 1, 8000, 16}, a.Header)
+	assert.Equal(t, time.Second, a.Duration())
+	assert.Equal(t, raw, a.Data)
+
+	b, err = Wrap(make([]byte, 4000), tts.RAW8khz8bitMonoMulaw)
+	assert.NoError(t, err)
+	a, err = Decode(b)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatMuLaw, a.AudioFormat)
+	assert.Equal(t, 500*time.Millisecond, a.Duration())
+
+	_, err = Wrap([]byte{1, 2, 3}, tts.AUDIO16khz32kbitrateMonoMP3)
+	assert.Error(t, err, "mp3 cannot be wrapped in RIFF")
+}
+
+func TestDecodeStreamed(t *testing.T) {
+	// a header written before the length was known, followed by 3 frames and a partial one.
+	b := append(header(Header{FormatPCM, 1, 24000, 16}, unknownSize), 1, 0, 2, 0, 3, 0, 4)
+	a, err := Decode(b)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 0, 2, 0, 3, 0}, a.Data)
+
+	assert.NoError(t, FixSizes(b))
+	assert.Equal(t, uint32(len(b)-8), binary.LittleEndian.Uint32(b[4:8]))
+	assert.Equal(t, uint32(6), binary.LittleEndian.Uint32(b[40:44]))
+
+	_, err = Decode([]byte("ID3\x03 not a wave file"))
+	assert.Equal(t, ErrNotWAVE, err)
+}
+
+func TestWriterSeeker(t *testing.T) {
+	f, err := ioutil.TempFile("", "azuretts-wav")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h, _ := HeaderFor(tts.RIFF8khz8bitMonoAlaw)
+	w, err := NewWriter(f, h)
+	assert.NoError(t, err)
+	w.Write([]byte{0xd5, 0xd5, 0xd5})
+	w.Write([]byte{0x55, 0x55})
+	assert.NoError(t, w.Close())
+
+	b, err := ioutil.ReadFile(f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, (&Audio{Header: h, Data: []byte{0xd5, 0xd5, 0xd5, 0x55, 0x55}}).Bytes(), b)
+	a, err := Decode(b)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatALaw, a.AudioFormat)
+	assert.Len(t, a.Data, 5)
+}
+
+func TestFixup(t *testing.T) {
+	f, err := ioutil.TempFile("", "azuretts-wav")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// simulate a streamed response written to disk by a plain io.Writer.
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf, Header{FormatPCM, 1, 16000, 16})
+	w.Write(make([]byte, 3200))
+	w.Close()
+	f.Write(buf.Bytes())
+	assert.Equal(t, uint32(unknownSize), binary.LittleEndian.Uint32(buf.Bytes()[40:44]))
+
+	assert.NoError(t, Fixup(f))
+	b, _ := ioutil.ReadFile(f.Name())
+	assert.Equal(t, uint32(3200), binary.LittleEndian.Uint32(b[40:44]))
+	assert.Equal(t, uint32(len(b)-8), binary.LittleEndian.Uint32(b[4:8]))
+}
+
+func TestDecodeExtensible(t *testing.T) {
+	fmtChunk := make([]byte, 40)
+	binary.LittleEndian.PutUint16(fmtChunk[0:], formatExtensible)
+	binary.LittleEndian.PutUint16(fmtChunk[2:], 1)
+	binary.LittleEndian.PutUint32(fmtChunk[4:], 48000)
+	binary.LittleEndian.PutUint32(fmtChunk[8:], 96000)
+	binary.LittleEndian.PutUint16(fmtChunk[12:], 2)
+	binary.LittleEndian.PutUint16(fmtChunk[14:], 16)
+	binary.LittleEndian.PutUint16(fmtChunk[24:], FormatPCM)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF\x00\x00\x00\x00WAVE")
+	b.WriteString("LIST\x03\x00\x00\x00abc\x00") // odd sized chunk with pad byte
+	b.WriteString("fmt \x28\x00\x00\x00")
+	b.Write(fmtChunk)
+	b.WriteString("data\x04\x00\x00\x00\x01\x02\x03\x04")
+
+	a, err := Decode(b.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, Header{FormatPCM, 1, 48000, 16}, a.Header)
+	assert.Equal(t, []byte{1, 2, 3, 4}, a.Data)
+}
+
+func TestReader(t *testing.T) {
+	// a streamed header with a LIST chunk ahead of the data.
+	b := header(Header{FormatPCM, 1, 24000, 16}, unknownSize)
+	list := append([]byte("LIST\x03\x00\x00\x00abc\x00"), b[36:]...)
+	b = append(append(b[:36:36], list...), 1, 0, 2, 0)
+
+	r, err := NewReader(bytes.NewReader(b))
+	assert.NoError(t, err)
+	assert.Equal(t, Header{FormatPCM, 1, 24000, 16}, r.Header)
+	data, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 0, 2, 0}, data)
+
+	_, err = NewReader(bytes.NewReader([]byte("OggS")))
+	assert.Equal(t, ErrNotWAVE, err)
+}
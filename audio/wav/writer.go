@@ -0,0 +1,140 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// header renders the RIFF, fmt, optional fact and data chunk headers for dataSize bytes of sample data.
+// Non-PCM formats carry an 18 byte fmt chunk and a fact chunk as required by the WAVE specification.
+// A dataSize of unknownSize marks a stream whose length is not yet known.
+func header(h Header, dataSize uint32) []byte {
+	pcm := h.AudioFormat == FormatPCM
+	fmtSize := 16
+	if !pcm {
+		fmtSize = 18
+	}
+
+	b := make([]byte, 0, 58)
+	le := binary.LittleEndian
+	u16 := func(v uint16) { b = append(b, byte(v), byte(v>>8)) }
+	u32 := func(v uint32) { b = append(b, 0, 0, 0, 0); le.PutUint32(b[len(b)-4:], v) }
+
+	headerSize := 4 + 8 + fmtSize + 8
+	if !pcm {
+		headerSize += 12
+	}
+	riffSize := uint32(unknownSize)
+	if dataSize != unknownSize {
+		riffSize = uint32(headerSize) + dataSize + dataSize%2
+	}
+
+	b = append(b, "RIFF"...)
+	u32(riffSize)
+	b = append(b, "WAVEfmt "...)
+	u32(uint32(fmtSize))
+	u16(h.AudioFormat)
+	u16(h.Channels)
+	u32(h.SampleRate)
+	u32(uint32(h.ByteRate()))
+	u16(uint16(h.BlockAlign()))
+	u16(h.BitsPerSample)
+	if !pcm {
+		u16(0) // cbSize
+		b = append(b, "fact"...)
+		u32(4)
+		if dataSize == unknownSize {
+			u32(unknownSize)
+		} else {
+			u32(dataSize / uint32(h.BlockAlign()))
+		}
+	}
+	b = append(b, "data"...)
+	u32(dataSize)
+	return b
+}
+
+// Writer streams sample data into a WAVE file. The header is written up front with the sizes marked as unknown;
+// when the underlying writer is an io.WriteSeeker, Close rewrites it with the final sizes.
+type Writer struct {
+	w      io.Writer
+	h      Header
+	n      int64
+	closed bool
+}
+
+// NewWriter writes the WAVE header for h to w and returns a Writer for the sample data.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	if h.BlockAlign() == 0 || h.SampleRate == 0 {
+		return nil, fmt.Errorf("invalid header %+v", h)
+	}
+	if _, err := w.Write(header(h, unknownSize)); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, h: h}, nil
+}
+
+// Write appends sample data.
+func (w *Writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Close pads the data chunk to an even length and, when possible, patches the header with the final sizes. It
+// does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.n%2 == 1 {
+		if _, err := w.w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	ws, ok := w.w.(io.WriteSeeker)
+	if !ok {
+		return nil
+	}
+	end, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	start := end - w.n - w.n%2 - int64(len(header(w.h, 0)))
+	if _, err := ws.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := ws.Write(header(w.h, uint32(w.n))); err != nil {
+		return err
+	}
+	_, err = ws.Seek(end, io.SeekStart)
+	return err
+}
+
+// Fixup repairs the size fields of a WAVE file that was written without knowing its final length, for example a
+// streamed RIFF response saved straight to disk.
+func Fixup(f io.ReadWriteSeeker) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	l, err := parse(b)
+	if err != nil {
+		return err
+	}
+	if err := FixSizes(b); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// only the header changes, the sample data is left untouched.
+	_, err = f.Write(b[:l.dataOff])
+	return err
+}
@@ -0,0 +1,303 @@
+package webm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/linexjlin/azuretexttospeech/audio/opus"
+)
+
+// Further element IDs used when reading and writing whole files.
+const (
+	idEBMLVersion        = 0x4286
+	idEBMLReadVersion    = 0x42F7
+	idEBMLMaxIDLength    = 0x42F2
+	idEBMLMaxSizeLength  = 0x42F3
+	idDocType            = 0x4282
+	idDocTypeVersion     = 0x4287
+	idDocTypeReadVersion = 0x4285
+	idInfo               = 0x1549A966
+	idTimecodeScale      = 0x2AD7B1
+	idMuxingApp          = 0x4D80
+	idWritingApp         = 0x5741
+	idDuration           = 0x4489
+	idTrackNumber        = 0xD7
+	idTrackUID           = 0x73C5
+	idTrackType          = 0x83
+	idCodecID            = 0x86
+	idCodecPrivate       = 0x63A2
+	idSeekPreRoll        = 0x56BB
+	idAudio              = 0xE1
+	idSamplingFrequency  = 0xB5
+	idChannels           = 0x9F
+	idTimecode           = 0xE7
+	idDiscardPadding     = 0x75A2
+)
+
+// Values written by Encode.
+const (
+	timecodeScale   = 1000000 // nanoseconds per timecode unit, i.e. millisecond timecodes
+	clusterDuration = 5000    // milliseconds of audio per cluster
+	seekPreRoll     = 80000000
+	muxingApp       = "azuretexttospeech"
+	trackTypeAudio  = 2
+)
+
+// walk calls fn for every element of b in document order. Master elements are reported with their body and then
+// descended into, so fn sees their children as well; the nesting itself is not tracked.
+func walk(b []byte, fn func(id uint32, body []byte) error) error {
+	for len(b) > 0 {
+		id, size, n, err := readElement(b)
+		if err == errShort {
+			return fmt.Errorf("truncated element")
+		}
+		if err != nil {
+			return err
+		}
+		if size == unknownSize || n+int(size) > len(b) {
+			if !isMaster(id) {
+				return fmt.Errorf("truncated element 0x%X", id)
+			}
+			// a streamed master element extends to the end of its parent.
+			size = int64(len(b) - n)
+		}
+		body := b[n : n+int(size)]
+		if err := fn(id, body); err != nil {
+			return err
+		}
+		if isMaster(id) {
+			b = b[n:]
+		} else {
+			b = b[n+int(size):]
+		}
+	}
+	return nil
+}
+
+// track holds the fields of a TrackEntry.
+type track struct {
+	number  int64
+	codec   string
+	private []byte
+	delay   int64
+}
+
+// Decode extracts the Opus stream of the first Opus track of a WebM file.
+func Decode(b []byte) (*opus.Stream, error) {
+	if id, _, _, err := readElement(b); err != nil || id != idEBML {
+		return nil, ErrNotWebM
+	}
+	s := &opus.Stream{}
+	var opusTrack *track
+	err := walk(b, func(id uint32, body []byte) error {
+		switch id {
+		case idTrackEntry:
+			t := &track{}
+			walk(body, func(id uint32, body []byte) error {
+				switch id {
+				case idTrackNumber:
+					t.number = readUint(body)
+				case idCodecID:
+					t.codec = string(body)
+				case idCodecPrivate:
+					t.private = body
+				case idCodecDelay:
+					t.delay = readUint(body)
+				}
+				return nil
+			})
+			if opusTrack == nil && t.codec == "A_OPUS" {
+				h, err := opus.ParseHead(t.private)
+				if err != nil {
+					return err
+				}
+				opusTrack, s.Head = t, h
+			}
+		case idSimpleBlock, idBlock:
+			if opusTrack == nil {
+				return fmt.Errorf("block precedes the opus track entry")
+			}
+			number, frames, err := blockFrames(body)
+			if err != nil {
+				return err
+			}
+			if number == opusTrack.number {
+				s.Packets = append(s.Packets, frames...)
+			}
+		case idDiscardPadding:
+			// a signed count of nanoseconds to drop from the end of the block, in practice the last one.
+			v := readUint(body)
+			if len(body) > 0 && len(body) < 8 && body[0]&0x80 != 0 {
+				v -= 1 << uint(8*len(body))
+			}
+			if v > 0 {
+				s.Discard = samples(v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.Head == nil {
+		return nil, fmt.Errorf("no opus track")
+	}
+	// WebM carries the pre-skip as the track's codec delay; the OpusHead of the codec private data normally agrees.
+	if opusTrack.delay > 0 {
+		s.Head.PreSkip = uint16(samples(opusTrack.delay))
+	}
+	return s, nil
+}
+
+// samples converts nanoseconds to 48 kHz samples, rounding to undo the truncation of the conversion the other way.
+func samples(ns int64) int64 {
+	return (ns*opus.SampleRate + 500000000) / 1000000000
+}
+
+// Encode writes an Opus stream as a WebM file with a single audio track. Packets are stored as SimpleBlocks in
+// clusters of five seconds; a final block with samples to discard is stored in a BlockGroup with DiscardPadding.
+func Encode(s *opus.Stream) []byte {
+	delay := int64(s.Head.PreSkip) * 1000000000 / opus.SampleRate
+	total := s.Samples()
+
+	head := concat(
+		element(idEBMLVersion, uintBody(1)),
+		element(idEBMLReadVersion, uintBody(1)),
+		element(idEBMLMaxIDLength, uintBody(4)),
+		element(idEBMLMaxSizeLength, uintBody(8)),
+		element(idDocType, []byte("webm")),
+		element(idDocTypeVersion, uintBody(4)),
+		element(idDocTypeReadVersion, uintBody(2)),
+	)
+	info := concat(
+		element(idTimecodeScale, uintBody(timecodeScale)),
+		element(idMuxingApp, []byte(muxingApp)),
+		element(idWritingApp, []byte(muxingApp)),
+		element(idDuration, floatBody(float64(opus.Duration(total-s.Discard))/timecodeScale)),
+	)
+	entry := concat(
+		element(idTrackNumber, uintBody(1)),
+		element(idTrackUID, uintBody(1)),
+		element(idTrackType, uintBody(trackTypeAudio)),
+		element(idCodecID, []byte("A_OPUS")),
+		element(idCodecPrivate, s.Head.Bytes()),
+		element(idCodecDelay, uintBody(uint64(delay))),
+		element(idSeekPreRoll, uintBody(seekPreRoll)),
+		element(idAudio, concat(
+			element(idSamplingFrequency, floatBody(opus.SampleRate)),
+			element(idChannels, uintBody(uint64(s.Head.Channels))),
+		)),
+	)
+	segment := concat(element(idInfo, info), element(idTracks, element(idTrackEntry, entry)))
+
+	var cluster []byte
+	var clusterTime, pos int64
+	for i, p := range s.Packets {
+		t := pos * 1000 / opus.SampleRate
+		if cluster == nil || t-clusterTime >= clusterDuration {
+			if cluster != nil {
+				segment = append(segment, element(idCluster, cluster)...)
+			}
+			clusterTime = t
+			cluster = element(idTimecode, uintBody(uint64(t)))
+		}
+		n, _ := opus.PacketSamples(p)
+		pos += int64(n)
+
+		block := make([]byte, 4, 4+len(p))
+		block[0] = 0x81 // track 1
+		binary.BigEndian.PutUint16(block[1:], uint16(t-clusterTime))
+		if i == len(s.Packets)-1 && s.Discard > 0 {
+			block = append(block, p...)
+			cluster = append(cluster, element(idBlockGroup, concat(
+				element(idBlock, block),
+				element(idDiscardPadding, intBody(s.Discard*1000000000/opus.SampleRate)),
+			))...)
+			continue
+		}
+		block[3] = 0x80 // keyframe
+		cluster = append(cluster, element(idSimpleBlock, append(block, p...))...)
+	}
+	if cluster != nil {
+		segment = append(segment, element(idCluster, cluster)...)
+	}
+	return concat(element(idEBML, head), element(idSegment, segment))
+}
+
+// Concat joins WebM Opus files into a single file with the track header of the first, see opus.Stream.Append.
+func Concat(segments [][]byte) ([]byte, error) {
+	out := &opus.Stream{}
+	for i, seg := range segments {
+		s, err := Decode(seg)
+		if err != nil {
+			return nil, fmt.Errorf("segment %d, %v", i, err)
+		}
+		if err := out.Append(s); err != nil {
+			return nil, fmt.Errorf("segment %d, %v", i, err)
+		}
+	}
+	if out.Head == nil {
+		return nil, fmt.Errorf("no segments")
+	}
+	return Encode(out), nil
+}
+
+// element encodes an element with the shortest size field that holds its length.
+func element(id uint32, body []byte) []byte {
+	var b []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if v := byte(id >> uint(shift)); v != 0 || len(b) > 0 {
+			b = append(b, v)
+		}
+	}
+	size := uint64(len(body))
+	n := 1
+	// a size of all ones is reserved for unknown sizes.
+	for size >= 1<<uint(7*n)-1 {
+		n++
+	}
+	for i := n - 1; i >= 0; i-- {
+		v := byte(size >> uint(8*i))
+		if i == n-1 {
+			v |= 0x80 >> uint(n-1)
+		}
+		b = append(b, v)
+	}
+	return append(b, body...)
+}
+
+// uintBody encodes an unsigned integer element body in as few bytes as possible.
+func uintBody(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	return b
+}
+
+// intBody encodes a signed integer element body in as few bytes as possible.
+func intBody(v int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	for len(b) > 1 && (b[0] == 0 && b[1]&0x80 == 0 || b[0] == 0xff && b[1]&0x80 != 0) {
+		b = b[1:]
+	}
+	return b
+}
+
+// floatBody encodes a float element body.
+func floatBody(f float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(f))
+	return b
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
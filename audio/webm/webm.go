@@ -0,0 +1,271 @@
+// Package webm reads and writes the WebM (Matroska) outputs of the text-to-speech service, which carry a single
+// Opus audio track. It measures their duration, and extracts their Opus streams to join them or move them to
+// another container.
+package webm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linexjlin/azuretexttospeech/audio/opus"
+)
+
+// Element IDs used by this package.
+const (
+	idEBML        = 0x1A45DFA3
+	idSegment     = 0x18538067
+	idCluster     = 0x1F43B675
+	idBlockGroup  = 0xA0
+	idBlock       = 0xA1
+	idSimpleBlock = 0xA3
+	idTracks      = 0x1654AE6B
+	idTrackEntry  = 0xAE
+	idCodecDelay  = 0x56AA
+)
+
+// unknownSize marks a master element streamed before its size was known.
+const unknownSize = -1
+
+// ErrNotWebM is returned when the input does not start with an EBML header.
+var ErrNotWebM = errors.New("not a WebM stream")
+
+// errShort signals that more input is required to parse an element header.
+var errShort = errors.New("short element")
+
+// readVint decodes an EBML variable length integer at the start of b, returning its value with the length marker
+// removed (for sizes) or kept (for IDs), and its length in bytes.
+func readVint(b []byte, keepMarker bool) (v int64, n int, err error) {
+	if len(b) == 0 {
+		return 0, 0, errShort
+	}
+	n = 1
+	for mask := byte(0x80); n <= 8 && b[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > 8 {
+		return 0, 0, fmt.Errorf("invalid EBML variable length integer")
+	}
+	if len(b) < n {
+		return 0, 0, errShort
+	}
+	v = int64(b[0])
+	if !keepMarker {
+		v &= int64(0xff >> uint(n))
+	}
+	allOnes := v == int64(0xff>>uint(n))
+	for _, c := range b[1:n] {
+		v = v<<8 | int64(c)
+		allOnes = allOnes && c == 0xff
+	}
+	if !keepMarker && allOnes {
+		v = unknownSize
+	}
+	return v, n, nil
+}
+
+// readElement decodes the ID and size of the element starting at b and returns the length of its header.
+func readElement(b []byte) (id uint32, size int64, n int, err error) {
+	v, idLen, err := readVint(b, true)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	size, sizeLen, err := readVint(b[idLen:], false)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return uint32(v), size, idLen + sizeLen, nil
+}
+
+// isMaster reports whether the walker descends into an element rather than skipping or reading it whole.
+func isMaster(id uint32) bool {
+	switch id {
+	case idSegment, idCluster, idBlockGroup, idTracks, idTrackEntry:
+		return true
+	}
+	return false
+}
+
+// readUint decodes the body of an EBML unsigned integer element.
+func readUint(b []byte) int64 {
+	var v int64
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+// blockFrames returns the track number and frames of a SimpleBlock or Block body, undoing any lacing.
+func blockFrames(b []byte) (track int64, frames [][]byte, err error) {
+	track, n, err := readVint(b, false)
+	if err != nil || len(b) < n+3 {
+		return 0, nil, fmt.Errorf("truncated block")
+	}
+	flags := b[n+2]
+	b = b[n+3:]
+	lacing := (flags >> 1) & 3
+	if lacing == 0 {
+		return track, [][]byte{b}, nil
+	}
+
+	if len(b) == 0 {
+		return 0, nil, fmt.Errorf("truncated block")
+	}
+	count := int(b[0]) + 1
+	b = b[1:]
+	sizes := make([]int, count)
+	switch lacing {
+	case 1: // Xiph
+		for i := 0; i < count-1; i++ {
+			for {
+				if len(b) == 0 {
+					return 0, nil, fmt.Errorf("truncated block lacing")
+				}
+				v := b[0]
+				b = b[1:]
+				sizes[i] += int(v)
+				if v < 255 {
+					break
+				}
+			}
+		}
+	case 3: // EBML
+		v, n, err := readVint(b, false)
+		if err != nil {
+			return 0, nil, fmt.Errorf("truncated block lacing")
+		}
+		sizes[0] = int(v)
+		b = b[n:]
+		for i := 1; i < count-1; i++ {
+			raw, n, err := readVint(b, false)
+			if err != nil {
+				return 0, nil, fmt.Errorf("truncated block lacing")
+			}
+			// signed difference to the previous size, biased by half the range of an n byte integer.
+			sizes[i] = sizes[i-1] + int(raw-(int64(1)<<uint(7*n-1)-1))
+			b = b[n:]
+		}
+	}
+
+	frames = make([][]byte, count)
+	if lacing == 2 { // fixed
+		if len(b)%count != 0 {
+			return 0, nil, fmt.Errorf("invalid fixed-size lacing")
+		}
+		for i := range frames {
+			frames[i] = b[i*len(b)/count : (i+1)*len(b)/count]
+		}
+		return track, frames, nil
+	}
+	for i := 0; i < count-1; i++ {
+		if sizes[i] < 0 || sizes[i] > len(b) {
+			return 0, nil, fmt.Errorf("invalid block lacing")
+		}
+		frames[i], b = b[:sizes[i]], b[sizes[i]:]
+	}
+	frames[count-1] = b
+	return track, frames, nil
+}
+
+// Counter measures the duration of a WebM Opus stream written to it incrementally, by adding up the duration of
+// every Opus packet and subtracting the track's codec delay. Blocks are counted once complete.
+type Counter struct {
+	buf     []byte
+	skip    int64
+	err     error
+	started bool
+	delay   time.Duration
+	samples int64
+}
+
+// Write consumes the next part of the stream. It fails once the stream turns out not to be WebM Opus.
+func (c *Counter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.buf = append(c.buf, p...)
+	off := 0
+	for off < len(c.buf) {
+		if c.skip > 0 {
+			n := c.skip
+			if n > int64(len(c.buf)-off) {
+				n = int64(len(c.buf) - off)
+			}
+			off += int(n)
+			c.skip -= n
+			continue
+		}
+		id, size, n, err := readElement(c.buf[off:])
+		if err == errShort {
+			break
+		}
+		if err == nil && !c.started && id != idEBML {
+			err = ErrNotWebM
+		}
+		if err != nil {
+			c.err = err
+			return 0, err
+		}
+		c.started = true
+		if isMaster(id) {
+			off += n
+			continue
+		}
+		if size == unknownSize {
+			c.err = fmt.Errorf("element 0x%X of unknown size", id)
+			return 0, c.err
+		}
+		switch id {
+		case idSimpleBlock, idBlock, idCodecDelay:
+		default:
+			off += n
+			c.skip = size
+			continue
+		}
+		if int64(len(c.buf)-off-n) < size {
+			break
+		}
+		body := c.buf[off+n : off+n+int(size)]
+		off += n + int(size)
+		if id == idCodecDelay {
+			c.delay = time.Duration(readUint(body))
+			continue
+		}
+		_, frames, err := blockFrames(body)
+		if err != nil {
+			c.err = err
+			return 0, err
+		}
+		for _, f := range frames {
+			s, err := opus.PacketSamples(f)
+			if err != nil {
+				c.err = err
+				return 0, err
+			}
+			c.samples += int64(s)
+		}
+	}
+	c.buf = append(c.buf[:0], c.buf[off:]...)
+	return len(p), nil
+}
+
+// Duration returns the play time of the blocks written so far.
+func (c *Counter) Duration() time.Duration {
+	d := opus.Duration(c.samples) - c.delay
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Duration returns the play time of a WebM Opus stream held in memory.
+func Duration(b []byte) (time.Duration, error) {
+	var c Counter
+	if _, err := c.Write(b); err != nil {
+		return 0, err
+	}
+	if !c.started {
+		return 0, ErrNotWebM
+	}
+	return c.Duration(), nil
+}
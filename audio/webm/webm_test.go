@@ -0,0 +1,155 @@
+package webm
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/linexjlin/azuretexttospeech/audio/opus"
+	"github.com/stretchr/testify/assert"
+)
+
+// el encodes an element with an eight byte size field, or of unknown size when body is nil.
+func el(id uint32, body ...[]byte) []byte {
+	var b []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if v := byte(id >> uint(shift)); v != 0 || len(b) > 0 {
+			b = append(b, v)
+		}
+	}
+	data := bytes.Join(body, nil)
+	if body == nil {
+		return append(b, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	}
+	n := len(data)
+	b = append(b, 0x01, 0, 0, 0, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	return append(b, data...)
+}
+
+// stream returns a streamed WebM Opus file of n 20 ms packets in clusters of 10 with a codec delay of 6.5 ms.
+func stream(n int) []byte {
+	b := el(idEBML, el(0x4282, []byte("webm")))
+	b = append(b, el(idSegment)...)
+	b = append(b, el(idTracks, el(idTrackEntry, el(0xD7, []byte{1}), el(0x86, []byte("A_OPUS")), el(idCodecPrivate, (&opus.Head{Version: 1, Channels: 1, PreSkip: 312, InputRate: 24000}).Bytes()), el(idCodecDelay, []byte{0x63, 0x2E, 0xA0})))...)
+	for i := 0; i < n; i++ {
+		if i%10 == 0 {
+			b = append(b, el(idCluster)...)
+			b = append(b, el(0xE7, []byte{byte(i * 20)})...)
+		}
+		// track 1, relative timecode, keyframe flag, then a 20 ms CELT packet.
+		b = append(b, el(idSimpleBlock, []byte{0x81, 0, byte(i % 10 * 20), 0x80, 0xFC, 1, 2, 3})...)
+	}
+	return b
+}
+
+func TestReadVint(t *testing.T) {
+	v, n, err := readVint([]byte{0x81}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+	assert.Equal(t, 1, n)
+
+	v, n, err = readVint([]byte{0x40, 0x02}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), v)
+	assert.Equal(t, 2, n)
+
+	v, _, err = readVint([]byte{0x1A, 0x45, 0xDF, 0xA3}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(idEBML), v)
+
+	v, _, err = readVint([]byte{0xFF}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(unknownSize), v)
+
+	_, _, err = readVint([]byte{0x40}, false)
+	assert.Equal(t, errShort, err)
+}
+
+func TestBlockFrames(t *testing.T) {
+	// Xiph lacing: three frames of 300, 2 and 1 bytes.
+	body := append([]byte{0x81, 0, 0, 0x82, 2, 255, 45, 2}, make([]byte, 303)...)
+	_, frames, err := blockFrames(body)
+	assert.NoError(t, err)
+	assert.Len(t, frames, 3)
+	assert.Len(t, frames[0], 300)
+	assert.Len(t, frames[2], 1)
+
+	// EBML lacing: 10, 12 and 5 bytes.
+	body = append([]byte{0x81, 0, 0, 0x86, 2, 0x8A, 0xC1}, make([]byte, 27)...)
+	_, frames, err = blockFrames(body)
+	assert.NoError(t, err)
+	assert.Len(t, frames[1], 12)
+	assert.Len(t, frames[2], 5)
+
+	// fixed lacing: two frames of 4 bytes.
+	_, frames, err = blockFrames([]byte{0x81, 0, 0, 0x84, 1, 1, 2, 3, 4, 5, 6, 7, 8})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}}, frames)
+}
+
+func TestDuration(t *testing.T) {
+	b := stream(50)
+	d, err := Duration(b)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second-6500*time.Microsecond, d)
+
+	var c Counter
+	for len(b) > 0 {
+		n := 3
+		if n > len(b) {
+			n = len(b)
+		}
+		_, err := c.Write(b[:n])
+		assert.NoError(t, err)
+		b = b[n:]
+	}
+	assert.Equal(t, time.Second-6500*time.Microsecond, c.Duration())
+
+	_, err = Duration([]byte("OggS"))
+	assert.Equal(t, ErrNotWebM, err)
+}
+
+func TestElement(t *testing.T) {
+	assert.Equal(t, []byte{0xE7, 0x81, 0x05}, element(idTimecode, uintBody(5)))
+	// 127 would be the reserved all-ones size in one byte.
+	b := element(idCodecPrivate, make([]byte, 127))
+	assert.Equal(t, []byte{0x63, 0xA2, 0x40, 0x7F}, b[:4])
+	id, size, n, err := readElement(b)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(idCodecPrivate), id)
+	assert.Equal(t, int64(127), size)
+	assert.Equal(t, 4, n)
+}
+
+func TestEncode(t *testing.T) {
+	s := &opus.Stream{Head: &opus.Head{Version: 1, Channels: 1, PreSkip: 312, InputRate: 24000}, Discard: 480}
+	for i := 0; i < 400; i++ {
+		s.Packets = append(s.Packets, append([]byte{0xFC}, byte(i), byte(i>>8)))
+	}
+	b := Encode(s)
+	d, err := Duration(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 8*time.Second-6500*time.Microsecond, d, "the counter does not apply DiscardPadding")
+
+	decoded, err := Decode(b)
+	assert.NoError(t, err)
+	assert.Equal(t, s, decoded)
+	assert.Equal(t, 8*time.Second-16500*time.Microsecond, decoded.Duration())
+
+	// a streamed file with clusters of unknown size decodes as well.
+	decoded, err = Decode(stream(30))
+	assert.NoError(t, err)
+	assert.Len(t, decoded.Packets, 30)
+	assert.Equal(t, uint16(312), decoded.Head.PreSkip)
+}
+
+func TestConcat(t *testing.T) {
+	b, err := Concat([][]byte{stream(50), stream(25)})
+	assert.NoError(t, err)
+	d, err := Duration(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond-6500*time.Microsecond, d)
+
+	_, err = Concat([][]byte{stream(1), []byte("OggS")})
+	assert.Error(t, err)
+}
@@ -5,10 +5,14 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"html"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -37,66 +41,104 @@ func (az *AzureCSTextToSpeech) SynthesizeWithContext(ctx context.Context, speech
 	}*/
 
 	v := voiceXML(speechText, locale, name, pitch, rate)
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, az.textToSpeechURL, bytes.NewBufferString(v))
-	if err != nil {
-		return nil, err
-	}
-	request.Header.Set("X-Microsoft-OutputFormat", fmt.Sprint(audioOutput))
-	request.Header.Set("Content-Type", "application/ssml+xml")
-	request.Header.Set("Authorization", "Bearer "+az.accessToken)
-	request.Header.Set("User-Agent", "azuretts")
-
-	//client := &http.Client{}
-
-	var client *http.Client
+	return az.cachedSynthesize(ctx, v, name, audioOutput)
+}
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+// cachedSynthesize consults az.Cache before issuing the request. Concurrent identical requests are collapsed into
+// a single upstream call, and successful results are stored for az.CacheTTL.
+func (az *AzureCSTextToSpeech) cachedSynthesize(ctx context.Context, ssml, voice string, audioOutput AudioOutput) ([]byte, error) {
+	if az.Cache == nil {
+		return az.synthesize(ctx, ssml, audioOutput)
 	}
 
-	if az.HttpProxy != "" {
-		proxyURL, err := url.Parse(az.HttpProxy)
+	key := CacheKey(ssml, voice, audioOutput)
+	if b, ok := az.Cache.Get(key); ok {
+		return b, nil
+	}
+	return az.flight.do(ctx, key, func() ([]byte, error) {
+		b, err := az.synthesize(ctx, ssml, audioOutput)
 		if err != nil {
 			return nil, err
 		}
+		if err := az.Cache.Set(key, b, az.CacheTTL); err != nil {
+			log.Printf("failed to store synthesis result in cache, %v", err)
+		}
+		return b, nil
+	})
+}
 
-		tr.Proxy = http.ProxyURL(proxyURL)
-		//transport := &http.Transport{Proxy: http.ProxyURL(proxyURL)}
-		client = &http.Client{Transport: tr}
-
-	} else {
+// synthesize posts the SSML payload to the text-to-speech endpoint and returns the rendered audio.
+func (az *AzureCSTextToSpeech) synthesize(ctx context.Context, ssml string, audioOutput AudioOutput) ([]byte, error) {
+	body, err := az.synthesizeStream(ctx, ssml, audioOutput)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
 
-		client = &http.Client{Transport: tr}
-		//client = &http.Client{}
+// synthesizeStream posts the SSML payload to the text-to-speech endpoint and returns the response body once the
+// service has accepted the request.
+func (az *AzureCSTextToSpeech) synthesizeStream(ctx context.Context, ssml string, audioOutput AudioOutput) (io.ReadCloser, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, az.textToSpeechURL, bytes.NewBufferString(ssml))
+	if err != nil {
+		return nil, err
 	}
+	request.Header.Set("X-Microsoft-OutputFormat", fmt.Sprint(audioOutput))
+	request.Header.Set("Content-Type", "application/ssml+xml")
+	request.Header.Set("Authorization", "Bearer "+az.token())
+	request.Header.Set("User-Agent", "azuretts")
 
-	response, err := client.Do(request.WithContext(ctx))
+	client, err := az.httpClient()
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
 
-	// list of acceptable response status codes
-	// see: https://docs.microsoft.com/en-us/azure/cognitive-services/speech-service/rest-text-to-speech#http-status-codes-1
-	switch response.StatusCode {
-	case http.StatusOK:
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusOK {
 		// The request was successful; the response body is an audio file.
-		return ioutil.ReadAll(response.Body)
+		return response.Body, nil
+	}
+	response.Body.Close()
+	return nil, synthesizeError(response.StatusCode)
+}
+
+// StatusError is returned when the service answers with an unexpected HTTP status code.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%d - %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the request may succeed when retried: the service was throttling it or failed.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout || e.StatusCode >= 500
+}
+
+// synthesizeError describes a non-200 status code returned by the text-to-speech endpoint.
+// see: https://docs.microsoft.com/en-us/azure/cognitive-services/speech-service/rest-text-to-speech#http-status-codes-1
+func synthesizeError(statusCode int) error {
+	switch statusCode {
 	case http.StatusBadRequest:
-		return nil, fmt.Errorf("%d - A required parameter is missing, empty, or null. Or, the value passed to either a required or optional parameter is invalid. A common issue is a header that is too long", response.StatusCode)
+		return &StatusError{statusCode, "A required parameter is missing, empty, or null. Or, the value passed to either a required or optional parameter is invalid. A common issue is a header that is too long"}
 	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("%d - The request is not authorized. Check to make sure your subscription key or token is valid and in the correct region", response.StatusCode)
+		return &StatusError{statusCode, "The request is not authorized. Check to make sure your subscription key or token is valid and in the correct region"}
 	case http.StatusRequestEntityTooLarge:
-		return nil, fmt.Errorf("%d - The SSML input is longer than 1024 characters", response.StatusCode)
+		return &StatusError{statusCode, "The SSML input is longer than 1024 characters"}
 	case http.StatusUnsupportedMediaType:
-		return nil, fmt.Errorf("%d - It's possible that the wrong Content-Type was provided. Content-Type should be set to application/ssml+xml", response.StatusCode)
+		return &StatusError{statusCode, "It's possible that the wrong Content-Type was provided. Content-Type should be set to application/ssml+xml"}
 	case http.StatusTooManyRequests:
-		return nil, fmt.Errorf("%d - You have exceeded the quota or rate of requests allowed for your subscription", response.StatusCode)
+		return &StatusError{statusCode, "You have exceeded the quota or rate of requests allowed for your subscription"}
 	case http.StatusBadGateway:
-		return nil, fmt.Errorf("%d - Network or server-side issue. May also indicate invalid headers", response.StatusCode)
+		return &StatusError{statusCode, "Network or server-side issue. May also indicate invalid headers"}
 	}
-
-	return nil, fmt.Errorf("%d - received unexpected HTTP status code", response.StatusCode)
+	return &StatusError{statusCode, "received unexpected HTTP status code"}
 }
 
 // Synthesize directs to SynthesizeWithContext. A new context.Withtimeout is created with the timeout as defined by synthesizeActionTimeout
@@ -106,12 +148,92 @@ func (az *AzureCSTextToSpeech) Synthesize(speechText string, locale Locale, name
 	return az.SynthesizeWithContext(ctx, speechText, locale, name, pitch, rate, audioOutput)
 }
 
+// SynthesizeSSMLWithContext returns the rendered audio for a caller supplied SSML document. Unlike
+// SynthesizeWithContext the payload is sent as is, so it must carry its own <speak> and <voice> elements.
+func (az *AzureCSTextToSpeech) SynthesizeSSMLWithContext(ctx context.Context, ssml string, audioOutput AudioOutput) ([]byte, error) {
+	return az.cachedSynthesize(ctx, ssml, "", audioOutput)
+}
+
+// SynthesizeSSML directs to SynthesizeSSMLWithContext using a timeout of synthesizeActionTimeout.
+func (az *AzureCSTextToSpeech) SynthesizeSSML(ssml string, audioOutput AudioOutput) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), synthesizeActionTimeout)
+	defer cancel()
+	return az.SynthesizeSSMLWithContext(ctx, ssml, audioOutput)
+}
+
+// SynthesizeStreamWithContext returns the rendered audio for an SSML document as a stream, so playback can start
+// before the service has finished rendering. The caller must close the returned reader. A result already held in
+// Cache is served from there; streamed results are not added to the cache.
+func (az *AzureCSTextToSpeech) SynthesizeStreamWithContext(ctx context.Context, ssml string, audioOutput AudioOutput) (io.ReadCloser, error) {
+	if az.Cache != nil {
+		if b, ok := az.Cache.Get(CacheKey(ssml, "", audioOutput)); ok {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		}
+	}
+	return az.synthesizeStream(ctx, ssml, audioOutput)
+}
+
+// Defaults of the servers built on the client for requests naming no voice or output format.
+const (
+	DefaultVoice       = "en-US-AriaNeural"
+	DefaultAudioOutput = AUDIO24khz48kbitrateMonoMP3
+)
+
+// VoiceSSML renders a complete SSML document speaking `text` with the voice short name `voice`. Unlike the payload
+// built by SynthesizeWithContext, `text` is XML escaped, and the locale is derived from the voice name.
+func VoiceSSML(text, voice, pitch, rate string) string {
+	locale := voice
+	if parts := strings.SplitN(voice, "-", 3); len(parts) >= 2 {
+		locale = parts[0] + "-" + parts[1]
+	}
+	return fmt.Sprintf(ttsApiXMLPayload, locale, locale, voice, rate, pitch, html.EscapeString(text))
+}
+
 // voiceXML renders the XML payload for the TTS api.
 // For API reference see https://docs.microsoft.com/en-us/azure/cognitive-services/speech-service/rest-text-to-speech#sample-request
 func voiceXML(speechText string, locale Locale, name, pitch, rate string) string {
 	return fmt.Sprintf(ttsApiXMLPayload, locale, locale, name, rate, pitch, speechText)
 }
 
+// httpClient returns the client used for all API requests, routed through HttpProxy when one is configured or
+// through the transport given with WithTransport. The transport is created on first use and shared by every
+// request, so that connections are reused; later changes of HttpProxy are not applied.
+func (az *AzureCSTextToSpeech) httpClient() (*http.Client, error) {
+	if az.transport != nil {
+		return &http.Client{Transport: az.transport}, nil
+	}
+	az.httpOnce.Do(func() {
+		tr := &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+		tr.Proxy, az.httpErr = az.proxy()
+		az.httpTransport = tr
+	})
+	if az.httpErr != nil {
+		return nil, az.httpErr
+	}
+	return &http.Client{Transport: az.httpTransport}, nil
+}
+
+// proxy returns the proxy of the API requests: HttpProxy when set, none otherwise.
+func (az *AzureCSTextToSpeech) proxy() (func(*http.Request) (*url.URL, error), error) {
+	if az.HttpProxy == "" {
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(az.HttpProxy)
+	if err != nil {
+		return nil, err
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+// token returns the current auth token, which the refresher replaces concurrently.
+func (az *AzureCSTextToSpeech) token() string {
+	az.tokenMu.RLock()
+	defer az.tokenMu.RUnlock()
+	return az.accessToken
+}
+
 // refreshToken fetches an updated token from the Azure cognitive speech/text services, or an error if unable to retrive.
 // Each token is valid for a maximum of 10 minutes. Details for auth tokens are referenced at
 // https://docs.microsoft.com/en-us/azure/cognitive-services/speech-service/rest-apis#authentication .
@@ -121,26 +243,9 @@ func (az *AzureCSTextToSpeech) refreshToken() error {
 	request, _ := http.NewRequest(http.MethodPost, az.tokenRefreshURL, nil)
 	request.Header.Set("Ocp-Apim-Subscription-Key", az.SubscriptionKey)
 
-	var client *http.Client
-
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-
-	if az.HttpProxy != "" {
-		proxyURL, err := url.Parse(az.HttpProxy)
-		if err != nil {
-			return err
-		}
-
-		tr.Proxy = http.ProxyURL(proxyURL)
-		//transport := &http.Transport{Proxy: http.ProxyURL(proxyURL)}
-		client = &http.Client{Transport: tr}
-
-	} else {
-
-		client = &http.Client{Transport: tr}
-		//client = &http.Client{}
+	client, err := az.httpClient()
+	if err != nil {
+		return err
 	}
 
 	response, err := client.Do(request)
@@ -154,7 +259,9 @@ func (az *AzureCSTextToSpeech) refreshToken() error {
 	}
 
 	body, _ := ioutil.ReadAll(response.Body)
+	az.tokenMu.Lock()
 	az.accessToken = string(body)
+	az.tokenMu.Unlock()
 	return nil
 }
 
@@ -182,26 +289,66 @@ func (az *AzureCSTextToSpeech) startRefresher() chan bool {
 
 // AzureCSTextToSpeech stores configuration and state information for the TTS client.
 type AzureCSTextToSpeech struct {
-	accessToken         string // is the auth token received from `TokenRefreshAPI`. Used in the Authorization: Bearer header.
+	accessToken         string       // is the auth token received from `TokenRefreshAPI`. Used in the Authorization: Bearer header.
+	tokenMu             sync.RWMutex // guards accessToken
 	RegionVoiceMap      RegionVoiceMap
 	SubscriptionKey     string    // API key for Azure's Congnitive Speech services
 	TokenRefreshDoneCh  chan bool // channel to stop the token refresh goroutine.
 	tokenRefreshURL     string
 	voiceServiceListURL string
 	textToSpeechURL     string
+	webSocketURL        string
+	transport           http.RoundTripper
+	httpOnce            sync.Once // creates httpTransport
+	httpTransport       http.RoundTripper
+	httpErr             error
 	HttpProxy           string
+	Cache               Cache         // optional store for rendered audio, see CacheKey.
+	CacheTTL            time.Duration // lifetime of entries written to Cache, zero never expires.
+	flight              flightGroup   // collapses concurrent identical requests when Cache is set.
+}
+
+// Option configures the client created by New.
+type Option func(az *AzureCSTextToSpeech)
+
+// WithEndpoint sends every request to baseURL, such as the address of a test server, instead of the regional
+// endpoints of the service. The standard paths are appended to it.
+func WithEndpoint(baseURL string) Option {
+	return func(az *AzureCSTextToSpeech) {
+		base := strings.TrimSuffix(baseURL, "/")
+		az.tokenRefreshURL = base + "/sts/v1.0/issueToken"
+		az.textToSpeechURL = base + "/cognitiveservices/v1"
+		az.voiceServiceListURL = base + "/cognitiveservices/voices/list"
+		ws := base
+		if strings.HasPrefix(ws, "http") {
+			ws = "ws" + strings.TrimPrefix(ws, "http")
+		}
+		az.webSocketURL = ws + "/cognitiveservices/websocket/v1"
+	}
+}
+
+// WithTransport sends the HTTP requests of the client, including the token refresh, through rt. HttpProxy is not
+// applied to them, and the WebSocket protocol, which is not HTTP, does not use rt.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(az *AzureCSTextToSpeech) {
+		az.transport = rt
+	}
 }
 
 // New returns an AzureCSTextToSpeech object.
-func New(subscriptionKey string, region Region, proxy string) (*AzureCSTextToSpeech, error) {
+func New(subscriptionKey string, region Region, proxy string, opts ...Option) (*AzureCSTextToSpeech, error) {
 	az := &AzureCSTextToSpeech{
 		SubscriptionKey: subscriptionKey,
 		HttpProxy:       proxy,
 	}
 
 	az.textToSpeechURL = fmt.Sprintf(textToSpeechAPI, region)
+	az.webSocketURL = fmt.Sprintf(webSocketAPI, region)
 	az.tokenRefreshURL = fmt.Sprintf(tokenRefreshAPI, region)
 	az.voiceServiceListURL = fmt.Sprintf(voiceListAPI, region)
+	for _, opt := range opts {
+		opt(az)
+	}
 
 	// api requires that the token is refreshed every 10 mintutes.
 	// We will do this task in the background every ~9 minutes.
@@ -1,16 +1,20 @@
 package azuretexttospeech
 
 import (
+	"context"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestVoiceXML(t *testing.T) {
-	expect := "<speak version='1.0' xml:lang='en-US'><voice xml:lang='en-US' xml:gender='Female' name='ar-EG-Hoda'>Microsoft Speech Service Text-to-Speech API</voice></speak>"
-	assert.Equal(t, expect, voiceXML("Microsoft Speech Service Text-to-Speech API", "ar-EG-Hoda", LocaleEnUS, GenderFemale))
+	expect := `<speak version='1.0' xml:lang='en-US'><voice xml:lang='en-US' name='ar-EG-Hoda'><prosody rate="0%" pitch="0%">Microsoft Speech Service Text-to-Speech API</prosody></voice></speak>`
+	assert.Equal(t, expect, voiceXML("Microsoft Speech Service Text-to-Speech API", LocaleenUS, "ar-EG-Hoda", "0%", "0%"))
 }
 
 func TestSynthesize(t *testing.T) {
@@ -18,11 +22,11 @@ func TestSynthesize(t *testing.T) {
 
 	// seed the supported region mapping
 	az.RegionVoiceMap = map[supportedVoices]string{
-		{GenderMale, LocaleDeCH}: "SYS2064",
+		{"Male", "de-CH"}: "SYS2064",
 	}
 
-	// payload should be nil and err should be true, since DeCH + Female is not a valid combination
-	payload, err := az.Synthesize("test-speech", LocaleDeCH, GenderFemale, AudioRIFF8Bit8kHzMonoPCM)
+	// payload should be nil and err should be true, since no endpoint has been configured yet
+	payload, err := az.Synthesize("test-speech", LocaledeCH, "SYS2064", "0%", "0%", RIFF8khz8bitMonoMulaw)
 	assert.Error(t, err, "should raise an error")
 	assert.Nil(t, payload, "payload should be nil")
 
@@ -35,7 +39,7 @@ func TestSynthesize(t *testing.T) {
 
 	az.textToSpeechURL = ts.URL
 	// request should now be successful with a valid locale and gender.
-	payload, err = az.Synthesize("SYS4096", LocaleDeCH, GenderMale, AudioRIFF8Bit8kHzMonoPCM)
+	payload, err = az.Synthesize("SYS4096", LocaledeCH, "SYS2064", "0%", "0%", RIFF8khz8bitMonoMulaw)
 	assert.NoError(t, err)
 	assert.Equal(t, payload, []byte("SYS4096"))
 }
@@ -57,3 +61,62 @@ func TestRefreshToken(t *testing.T) {
 	assert.NoError(t, err, "should not return an error")
 	assert.Equal(t, az.SubscriptionKey, az.accessToken, "values should be equal")
 }
+
+func TestConnectionReuse(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("SYS4096"))
+	}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+	az := &AzureCSTextToSpeech{SubscriptionKey: "SYS64738", textToSpeechURL: ts.URL, tokenRefreshURL: ts.URL}
+
+	// the token is replaced while requests read it.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, az.refreshToken())
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		_, err := az.SynthesizeSSMLWithContext(context.Background(), "<speak/>", RIFF16khz16bitMonoPCM)
+		assert.NoError(t, err)
+	}
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	assert.LessOrEqual(t, conns, 2, "connections are reused")
+}
+
+func TestSynthesizeStream(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Microsoft-OutputFormat") != RAW8khz8bitMonoMulaw.String() {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("SYS4096"))
+		}),
+	)
+	defer ts.Close()
+	az := &AzureCSTextToSpeech{accessToken: "SYS49152", textToSpeechURL: ts.URL}
+
+	body, err := az.SynthesizeStreamWithContext(context.Background(), "<speak/>", RAW8khz8bitMonoMulaw)
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(body)
+	body.Close()
+	assert.Equal(t, []byte("SYS4096"), b)
+
+	body, err = az.SynthesizeStreamWithContext(context.Background(), "<speak/>", RIFF16khz16bitMonoPCM)
+	assert.Error(t, err, "a 400 response should be reported before streaming")
+	assert.Nil(t, body)
+}
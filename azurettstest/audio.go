@@ -0,0 +1,92 @@
+package azurettstest
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/mp3"
+	"github.com/linexjlin/azuretexttospeech/audio/ogg"
+	"github.com/linexjlin/azuretexttospeech/audio/opus"
+	"github.com/linexjlin/azuretexttospeech/audio/pcm"
+	"github.com/linexjlin/azuretexttospeech/audio/webm"
+)
+
+// Tone is the frequency and level of the audio rendered in sample based formats.
+const (
+	ToneFrequency = 440.0
+	ToneAmplitude = 0.25
+)
+
+// opusSilence is an Opus packet holding 20 ms of silence: a CELT only frame at full bandwidth whose energy is all
+// zero.
+var opusSilence = []byte{0xF8, 0xFF, 0xFE}
+
+// opusPreSkip is the pre-skip written to the Opus streams, the usual encoder delay of 6.5 ms.
+const opusPreSkip = 312
+
+// Audio renders d of deterministic audio in format: a sine tone for PCM, mu-law and A-law formats, and valid
+// silent streams for MP3, Ogg and WebM Opus and AMR-WB, so that the audio package measures d (rounded to whole
+// frames) for every one of them. Proprietary codecs get zero bytes at their bitrate.
+func Audio(format tts.AudioOutput, d time.Duration) ([]byte, error) {
+	info := format.Info()
+	switch info.Container {
+	case tts.ContainerRaw, tts.ContainerRIFF:
+		switch info.Codec {
+		case tts.CodecPCM, tts.CodecMulaw, tts.CodecAlaw:
+			n := int(int64(d) * int64(info.SampleRate) / int64(time.Second))
+			b := &pcm.Buffer{SampleRate: info.SampleRate, Channels: info.Channels, Samples: make([]float64, n*info.Channels)}
+			for i := 0; i < n; i++ {
+				v := ToneAmplitude * math.Sin(2*math.Pi*ToneFrequency*float64(i)/float64(info.SampleRate))
+				for c := 0; c < info.Channels; c++ {
+					b.Samples[i*info.Channels+c] = v
+				}
+			}
+			return pcm.Encode(b, format)
+		}
+		return make([]byte, int64(info.Bitrate/8)*int64(d)/int64(time.Second)), nil
+	case tts.ContainerMP3:
+		return mp3Silence(info.SampleRate, info.Bitrate, d)
+	case tts.ContainerOgg, tts.ContainerWebM:
+		s := &opus.Stream{Head: &opus.Head{Version: 1, Channels: 1, PreSkip: opusPreSkip, InputRate: uint32(info.SampleRate)}}
+		samples := int64(d)*opus.SampleRate/int64(time.Second) + opusPreSkip
+		for n := int64(0); n < samples; n += 960 {
+			s.Packets = append(s.Packets, opusSilence)
+			s.Discard = n + 960 - samples
+		}
+		if info.Container == tts.ContainerOgg {
+			return ogg.Encode(s, 1), nil
+		}
+		return webm.Encode(s), nil
+	case tts.ContainerAMR:
+		// NO_DATA frames, 20 ms each.
+		b := []byte("#!AMR-WB\n")
+		for n := d / (20 * time.Millisecond); n > 0; n-- {
+			b = append(b, 15<<3|0x04)
+		}
+		return b, nil
+	}
+	return nil, nil
+}
+
+// mp3Silence returns mono layer 3 frames of silence amounting to d, rounded to the nearest frame, at the given sample
+// rate and constant bitrate. The frames carry no CRC and all of their side information is zero.
+func mp3Silence(sampleRate, bitrate int, d time.Duration) ([]byte, error) {
+	for _, version := range []byte{3, 2, 0} { // MPEG1, MPEG2 and MPEG2.5
+		for ri := byte(0); ri < 3; ri++ {
+			for bi := byte(1); bi < 15; bi++ {
+				header := []byte{0xff, 0xe0 | version<<3 | 1<<1 | 1, bi<<4 | ri<<2, 3 << 6}
+				h, err := mp3.ParseHeader(header)
+				if err != nil || h.SampleRate != sampleRate || h.Bitrate != bitrate {
+					continue
+				}
+				frame := make([]byte, h.Size())
+				copy(frame, header)
+				return bytes.Repeat(frame, int((d+h.Duration()/2)/h.Duration())), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported MP3 bitrate %d at %d Hz", bitrate, sampleRate)
+}
@@ -0,0 +1,117 @@
+package azurettstest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+// Fake is an in-memory tts.Synthesizer for unit tests that need no server. It validates SSML and renders audio
+// like Server.
+type Fake struct {
+	Voices []tts.Voice // listed and accepted in SSML
+	// Errors are returned by the following calls, one each, before the fake answers normally again. A nil entry
+	// lets its call succeed.
+	Errors []error
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+var _ tts.EventSynthesizer = (*Fake)(nil)
+
+// NewFake returns a fake serving DefaultVoices.
+func NewFake() *Fake {
+	return &Fake{Voices: DefaultVoices()}
+}
+
+// Requests returns the synthesis requests received so far.
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+// next returns the error queued for a call, if any.
+func (f *Fake) next(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.Errors) == 0 {
+		return nil
+	}
+	err := f.Errors[0]
+	f.Errors = f.Errors[1:]
+	return err
+}
+
+// SynthesizeSSMLWithContext implements tts.Synthesizer. Invalid SSML fails with a 400 tts.StatusError.
+func (f *Fake) SynthesizeSSMLWithContext(ctx context.Context, ssml string, audioOutput tts.AudioOutput) ([]byte, error) {
+	doc, err := f.parse(ctx, ssml, audioOutput)
+	if err != nil {
+		return nil, err
+	}
+	return Audio(audioOutput, Duration(doc.text))
+}
+
+// SynthesizeEventsWithContext implements tts.EventSynthesizer, writing the audio before reporting the events Server
+// sends.
+func (f *Fake) SynthesizeEventsWithContext(ctx context.Context, ssml string, audioOutput tts.AudioOutput, w io.Writer, onEvent func(tts.Event)) error {
+	doc, err := f.parse(ctx, ssml, audioOutput)
+	if err != nil {
+		return err
+	}
+	b, err := Audio(audioOutput, Duration(doc.text))
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	for _, e := range doc.events() {
+		onEvent(e)
+	}
+	return nil
+}
+
+// parse records a synthesis request and parses its SSML.
+func (f *Fake) parse(ctx context.Context, ssml string, audioOutput tts.AudioOutput) (*document, error) {
+	if err := f.next(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.requests = append(f.requests, Request{Format: audioOutput, SSML: ssml})
+	voices := f.Voices
+	f.mu.Unlock()
+	doc, err := parseSSML(ssml, func(name string) bool { return hasVoice(voices, name) })
+	if err != nil {
+		return nil, &tts.StatusError{StatusCode: http.StatusBadRequest, Message: err.Error()}
+	}
+	return doc, nil
+}
+
+// SynthesizeStreamWithContext implements tts.Synthesizer.
+func (f *Fake) SynthesizeStreamWithContext(ctx context.Context, ssml string, audioOutput tts.AudioOutput) (io.ReadCloser, error) {
+	b, err := f.SynthesizeSSMLWithContext(ctx, ssml, audioOutput)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// VoicesWithContext implements tts.Synthesizer.
+func (f *Fake) VoicesWithContext(ctx context.Context) ([]tts.Voice, error) {
+	if err := f.next(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]tts.Voice(nil), f.Voices...), nil
+}
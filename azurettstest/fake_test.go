@@ -0,0 +1,56 @@
+package azurettstest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	f := NewFake()
+	f.Errors = []error{errors.New("boom"), nil}
+	s := tts.Wrap(f, tts.RetryMiddleware(2, time.Millisecond))
+	ctx := context.Background()
+
+	_, err := s.SynthesizeSSMLWithContext(ctx, testSSML, tts.RIFF16khz16bitMonoPCM)
+	assert.EqualError(t, err, "boom", "not a temporary error")
+	b, err := s.SynthesizeSSMLWithContext(ctx, testSSML, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	want, _ := Audio(tts.RIFF16khz16bitMonoPCM, Duration("Hello world. How are you?"))
+	assert.Equal(t, want, b)
+
+	r, err := s.SynthesizeStreamWithContext(ctx, testSSML, tts.RIFF16khz16bitMonoPCM)
+	if assert.NoError(t, err) {
+		streamed, _ := ioutil.ReadAll(r)
+		assert.Equal(t, want, streamed)
+	}
+
+	var audio bytes.Buffer
+	var bookmarks []string
+	err = s.(tts.EventSynthesizer).SynthesizeEventsWithContext(ctx, testSSML, tts.RIFF16khz16bitMonoPCM, &audio, func(e tts.Event) {
+		if e.Type == tts.EventBookmark {
+			bookmarks = append(bookmarks, e.Bookmark)
+		}
+	})
+	assert.NoError(t, err, "forwarded by the middleware")
+	assert.Equal(t, want, audio.Bytes())
+	assert.Equal(t, []string{"next"}, bookmarks)
+	assert.Len(t, f.Requests(), 3)
+
+	_, err = s.SynthesizeSSMLWithContext(ctx, `<speak version="1.0" xml:lang="en-US"><voice name="x">Hi</voice></speak>`, tts.RIFF16khz16bitMonoPCM)
+	var status *tts.StatusError
+	if assert.True(t, errors.As(err, &status)) {
+		assert.Equal(t, 400, status.StatusCode)
+	}
+
+	f.Errors = []error{&tts.StatusError{StatusCode: 503, Message: "unavailable"}}
+	voices, err := s.VoicesWithContext(ctx)
+	assert.NoError(t, err, "retried")
+	assert.Equal(t, DefaultVoices(), voices)
+}
@@ -0,0 +1,438 @@
+// Package azurettstest provides a fake of the Azure text-to-speech service for tests. The server issues tokens,
+// serves a voice list, validates authentication and SSML like the service, and renders deterministic audio, a
+// tone or silence lasting CharDuration per character of text, in the requested format over both the REST and the
+// WebSocket protocols. Faults such as 401, 429 and 5xx responses or added latency can be injected per endpoint.
+//
+//	s := azurettstest.NewServer()
+//	defer s.Close()
+//	az, err := s.Client()
+package azurettstest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+// Endpoint paths, as used by tts.WithEndpoint.
+const (
+	TokenPath     = "/sts/v1.0/issueToken"
+	SynthesisPath = "/cognitiveservices/v1"
+	VoicesPath    = "/cognitiveservices/voices/list"
+	WebSocketPath = "/cognitiveservices/websocket/v1"
+)
+
+const (
+	// DefaultKey is the subscription key accepted by a new Server.
+	DefaultKey = "azurettstest-key"
+	// CharDuration is the length of audio rendered for every character of text.
+	CharDuration = 50 * time.Millisecond
+	// TokenLifetime is how long an issued token is accepted, as with the service.
+	TokenLifetime = 10 * time.Minute
+)
+
+// Fault changes how the server answers requests to an endpoint.
+type Fault struct {
+	Path   string        // endpoint affected, one of the Path constants, or empty for all of them
+	Status int           // status answered instead of the normal response, zero to answer normally
+	Delay  time.Duration // wait before answering
+	Count  int           // number of requests affected, zero for all following requests
+}
+
+// Request is a synthesis request received by the server.
+type Request struct {
+	Path   string
+	Format tts.AudioOutput
+	SSML   string
+}
+
+// Server is a fake text-to-speech service listening on a local address.
+type Server struct {
+	*httptest.Server
+	Key    string      // subscription key accepted by the token endpoint and in the Ocp-Apim-Subscription-Key header
+	Voices []tts.Voice // served by the voice list endpoint and accepted in SSML
+
+	mu       sync.Mutex
+	tokens   map[string]time.Time // expiry of the tokens issued
+	faults   []*Fault
+	requests []Request
+}
+
+// NewServer starts a server accepting DefaultKey and serving DefaultVoices. Close must be called when done.
+func NewServer() *Server {
+	s := &Server{Key: DefaultKey, Voices: DefaultVoices(), tokens: map[string]time.Time{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Client returns a client for the server, created with tts.New. Close its TokenRefreshDoneCh when done.
+func (s *Server) Client(opts ...tts.Option) (*tts.AzureCSTextToSpeech, error) {
+	return tts.New(s.Key, tts.RegionWestUS2, "", append([]tts.Option{tts.WithEndpoint(s.URL)}, opts...)...)
+}
+
+// Inject adds a fault. Faults apply in the order they were added; the first matching a request is used.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the synthesis requests received so far, over either protocol.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// fault returns the fault applying to a request for path, if any, counting it against its limit.
+func (s *Server) fault(path string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if f.Path != "" && f.Path != path {
+			continue
+		}
+		applied := *f
+		if f.Count > 0 {
+			if f.Count--; f.Count == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return &applied
+	}
+	return nil
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if f := s.fault(r.URL.Path); f != nil {
+		if !sleep(r.Context(), f.Delay) {
+			return
+		}
+		if f.Status != 0 {
+			if f.Status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+			}
+			w.WriteHeader(f.Status)
+			return
+		}
+	}
+	switch r.URL.Path {
+	case TokenPath:
+		s.serveToken(w, r)
+	case VoicesPath:
+		if s.authorize(w, r) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			json.NewEncoder(w).Encode(s.Voices)
+		}
+	case SynthesisPath:
+		if s.authorize(w, r) {
+			s.serveSynthesis(w, r)
+		}
+	case WebSocketPath:
+		if s.authorize(w, r) {
+			s.serveWebSocket(w, r)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// sleep waits for d unless ctx is done first, reporting whether it waited.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("Ocp-Apim-Subscription-Key") != s.Key {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	token := randomID()
+	s.mu.Lock()
+	s.tokens[token] = time.Now().Add(TokenLifetime)
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/jwt; charset=us-ascii")
+	w.Write([]byte(token))
+}
+
+// authorize checks the bearer token or subscription key of a request, answering 401 when neither is valid.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Ocp-Apim-Subscription-Key") == s.Key {
+		return true
+	}
+	if token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token != "" {
+		s.mu.Lock()
+		expiry, ok := s.tokens[token]
+		s.mu.Unlock()
+		if ok && time.Now().Before(expiry) {
+			return true
+		}
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+	return false
+}
+
+func (s *Server) knownVoice(name string) bool {
+	return hasVoice(s.Voices, name)
+}
+
+// hasVoice reports whether name is the short or full name of one of voices.
+func hasVoice(voices []tts.Voice, name string) bool {
+	for _, v := range voices {
+		if name != "" && (v.ShortName == name || v.Name == name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) serveSynthesis(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/ssml+xml") {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	format, ok := tts.AudioOutputByHeader(r.Header.Get("X-Microsoft-OutputFormat"))
+	if !ok {
+		http.Error(w, "unsupported output format", http.StatusBadRequest)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	s.record(Request{Path: SynthesisPath, Format: format, SSML: string(body)})
+	doc, err := parseSSML(string(body), s.knownVoice)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	audio, err := Audio(format, Duration(doc.text))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", format.Info().MIMEType)
+	w.Write(audio)
+}
+
+func (s *Server) record(r Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+}
+
+// serveWebSocket speaks the WebSocket protocol: after the speech.config, synthesis.context and ssml messages it
+// sends the events of the document, the audio in chunks and turn.end. Invalid SSML closes the connection with
+// status 1007 as the service does.
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fail := func(reason string) {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInvalidFramePayloadData, reason))
+	}
+
+	var format tts.AudioOutput
+	var ssml, requestID string
+	configured := false
+	for ssml == "" {
+		_, m, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		headers, body := parseTextMessage(m)
+		switch headers["path"] {
+		case "synthesis.context":
+			var c struct {
+				Synthesis struct{ Audio struct{ OutputFormat string } }
+			}
+			json.Unmarshal([]byte(body), &c)
+			f, ok := tts.AudioOutputByHeader(c.Synthesis.Audio.OutputFormat)
+			if !ok {
+				fail("unsupported output format")
+				return
+			}
+			format, configured = f, true
+		case "ssml":
+			if !configured {
+				fail("missing synthesis.context")
+				return
+			}
+			ssml, requestID = body, headers["x-requestid"]
+			if ssml == "" {
+				fail("empty SSML")
+				return
+			}
+		}
+	}
+	s.record(Request{Path: WebSocketPath, Format: format, SSML: ssml})
+	doc, err := parseSSML(ssml, s.knownVoice)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	audio, err := Audio(format, Duration(doc.text))
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+
+	send := func(kind int, m []byte) bool {
+		return conn.WriteMessage(kind, m) == nil
+	}
+	audioHeaders := fmt.Sprintf("X-RequestId:%s\r\nContent-Type:%s\r\nPath:audio", requestID, format.Info().MIMEType)
+	if !send(websocket.TextMessage, textMessage("turn.start", requestID, `{"context":{"serviceTag":"azurettstest"}}`)) ||
+		!send(websocket.TextMessage, textMessage("response", requestID, `{"context":{"serviceTag":"azurettstest"},"audio":{"type":"inline"}}`)) {
+		return
+	}
+	if events := doc.events(); len(events) > 0 && !send(websocket.TextMessage, textMessage("audio.metadata", requestID, metadata(events))) {
+		return
+	}
+	for len(audio) > 0 {
+		n := 4096
+		if n > len(audio) {
+			n = len(audio)
+		}
+		if !send(websocket.BinaryMessage, binaryMessage(audioHeaders, audio[:n])) {
+			return
+		}
+		audio = audio[n:]
+	}
+	if send(websocket.BinaryMessage, binaryMessage(audioHeaders, nil)) {
+		send(websocket.TextMessage, textMessage("turn.end", requestID, "{}"))
+	}
+}
+
+// metadata encodes events as the body of an audio.metadata message, with offsets in ticks of 100 ns.
+func metadata(events []tts.Event) string {
+	type text struct {
+		Text         string
+		Length       int
+		BoundaryType string
+	}
+	type data struct {
+		Offset         int64
+		Duration       int64  `json:",omitempty"`
+		Text           *text  `json:"text,omitempty"`
+		Bookmark       string `json:",omitempty"`
+		VisemeID       int    `json:"VisemeId,omitempty"`
+		AnimationChunk string `json:",omitempty"`
+	}
+	type entry struct {
+		Type string
+		Data data
+	}
+	var m struct{ Metadata []entry }
+	for _, e := range events {
+		d := data{Offset: int64(e.Offset / 100), Duration: int64(e.Duration / 100), Bookmark: e.Bookmark, VisemeID: e.VisemeID, AnimationChunk: e.Animation}
+		if e.Text != "" {
+			d.Text = &text{Text: e.Text, Length: len([]rune(e.Text)), BoundaryType: e.Boundary}
+		}
+		m.Metadata = append(m.Metadata, entry{Type: string(e.Type), Data: d})
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+// textMessage encodes a text message of the WebSocket protocol.
+func textMessage(path, requestID, body string) []byte {
+	return []byte(fmt.Sprintf("X-RequestId:%s\r\nContent-Type:application/json; charset=utf-8\r\nPath:%s\r\n\r\n%s", requestID, path, body))
+}
+
+// binaryMessage encodes a binary message of the WebSocket protocol: the length of the headers as a big endian
+// uint16, the headers and the body.
+func binaryMessage(headers string, body []byte) []byte {
+	b := make([]byte, 2, 2+len(headers)+len(body))
+	binary.BigEndian.PutUint16(b, uint16(len(headers)))
+	return append(append(b, headers...), body...)
+}
+
+// parseTextMessage splits a text message of the WebSocket protocol into its headers, keyed in lower case, and
+// body.
+func parseTextMessage(m []byte) (map[string]string, string) {
+	head, body := string(m), ""
+	if i := strings.Index(head, "\r\n\r\n"); i >= 0 {
+		head, body = head[:i], head[i+4:]
+	}
+	headers := map[string]string{}
+	for _, line := range strings.Split(head, "\r\n") {
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			headers[strings.ToLower(strings.TrimSpace(line[:i]))] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	return headers, body
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// DefaultVoices returns a small voice list in the form the service returns it.
+func DefaultVoices() []tts.Voice {
+	voice := func(locale, name, gender string, styles ...string) tts.Voice {
+		return tts.Voice{
+			Name:            fmt.Sprintf("Microsoft Server Speech Text to Speech Voice (%s, %s)", locale, name),
+			ShortName:       locale + "-" + name,
+			DisplayName:     strings.TrimSuffix(name, "Neural"),
+			LocalName:       strings.TrimSuffix(name, "Neural"),
+			Gender:          gender,
+			StyleList:       styles,
+			Locale:          locale,
+			Status:          "GA",
+			SampleRateHertz: "48000",
+			VoiceType:       "Neural",
+			WordsPerMinute:  "150",
+		}
+	}
+	return []tts.Voice{
+		voice("en-US", "JennyNeural", "Female", "assistant", "chat", "customerservice", "newscast", "angry", "cheerful", "sad"),
+		voice("en-US", "GuyNeural", "Male", "newscast", "angry", "cheerful", "sad"),
+		voice("en-GB", "SoniaNeural", "Female", "cheerful", "sad"),
+		voice("de-DE", "KatjaNeural", "Female"),
+		voice("de-CH", "JanNeural", "Male"),
+		voice("fr-FR", "DeniseNeural", "Female", "cheerful", "sad"),
+		voice("ja-JP", "NanamiNeural", "Female", "chat", "cheerful", "customerservice"),
+		voice("zh-CN", "XiaoxiaoNeural", "Female", "assistant", "chat", "customerservice", "newscast", "affectionate"),
+	}
+}
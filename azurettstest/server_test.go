@@ -0,0 +1,186 @@
+package azurettstest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio"
+	"github.com/linexjlin/azuretexttospeech/audio/mp3"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSSML = `<speak version="1.0" xml:lang="en-US"><voice name="en-US-JennyNeural">Hello world. <bookmark mark="next"/>How are you?</voice></speak>`
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	az, err := s.Client()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer close(az.TokenRefreshDoneCh)
+
+	voices, err := az.Voices()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultVoices(), voices)
+
+	// every container renders audio of the expected length, to within a frame.
+	for _, format := range []tts.AudioOutput{
+		tts.RIFF24khz16bitMonoPCM,
+		tts.RAW8khz8bitMonoMulaw,
+		tts.AUDIO16khz32kbitrateMonoMP3,
+		tts.OGG48khz16bitMonoOpus,
+		tts.WEBM24khz16bitMonoOpus,
+		tts.AMRWB16000hz,
+	} {
+		b, err := az.Synthesize("Hello world", tts.LocaleenUS, "en-US-JennyNeural", "default", "default", format)
+		if !assert.NoError(t, err, format.String()) {
+			continue
+		}
+		d, err := audio.Duration(b, format)
+		assert.NoError(t, err, format.String())
+		assert.InDelta(t, float64(Duration("Hello world")), float64(d), float64(72*time.Millisecond), format.String())
+	}
+	requests := s.Requests()
+	assert.Len(t, requests, 6)
+	assert.Equal(t, tts.AMRWB16000hz, requests[5].Format)
+	assert.Contains(t, requests[5].SSML, "Hello world")
+
+	_, err = az.SynthesizeSSML(`<speak version="1.0" xml:lang="en-US"><voice name="en-US-NobodyNeural">Hi</voice></speak>`, tts.RIFF16khz16bitMonoPCM)
+	assert.Error(t, err, "unknown voice")
+	_, err = az.SynthesizeSSML(`<speak version="1.0" xml:lang="en-US">Hi</speak>`, tts.RIFF16khz16bitMonoPCM)
+	assert.Error(t, err, "text outside of a voice")
+	_, err = az.SynthesizeSSML(`<speak version="1.0"><voice name="en-US-GuyNeural">Hi</voice></speak>`, tts.RIFF16khz16bitMonoPCM)
+	assert.Error(t, err, "missing xml:lang")
+
+	_, err = tts.New("wrong", tts.RegionWestUS2, "", tts.WithEndpoint(s.URL))
+	assert.Error(t, err)
+
+	// requests are accepted with the subscription key in place of a token.
+	request, _ := http.NewRequest(http.MethodGet, s.URL+VoicesPath, nil)
+	request.Header.Set("Ocp-Apim-Subscription-Key", DefaultKey)
+	response, err := http.DefaultClient.Do(request)
+	if assert.NoError(t, err) {
+		response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+	request.Header.Set("Ocp-Apim-Subscription-Key", "")
+	request.Header.Set("Authorization", "Bearer forged")
+	response, err = http.DefaultClient.Do(request)
+	if assert.NoError(t, err) {
+		response.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	}
+}
+
+func TestServerWebSocket(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	az, err := s.Client()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer close(az.TokenRefreshDoneCh)
+
+	b, events, err := az.SynthesizeSSMLWithEvents(testSSML, tts.RIFF16khz16bitMonoPCM)
+	if !assert.NoError(t, err) {
+		return
+	}
+	d, err := audio.Duration(b, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, Duration("Hello world. How are you?"), d)
+
+	var words, sentences []string
+	var mark tts.Event
+	for _, e := range events {
+		switch e.Type {
+		case tts.EventWordBoundary:
+			words = append(words, e.Text)
+		case tts.EventSentenceBoundary:
+			sentences = append(sentences, e.Text)
+		case tts.EventBookmark:
+			mark = e
+		}
+	}
+	assert.Equal(t, []string{"Hello", "world.", "How", "are", "you?"}, words)
+	assert.Equal(t, []string{"Hello world.", "How are you?"}, sentences)
+	assert.Equal(t, "next", mark.Bookmark)
+	assert.Equal(t, Duration("Hello world."), mark.Offset)
+
+	segments, err := audio.Split(b, tts.RIFF16khz16bitMonoPCM, events)
+	assert.NoError(t, err)
+	assert.Len(t, segments, 2)
+
+	_, _, err = az.SynthesizeSSMLWithEvents(`<speak version="1.0" xml:lang="en-US">Hi</speak>`, tts.RIFF16khz16bitMonoPCM)
+	assert.Error(t, err)
+}
+
+func TestServerFaults(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	az, err := s.Client()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer close(az.TokenRefreshDoneCh)
+
+	s.Inject(Fault{Path: SynthesisPath, Status: http.StatusTooManyRequests, Count: 1})
+	_, err = az.SynthesizeSSML(testSSML, tts.RIFF16khz16bitMonoPCM)
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "429"), err.Error())
+	}
+	_, err = az.SynthesizeSSML(testSSML, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err, "the fault only applied once")
+
+	s.Inject(Fault{Status: http.StatusInternalServerError})
+	_, err = az.Voices()
+	assert.Error(t, err)
+	_, err = az.SynthesizeSSML(testSSML, tts.RIFF16khz16bitMonoPCM)
+	assert.Error(t, err)
+	_, _, err = az.SynthesizeSSMLWithEvents(testSSML, tts.RIFF16khz16bitMonoPCM)
+	assert.Error(t, err)
+	s.ClearFaults()
+
+	s.Inject(Fault{Path: TokenPath, Status: http.StatusUnauthorized})
+	_, err = s.Client()
+	assert.Error(t, err)
+	s.ClearFaults()
+
+	s.Inject(Fault{Path: SynthesisPath, Delay: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = az.SynthesizeSSMLWithContext(ctx, testSSML, tts.RIFF16khz16bitMonoPCM)
+	assert.Error(t, err, "the delay outlasts the deadline")
+}
+
+func TestAudio(t *testing.T) {
+	b, err := Audio(tts.RAW16khz16bitMonoPCM, 100*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Len(t, b, 3200)
+	assert.False(t, bytes.Equal(b, make([]byte, len(b))), "a tone, not silence")
+
+	b, err = Audio(tts.G722_16khz64kbps, time.Second)
+	assert.NoError(t, err)
+	assert.Len(t, b, 8000)
+
+	b, err = mp3Silence(24000, 48000, time.Second)
+	assert.NoError(t, err)
+	s, err := mp3.Parse(b)
+	assert.NoError(t, err)
+	assert.Len(t, s.Frames, 42)
+	assert.Equal(t, "MPEG2 layer 3 24000 Hz mono", s.Frames[0].String())
+	assert.Equal(t, 1008*time.Millisecond, s.Duration())
+	b, err = mp3Silence(44100, 128000, time.Second)
+	assert.NoError(t, err)
+	s, err = mp3.Parse(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "MPEG1 layer 3 44100 Hz mono", s.Frames[0].String())
+	_, err = mp3Silence(24000, 320000, time.Second)
+	assert.Error(t, err)
+}
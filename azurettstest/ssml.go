@@ -0,0 +1,154 @@
+package azurettstest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+// document is the part of an SSML document the server renders: its text, with runs of whitespace collapsed, and
+// the bookmarks placed in it.
+type document struct {
+	text      string
+	voices    []string
+	bookmarks []bookmark
+}
+
+type bookmark struct {
+	mark string
+	at   int // rune offset into text
+}
+
+// parseSSML validates an SSML document the way the service does: the root must be a <speak> element with version
+// and xml:lang attributes, text must be spoken by a <voice> naming a known voice, and the XML must be well formed.
+func parseSSML(ssml string, known func(voice string) bool) (*document, error) {
+	d := &document{}
+	var text strings.Builder
+	dec := xml.NewDecoder(strings.NewReader(ssml))
+	depth, voiceDepth := 0, 0
+	root := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSML, %v", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				if root || t.Name.Local != "speak" {
+					return nil, fmt.Errorf("invalid SSML, the root element must be <speak>")
+				}
+				if attr(t, "version") == "" || attr(t, "lang") == "" {
+					return nil, fmt.Errorf("invalid SSML, <speak> requires the version and xml:lang attributes")
+				}
+				root = true
+				continue
+			}
+			switch t.Name.Local {
+			case "voice":
+				name := attr(t, "name")
+				if !known(name) {
+					return nil, fmt.Errorf("unsupported voice %q", name)
+				}
+				d.voices = append(d.voices, name)
+				if voiceDepth == 0 {
+					voiceDepth = depth
+				}
+			case "bookmark":
+				d.bookmarks = append(d.bookmarks, bookmark{mark: attr(t, "mark"), at: utf8.RuneCountInString(text.String())})
+			}
+		case xml.EndElement:
+			if depth == voiceDepth {
+				voiceDepth = 0
+			}
+			depth--
+		case xml.CharData:
+			s := strings.Join(strings.Fields(string(t)), " ")
+			if s == "" {
+				continue
+			}
+			if voiceDepth == 0 {
+				return nil, fmt.Errorf("invalid SSML, text outside of a <voice> element")
+			}
+			if text.Len() > 0 {
+				text.WriteByte(' ')
+			}
+			text.WriteString(s)
+		}
+	}
+	if !root {
+		return nil, fmt.Errorf("invalid SSML, no <speak> element")
+	}
+	d.text = text.String()
+	return d, nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Duration returns the length of the audio the server renders for text: CharDuration for every character once
+// runs of whitespace are collapsed.
+func Duration(text string) time.Duration {
+	return time.Duration(utf8.RuneCountInString(strings.Join(strings.Fields(text), " "))) * CharDuration
+}
+
+// events returns the events of the WebSocket protocol for d: a boundary and a viseme for every word, a sentence
+// boundary for every sentence and the bookmarks, each placed at the offset of its first character.
+func (d *document) events() []tts.Event {
+	var events []tts.Event
+	at := func(i int) time.Duration { return time.Duration(i) * CharDuration }
+	marks := d.bookmarks
+	runes := []rune(d.text)
+	sentence := 0
+	for i := 0; i <= len(runes); {
+		for len(marks) > 0 && marks[0].at <= i {
+			events = append(events, tts.Event{Type: tts.EventBookmark, Offset: at(i), Bookmark: marks[0].mark})
+			marks = marks[1:]
+		}
+		if i == len(runes) {
+			break
+		}
+		if runes[i] == ' ' {
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && runes[end] != ' ' {
+			end++
+		}
+		word := string(runes[i:end])
+		events = append(events,
+			tts.Event{Type: tts.EventWordBoundary, Offset: at(i), Duration: at(end - i), Text: word, Boundary: "WordBoundary"},
+			tts.Event{Type: tts.EventViseme, Offset: at(i), VisemeID: int(runes[i])%21 + 1},
+		)
+		if strings.ContainsAny(word[len(word)-1:], ".!?") || end == len(runes) {
+			events = append(events, tts.Event{Type: tts.EventSentenceBoundary, Offset: at(sentence), Duration: at(end - sentence), Text: string(runes[sentence:end]), Boundary: "SentenceBoundary"})
+			sentence = end + 1
+		}
+		i = end
+	}
+	// the service reports a sentence before its words.
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Offset != events[j].Offset {
+			return events[i].Offset < events[j].Offset
+		}
+		return events[i].Type == tts.EventSentenceBoundary && events[j].Type != tts.EventSentenceBoundary
+	})
+	return events
+}
@@ -0,0 +1,182 @@
+// Package batch pre-renders a manifest of prompts to audio files with bounded concurrency. Prompts whose content
+// is unchanged since a previous run are skipped, and every run produces a result manifest describing the files
+// written.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio"
+)
+
+// defaultConcurrency is the number of simultaneous synthesis requests when Options.Concurrency is not set.
+const defaultConcurrency = 4
+
+// Synthesizer renders an SSML document, *tts.AzureCSTextToSpeech satisfies this interface.
+type Synthesizer interface {
+	SynthesizeSSMLWithContext(ctx context.Context, ssml string, audioOutput tts.AudioOutput) ([]byte, error)
+}
+
+// Options control a Run.
+type Options struct {
+	OutputDir   string  // directory receiving the audio files, created when missing.
+	Concurrency int     // maximum simultaneous synthesis requests, defaults to 4.
+	Previous    *Result // result of an earlier run, used to skip unchanged prompts.
+	Force       bool    // synthesize every prompt even when unchanged.
+}
+
+// PromptResult records the outcome for a single prompt.
+type PromptResult struct {
+	ID         string `json:"id"`
+	File       string `json:"file,omitempty"`
+	Format     string `json:"format,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	Size       int64  `json:"size"`
+	DurationMS int64  `json:"duration_ms,omitempty"` // play time of the audio, omitted when it cannot be measured
+	ElapsedMS  int64  `json:"elapsed_ms"`            // wall-clock time of the synthesis request, not of the audio
+	Skipped    bool   `json:"skipped,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Result is the manifest emitted by Run. Prompts are listed in manifest order.
+type Result struct {
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	Prompts  []PromptResult `json:"prompts"`
+}
+
+// Failed returns the number of prompts that could not be rendered.
+func (r *Result) Failed() int {
+	n := 0
+	for _, p := range r.Prompts {
+		if p.Error != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// LoadResult reads a result manifest previously written by WriteFile.
+func LoadResult(path string) (*Result, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Result
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("unable to decode result manifest %s, %v", path, err)
+	}
+	return &r, nil
+}
+
+// WriteFile stores the result manifest as indented JSON.
+func (r *Result) WriteFile(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// Run synthesizes every prompt in m and writes the audio to opts.OutputDir as <id>.<extension>. Failures of
+// individual prompts are reported in the Result; an error is only returned when the manifest is invalid or the
+// output directory cannot be created.
+func Run(ctx context.Context, s Synthesizer, m *Manifest, opts Options) (*Result, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.OutputDir == "" {
+		opts.OutputDir = "."
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create output directory, %v", err)
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+
+	previous := make(map[string]PromptResult)
+	if opts.Previous != nil {
+		for _, p := range opts.Previous.Prompts {
+			previous[p.ID] = p
+		}
+	}
+
+	res := &Result{Started: time.Now(), Prompts: make([]PromptResult, len(m.Prompts))}
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, p := range m.Prompts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, p Prompt) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res.Prompts[i] = render(ctx, s, m, p, previous[p.ID], opts)
+		}(i, p)
+	}
+	wg.Wait()
+	res.Finished = time.Now()
+	return res, nil
+}
+
+// render synthesizes a single prompt unless the previous result shows it is unchanged and still on disk.
+func render(ctx context.Context, s Synthesizer, m *Manifest, p Prompt, prev PromptResult, opts Options) PromptResult {
+	format, _ := tts.AudioOutputString(m.format(p))
+	ssml := m.ssml(p)
+	r := PromptResult{
+		ID:     p.ID,
+		File:   p.ID + "." + format.Info().Extension,
+		Format: format.String(),
+		Hash:   tts.CacheKey(ssml, m.voice(p), format),
+	}
+	path := filepath.Join(opts.OutputDir, r.File)
+
+	if !opts.Force && prev.Error == "" && prev.Hash == r.Hash && prev.File == r.File {
+		if fi, err := os.Stat(path); err == nil {
+			r.Size = fi.Size()
+			r.DurationMS = prev.DurationMS
+			r.Skipped = true
+			// results written before durations were recorded lack them.
+			if r.DurationMS == 0 {
+				if b, err := ioutil.ReadFile(path); err == nil {
+					if d, err := audio.Duration(b, format); err == nil {
+						r.DurationMS = d.Milliseconds()
+					}
+				}
+			}
+			return r
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	start := time.Now()
+	b, err := s.SynthesizeSSMLWithContext(ctx, ssml, format)
+	r.ElapsedMS = time.Since(start).Milliseconds()
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Size = int64(len(b))
+	if d, err := audio.Duration(b, format); err == nil {
+		r.DurationMS = d.Milliseconds()
+	}
+	return r
+}
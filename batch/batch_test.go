@@ -0,0 +1,121 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a Synthesizer that echoes the payload back and counts calls.
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) SynthesizeSSMLWithContext(ctx context.Context, ssml string, audioOutput tts.AudioOutput) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, ssml)
+	if strings.Contains(ssml, "FAIL") {
+		return nil, fmt.Errorf("429 - You have exceeded the quota")
+	}
+	if audioOutput.Info().Container == tts.ContainerRIFF {
+		// half a second of silence.
+		return wav.Wrap(make([]byte, audioOutput.Info().Bitrate/16), audioOutput)
+	}
+	return []byte(audioOutput.String()), nil
+}
+
+const manifestYAML = `
+voice: en-US-AriaNeural
+format: RIFF16khz16bitMonoPCM
+prompts:
+  - id: welcome
+    text: Welcome & thanks for calling.
+  - id: goodbye
+    ssml: <speak version='1.0' xml:lang='en-US'><voice name='en-US-GuyNeural'>Goodbye</voice></speak>
+    format: audio-16khz-32kbitrate-mono-mp3
+  - id: broken
+    text: FAIL
+`
+
+func TestLoadManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "azuretts-batch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "prompts.yaml")
+	assert.NoError(t, ioutil.WriteFile(p, []byte(manifestYAML), 0644))
+	m, err := LoadManifest(p)
+	assert.NoError(t, err)
+	assert.Len(t, m.Prompts, 3)
+	assert.Contains(t, m.ssml(m.Prompts[0]), "Welcome &amp; thanks for calling.")
+	assert.Contains(t, m.ssml(m.Prompts[0]), "xml:lang='en-US'")
+
+	p = filepath.Join(dir, "prompts.json")
+	assert.NoError(t, ioutil.WriteFile(p, []byte(`{"format":"nope","prompts":[{"id":"a","ssml":"<speak/>"}]}`), 0644))
+	m, err = LoadManifest(p)
+	assert.NoError(t, err)
+	assert.Error(t, m.Validate(), "unknown format should be rejected")
+
+	dup := &Manifest{Format: "RIFF16khz16bitMonoPCM", Prompts: []Prompt{{ID: "a", SSML: "x"}, {ID: "a", SSML: "y"}}}
+	assert.Error(t, dup.Validate())
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "azuretts-batch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "prompts.yaml")
+	assert.NoError(t, ioutil.WriteFile(p, []byte(manifestYAML), 0644))
+	m, err := LoadManifest(p)
+	assert.NoError(t, err)
+
+	s := &recorder{}
+	res, err := Run(context.Background(), s, m, Options{OutputDir: dir, Concurrency: 2})
+	assert.NoError(t, err)
+	assert.Len(t, s.calls, 3)
+	assert.Equal(t, 1, res.Failed())
+	assert.Equal(t, "welcome.wav", res.Prompts[0].File)
+	assert.Equal(t, int64(500), res.Prompts[0].DurationMS)
+	assert.Equal(t, "goodbye.mp3", res.Prompts[1].File)
+	assert.NotEmpty(t, res.Prompts[2].Error)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "goodbye.mp3"))
+	assert.NoError(t, err)
+	assert.Equal(t, "audio-16khz-32kbitrate-mono-mp3", string(b))
+
+	// a second run only retries the failed prompt and the one whose text changed.
+	m.Prompts[0].Text = "Welcome back."
+	s.calls = nil
+	res, err = Run(context.Background(), s, m, Options{OutputDir: dir, Previous: res})
+	assert.NoError(t, err)
+	assert.Len(t, s.calls, 2)
+	assert.False(t, res.Prompts[0].Skipped)
+	assert.True(t, res.Prompts[1].Skipped)
+	assert.Equal(t, int64(len("audio-16khz-32kbitrate-mono-mp3")), res.Prompts[1].Size)
+
+	// the duration of a skipped prompt is measured when the previous result lacks it.
+	res.Prompts[0].DurationMS = 0
+	res, err = Run(context.Background(), s, m, Options{OutputDir: dir, Previous: res})
+	assert.NoError(t, err)
+	assert.True(t, res.Prompts[0].Skipped)
+	assert.Equal(t, int64(500), res.Prompts[0].DurationMS)
+
+	// result manifests survive a round trip.
+	rp := filepath.Join(dir, "result.json")
+	assert.NoError(t, res.WriteFile(rp))
+	loaded, err := LoadResult(rp)
+	assert.NoError(t, err)
+	assert.Equal(t, res.Prompts, loaded.Prompts)
+}
@@ -0,0 +1,100 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"gopkg.in/yaml.v3"
+)
+
+// Prompt is a single entry of a Manifest. Exactly one of Text or SSML must be set. Voice, Format, Pitch and Rate
+// fall back to the manifest wide defaults when empty.
+type Prompt struct {
+	ID     string `json:"id" yaml:"id"`
+	Text   string `json:"text,omitempty" yaml:"text,omitempty"`
+	SSML   string `json:"ssml,omitempty" yaml:"ssml,omitempty"`
+	Voice  string `json:"voice,omitempty" yaml:"voice,omitempty"`
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+	Pitch  string `json:"pitch,omitempty" yaml:"pitch,omitempty"`
+	Rate   string `json:"rate,omitempty" yaml:"rate,omitempty"`
+}
+
+// Manifest describes a set of prompts to pre-render. Format accepts either the constant name
+// (e.g. "RIFF16khz16bitMonoPCM") or the Azure header value (e.g. "riff-16khz-16bit-mono-pcm").
+type Manifest struct {
+	Voice   string   `json:"voice,omitempty" yaml:"voice,omitempty"`
+	Format  string   `json:"format,omitempty" yaml:"format,omitempty"`
+	Pitch   string   `json:"pitch,omitempty" yaml:"pitch,omitempty"`
+	Rate    string   `json:"rate,omitempty" yaml:"rate,omitempty"`
+	Prompts []Prompt `json:"prompts" yaml:"prompts"`
+}
+
+// LoadManifest reads a YAML or JSON manifest from path. Files ending in .json are decoded as JSON, anything else
+// as YAML. The manifest is not validated, callers may fill in defaults before handing it to Run.
+func LoadManifest(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &m)
+	} else {
+		err = yaml.Unmarshal(b, &m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode manifest %s, %v", path, err)
+	}
+	return &m, nil
+}
+
+// Validate reports the first structural problem found in the manifest.
+func (m *Manifest) Validate() error {
+	seen := make(map[string]bool, len(m.Prompts))
+	for i, p := range m.Prompts {
+		switch {
+		case p.ID == "":
+			return fmt.Errorf("prompt %d has no id", i)
+		case strings.ContainsAny(p.ID, `/\`):
+			return fmt.Errorf("prompt %s: id may not contain path separators", p.ID)
+		case seen[p.ID]:
+			return fmt.Errorf("prompt %s: duplicate id", p.ID)
+		case (p.Text == "") == (p.SSML == ""):
+			return fmt.Errorf("prompt %s: exactly one of text or ssml is required", p.ID)
+		}
+		seen[p.ID] = true
+
+		if _, err := tts.AudioOutputString(m.format(p)); err != nil {
+			return fmt.Errorf("prompt %s: %v", p.ID, err)
+		}
+		if p.Text != "" && m.voice(p) == "" {
+			return fmt.Errorf("prompt %s: a voice is required for text prompts", p.ID)
+		}
+	}
+	return nil
+}
+
+func (m *Manifest) voice(p Prompt) string  { return firstOf(p.Voice, m.Voice) }
+func (m *Manifest) format(p Prompt) string { return firstOf(p.Format, m.Format) }
+
+// ssml renders the payload sent to the service for p.
+func (m *Manifest) ssml(p Prompt) string {
+	if p.SSML != "" {
+		return p.SSML
+	}
+	return tts.VoiceSSML(p.Text, m.voice(p), firstOf(p.Pitch, m.Pitch, "0%"), firstOf(p.Rate, m.Rate, "0%"))
+}
+
+func firstOf(v ...string) string {
+	for _, s := range v {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,278 @@
+package azuretexttospeech
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeNow is used for all cache expiry decisions, tests may override it.
+var timeNow = time.Now
+
+// Cache stores rendered audio keyed by the value returned from CacheKey. Implementations must be safe for
+// concurrent use. A ttl of zero means the entry does not expire.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, audio []byte, ttl time.Duration) error
+}
+
+var (
+	ssmlStructuralGap = regexp.MustCompile(`(</?(?:speak|voice)\b[^>]*>)\s+(</?(?:speak|voice)\b[^>]*>)`)
+	ssmlWhitespace    = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeSSML collapses insignificant whitespace so that cosmetically different payloads share a cache entry:
+// runs of whitespace become a single space, and whitespace between speak and voice tags is removed. Other gaps
+// between elements, such as the space in "<emphasis>a</emphasis> <emphasis>b</emphasis>", are spoken and kept.
+func NormalizeSSML(ssml string) string {
+	s := ssmlWhitespace.ReplaceAllString(strings.TrimSpace(ssml), " ")
+	// matches share no tags, so gaps next to a replaced one need another pass.
+	for {
+		next := ssmlStructuralGap.ReplaceAllString(s, "$1$2")
+		if next == s {
+			return s
+		}
+		s = next
+	}
+}
+
+// CacheKey returns a stable, content-addressed key for a synthesis request. `ssml` is the payload sent to the
+// service, `voice` the voice short name and `audioOutput` the requested format.
+func CacheKey(ssml, voice string, audioOutput AudioOutput) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", NormalizeSSML(ssml), voice, audioOutput)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// memoryEntry is a single element held by MemoryCache.
+type memoryEntry struct {
+	key     string
+	audio   []byte
+	expires time.Time
+}
+
+// MemoryCache is an in-memory LRU Cache bounded by the total size of the stored audio.
+type MemoryCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+// NewMemoryCache returns a MemoryCache holding at most maxBytes of audio.
+func NewMemoryCache(maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns a copy of the cached audio for key.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*memoryEntry)
+	if !e.expires.IsZero() && timeNow().After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return append([]byte(nil), e.audio...), true
+}
+
+// Set stores a copy of audio under key, evicting the least recently used entries to stay within the size bound.
+// Entries larger than the bound are not stored.
+func (c *MemoryCache) Set(key string, audio []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	if int64(len(audio)) > c.maxBytes {
+		return nil
+	}
+
+	e := &memoryEntry{key: key, audio: append([]byte(nil), audio...)}
+	if ttl > 0 {
+		e.expires = timeNow().Add(ttl)
+	}
+	c.items[key] = c.ll.PushFront(e)
+	c.size += int64(len(audio))
+
+	for c.size > c.maxBytes {
+		c.remove(c.ll.Back())
+	}
+	return nil
+}
+
+// Len returns the number of entries currently held.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*memoryEntry)
+	delete(c.items, e.key)
+	c.size -= int64(len(e.audio))
+}
+
+// FileCache is a Cache persisting each entry as a file below a directory. The first 8 bytes of each file hold
+// the expiry as unix nanoseconds (zero for no expiry), followed by the audio.
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache returns a FileCache rooted at dir, creating the directory when required.
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create cache directory, %v", err)
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+// path shards entries by the first two characters of the key to keep directories small. Keys other than the
+// lowercase hex that CacheKey returns are hashed first, so that no key names a file outside the directory.
+func (c *FileCache) path(key string) string {
+	if !isHex(key) {
+		sum := sha256.Sum256([]byte(key))
+		key = hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// isHex reports whether key is made of at least two lowercase hex digits.
+func isHex(key string) bool {
+	if len(key) < 2 {
+		return false
+	}
+	for _, r := range key {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the cached audio for key. Unreadable or expired entries are reported as a miss.
+func (c *FileCache) Get(key string) ([]byte, bool) {
+	p := c.path(key)
+	b, err := ioutil.ReadFile(p)
+	if err != nil || len(b) < 8 {
+		return nil, false
+	}
+	if exp := int64(binary.BigEndian.Uint64(b[:8])); exp != 0 && timeNow().UnixNano() > exp {
+		os.Remove(p)
+		return nil, false
+	}
+	return b[8:], true
+}
+
+// Set writes audio for key. The file is written to a temporary name and renamed so readers never observe a
+// partial entry.
+func (c *FileCache) Set(key string, audio []byte, ttl time.Duration) error {
+	p := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	var exp int64
+	if ttl > 0 {
+		exp = timeNow().Add(ttl).UnixNano()
+	}
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint64(header, uint64(exp))
+
+	f, err := ioutil.TempFile(filepath.Dir(p), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(header, audio...)); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+// flightCall is an in-flight or completed flightGroup.do call.
+type flightCall struct {
+	done chan struct{} // closed once val and err are set
+	val  []byte
+	err  error
+}
+
+// testHookFlightWait is called with the key of a call in flight whenever a caller starts waiting for it.
+var testHookFlightWait = func(key string) {}
+
+// flightGroup suppresses duplicate concurrent synthesis requests for the same key, so that callers asking for
+// identical audio at the same time share a single upstream request.
+type flightGroup struct {
+	mu sync.Mutex
+	m  map[string]*flightCall
+}
+
+// do returns the result of fn, or of the call of fn in flight for key. Callers waiting for another call stop when
+// ctx is done, and try again when that call failed because its own context was done.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, error) {
+	for {
+		g.mu.Lock()
+		if g.m == nil {
+			g.m = make(map[string]*flightCall)
+		}
+		c, ok := g.m[key]
+		if !ok {
+			c = &flightCall{done: make(chan struct{})}
+			g.m[key] = c
+			g.mu.Unlock()
+
+			c.val, c.err = fn()
+			g.mu.Lock()
+			delete(g.m, key)
+			g.mu.Unlock()
+			close(c.done)
+			return c.val, c.err
+		}
+		g.mu.Unlock()
+		testHookFlightWait(key)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+		}
+		if c.err == nil {
+			return append([]byte(nil), c.val...), nil
+		}
+		if !errors.Is(c.err, context.Canceled) && !errors.Is(c.err, context.DeadlineExceeded) {
+			return nil, c.err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
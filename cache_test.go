@@ -0,0 +1,184 @@
+package azuretexttospeech
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheKey(t *testing.T) {
+	a := CacheKey("<speak>\n  <voice name='x'>hello   world</voice>\n</speak>", "x", RIFF16khz16bitMonoPCM)
+	b := CacheKey("<speak><voice name='x'>hello world</voice></speak>", "x", RIFF16khz16bitMonoPCM)
+	assert.Equal(t, a, b, "whitespace differences should not change the key")
+	assert.NotEqual(t, a, CacheKey("<speak><voice name='x'>hello world</voice></speak>", "y", RIFF16khz16bitMonoPCM))
+	assert.NotEqual(t, a, CacheKey("<speak><voice name='x'>hello world</voice></speak>", "x", RIFF24khz16bitMonoPCM))
+
+	spaced := "<speak><voice name='x'><emphasis>a</emphasis> <emphasis>b</emphasis></voice></speak>"
+	assert.NotEqual(t, CacheKey(spaced, "x", RIFF16khz16bitMonoPCM), CacheKey(strings.Replace(spaced, "> <", "><", 1), "x", RIFF16khz16bitMonoPCM),
+		"spoken spaces between elements change the key")
+	assert.Equal(t, "<speak><voice name='x'>a</voice><voice name='y'>b</voice></speak>",
+		NormalizeSSML("<speak>\n <voice name='x'>a</voice>\n <voice name='y'>b</voice>\n</speak>\n"))
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	c := NewMemoryCache(10)
+	assert.NoError(t, c.Set("a", []byte("aaaa"), 0))
+	assert.NoError(t, c.Set("b", []byte("bbbb"), 0))
+
+	// touch "a" so "b" becomes the least recently used entry.
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	assert.NoError(t, c.Set("c", []byte("cccc"), 0))
+	_, ok = c.Get("b")
+	assert.False(t, ok, "b should have been evicted")
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("aaaa"), v)
+
+	// entries larger than the bound are never stored.
+	assert.NoError(t, c.Set("big", make([]byte, 11), 0))
+	_, ok = c.Get("big")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestCacheTTL(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	dir, err := ioutil.TempDir("", "azuretts-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fc, err := NewFileCache(dir)
+	assert.NoError(t, err)
+
+	for name, c := range map[string]Cache{"memory": NewMemoryCache(1024), "file": fc} {
+		assert.NoError(t, c.Set("k", []byte("SYS64738"), time.Minute), name)
+		assert.NoError(t, c.Set("forever", []byte("READY."), 0), name)
+
+		v, ok := c.Get("k")
+		assert.True(t, ok, name)
+		assert.Equal(t, []byte("SYS64738"), v, name)
+
+		now = now.Add(2 * time.Minute)
+		_, ok = c.Get("k")
+		assert.False(t, ok, "%s: entry should have expired", name)
+		_, ok = c.Get("forever")
+		assert.True(t, ok, name)
+	}
+
+	// keys cannot name files outside the directory.
+	assert.NoError(t, fc.Set("../../escaped", []byte("READY."), 0))
+	v, ok := fc.Get("../../escaped")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("READY."), v)
+	_, err = os.Stat(filepath.Join(dir, "..", "..", "escaped"))
+	assert.True(t, os.IsNotExist(err))
+	key := CacheKey("<speak/>", "", RIFF16khz16bitMonoPCM)
+	assert.NoError(t, fc.Set(key, []byte("READY."), 0))
+	_, err = os.Stat(filepath.Join(dir, key[:2], key))
+	assert.NoError(t, err, "keys of CacheKey are used as file names")
+}
+
+func TestSynthesizeCacheDeduplicates(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Write([]byte("SYS4096"))
+	}))
+	defer ts.Close()
+
+	az := &AzureCSTextToSpeech{accessToken: "SYS49152", textToSpeechURL: ts.URL, Cache: NewMemoryCache(1024)}
+	waits, restore := flightWaits()
+	defer restore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b, err := az.Synthesize("READY.", LocaleenUS, "en-US-AriaNeural", "0%", "0%", RIFF16khz16bitMonoPCM)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("SYS4096"), b)
+		}()
+	}
+	for i := 0; i < 7; i++ {
+		<-waits
+	}
+	close(release)
+	wg.Wait()
+
+	_, err := az.Synthesize("READY.", LocaleenUS, "en-US-AriaNeural", "0%", "0%", RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits), "expected a single upstream request")
+}
+
+// flightWaits reports the keys of the calls in flight that callers start waiting for, until restore is called.
+func flightWaits() (waits <-chan string, restore func()) {
+	c := make(chan string, 16)
+	testHookFlightWait = func(key string) { c <- key }
+	return c, func() { testHookFlightWait = func(string) {} }
+}
+
+func TestFlightGroupContexts(t *testing.T) {
+	var g flightGroup
+	waits, restore := flightWaits()
+	defer restore()
+	release := make(chan struct{})
+	leader, cancelLeader := context.WithCancel(context.Background())
+	led := make(chan error, 1)
+	started := make(chan struct{})
+	go func() {
+		_, err := g.do(leader, "k", func() ([]byte, error) {
+			close(started)
+			select {
+			case <-leader.Done():
+				return nil, fmt.Errorf("request failed, %w", leader.Err())
+			case <-release:
+				return []byte("SYS4096"), nil
+			}
+		})
+		led <- err
+	}()
+	<-started
+
+	// a waiter gives up with its own context, while the call goes on.
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan error, 1)
+	go func() {
+		_, err := g.do(ctx, "k", func() ([]byte, error) { return nil, errors.New("not the leader") })
+		waited <- err
+	}()
+	<-waits
+	cancel()
+	assert.Equal(t, context.Canceled, <-waited)
+
+	// the leader cancelling makes the next waiter call fn itself.
+	retried := make(chan []byte, 1)
+	go func() {
+		b, err := g.do(context.Background(), "k", func() ([]byte, error) { return []byte("READY."), nil })
+		assert.NoError(t, err)
+		retried <- b
+	}()
+	<-waits
+	cancelLeader()
+	assert.True(t, errors.Is(<-led, context.Canceled))
+	assert.Equal(t, []byte("READY."), <-retried)
+	close(release)
+}
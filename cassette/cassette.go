@@ -0,0 +1,232 @@
+// Package cassette records the HTTP requests of a client to a file and replays them, so that integration tests
+// run against responses of the real service without network access or credentials:
+//
+//	mode := cassette.Replay
+//	if os.Getenv("AZURETTS_RECORD") != "" {
+//		mode = cassette.Record
+//	}
+//	rec, err := cassette.New("testdata/hello.json", mode, nil)
+//	...
+//	defer rec.Save()
+//	az, err := tts.New(key, tts.RegionWestUS2, "", rec.Option())
+//
+// Requests are matched by method, URL and body, with runs of whitespace in SSML bodies collapsed. Subscription keys
+// and tokens are redacted before they are written. The WebSocket protocol is not HTTP and is not recorded.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+// Mode selects whether a Recorder talks to the service.
+type Mode int
+
+const (
+	// Replay answers requests from the cassette only. A request that was not recorded fails.
+	Replay Mode = iota
+	// Record sends requests to the service and adds them to the cassette, which Save writes.
+	Record
+)
+
+// Redacted replaces credentials in recorded interactions.
+const Redacted = "REDACTED"
+
+// redactedHeaders hold credentials in requests.
+var redactedHeaders = []string{"Ocp-Apim-Subscription-Key", "Authorization"}
+
+// tokenPath is the path of the token endpoint, whose responses are tokens.
+const tokenPath = "/sts/v1.0/issueToken"
+
+// Request is a recorded request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded response. Body is binary audio for synthesis requests.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// Interaction is a request and the response the service gave to it.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Recorder is an http.RoundTripper recording to or replaying from a cassette file.
+type Recorder struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New returns a recorder for the cassette at path. In Replay mode the cassette is loaded and must exist; in Record
+// mode requests are sent through next, or http.DefaultTransport when nil, and Save replaces the cassette.
+func New(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{path: path, mode: mode, next: next}
+	if mode == Replay {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read cassette, %v", err)
+		}
+		if err := json.Unmarshal(b, &r.interactions); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s, %v", path, err)
+		}
+		r.used = make([]bool, len(r.interactions))
+	}
+	return r, nil
+}
+
+// Option returns the client option sending requests through the recorder, see tts.WithTransport.
+func (r *Recorder) Option() tts.Option {
+	return tts.WithTransport(r)
+}
+
+// Interactions returns the interactions of the cassette.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	if r.mode == Replay {
+		return r.replay(req, body)
+	}
+
+	out := req.Clone(req.Context())
+	out.Body = ioutil.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	resp, err := r.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	recorded := Interaction{
+		Request:  Request{Method: req.Method, URL: req.URL.String(), Header: redact(req.Header), Body: string(body)},
+		Response: Response{Status: resp.StatusCode, Header: resp.Header.Clone(), Body: b},
+	}
+	recorded.Response.Header.Del("Set-Cookie")
+	if req.URL.Path == tokenPath && resp.StatusCode == http.StatusOK {
+		recorded.Response.Body = []byte(Redacted)
+	}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, recorded)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// replay answers req with the first unused interaction matching it, or the last one matching it once all were
+// used, so that repeated requests such as token refreshes keep being answered.
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	key := matchKey(req.Method, req.URL.String(), string(body))
+	r.mu.Lock()
+	found := -1
+	for i, in := range r.interactions {
+		if matchKey(in.Request.Method, in.Request.URL, in.Request.Body) != key {
+			continue
+		}
+		found = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if found >= 0 {
+		r.used[found] = true
+	}
+	r.mu.Unlock()
+	if found < 0 {
+		return nil, fmt.Errorf("cassette %s has no interaction recorded for %s %s", r.path, req.Method, req.URL)
+	}
+
+	in := r.interactions[found].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        in.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(in.Body)),
+		ContentLength: int64(len(in.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Save writes the interactions recorded to the cassette file, creating its directory. It does nothing in Replay
+// mode.
+func (r *Recorder) Save() error {
+	if r.mode == Replay {
+		return nil
+	}
+	b, err := json.MarshalIndent(r.Interactions(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("unable to create cassette directory, %v", err)
+	}
+	if err := ioutil.WriteFile(r.path, b, 0644); err != nil {
+		return fmt.Errorf("unable to write cassette, %v", err)
+	}
+	return nil
+}
+
+// redact returns a copy of header with credentials replaced.
+func redact(header http.Header) http.Header {
+	h := header.Clone()
+	for _, k := range redactedHeaders {
+		if h.Get(k) != "" {
+			h.Set(k, Redacted)
+		}
+	}
+	return h
+}
+
+// matchKey identifies a request for matching: its method, its URL with the query parameters sorted, and its body
+// normalized with tts.NormalizeSSML, so that indentation of SSML does not matter.
+func matchKey(method, rawURL, body string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		u.RawQuery = u.Query().Encode()
+		rawURL = u.String()
+	}
+	return method + " " + rawURL + "\n" + tts.NormalizeSSML(body)
+}
@@ -0,0 +1,87 @@
+package cassette
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/azurettstest"
+	"github.com/stretchr/testify/assert"
+)
+
+const ssml = `<speak version="1.0" xml:lang="en-US"><voice name="en-US-JennyNeural">Hello world</voice></speak>`
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "testdata", "hello.json")
+
+	s := azurettstest.NewServer()
+	rec, err := New(path, Record, nil)
+	assert.NoError(t, err)
+	az, err := s.Client(rec.Option())
+	if !assert.NoError(t, err) {
+		return
+	}
+	recorded, err := az.SynthesizeSSML(ssml, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	close(az.TokenRefreshDoneCh)
+	s.Close()
+	assert.NoError(t, rec.Save())
+	assert.Len(t, rec.Interactions(), 3, "token, voice list and synthesis")
+
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), azurettstest.DefaultKey)
+	assert.Contains(t, string(b), Redacted)
+
+	// the server is gone: everything comes from the cassette.
+	rec, err = New(path, Replay, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	az, err = tts.New(azurettstest.DefaultKey, tts.RegionWestUS2, "", tts.WithEndpoint(s.URL), rec.Option())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer close(az.TokenRefreshDoneCh)
+
+	indented := strings.Replace(ssml, "><voice", ">\n  <voice", 1)
+	replayed, err := az.SynthesizeSSML(indented, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+	// repeated requests are answered again.
+	replayed, err = az.SynthesizeSSML(ssml, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+
+	_, err = az.SynthesizeSSML(strings.Replace(ssml, "world", "there", 1), tts.RIFF16khz16bitMonoPCM)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no interaction recorded")
+	}
+
+	_, err = New(filepath.Join(dir, "missing.json"), Replay, nil)
+	assert.Error(t, err)
+}
+
+func TestMatchKey(t *testing.T) {
+	assert.Equal(t,
+		matchKey("GET", "https://example.com/v1?b=2&a=1", ""),
+		matchKey("GET", "https://example.com/v1?a=1&b=2", ""))
+	assert.Equal(t,
+		matchKey("POST", "https://example.com/v1", "<speak>\n\t<voice>Hello   world</voice>\n</speak>"),
+		matchKey("POST", "https://example.com/v1", "<speak><voice>Hello world</voice></speak>"))
+	assert.NotEqual(t,
+		matchKey("POST", "https://example.com/v1", "<speak>Hello</speak>"),
+		matchKey("GET", "https://example.com/v1", "<speak>Hello</speak>"))
+	assert.NotEqual(t,
+		matchKey("POST", "https://example.com/v1", "<speak><voice><emphasis>a</emphasis> <emphasis>b</emphasis></voice></speak>"),
+		matchKey("POST", "https://example.com/v1", "<speak><voice><emphasis>a</emphasis><emphasis>b</emphasis></voice></speak>"),
+		"spoken spaces between elements")
+}
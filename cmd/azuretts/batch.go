@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/linexjlin/azuretexttospeech/batch"
+)
+
+func runBatch(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	out := fs.String("out", ".", "directory receiving the audio files")
+	result := fs.String("result", "", "result manifest path (default <out>/result.json)")
+	concurrency := fs.Int("concurrency", 4, "maximum simultaneous synthesis requests")
+	force := fs.Bool("force", false, "synthesize every prompt even when unchanged")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: azuretts batch [flags] manifest.yaml")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	cfg, err := flags.resolve()
+	if err != nil {
+		return err
+	}
+
+	m, err := batch.LoadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	// the configured voice and format act as defaults for manifests that do not name their own.
+	m.Voice = firstOf(m.Voice, cfg.Voice)
+	m.Format = firstOf(m.Format, cfg.Format)
+
+	if *result == "" {
+		*result = filepath.Join(*out, "result.json")
+	}
+	opts := batch.Options{OutputDir: *out, Concurrency: *concurrency, Force: *force}
+	if prev, err := batch.LoadResult(*result); err == nil {
+		opts.Previous = prev
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	az, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer close(az.TokenRefreshDoneCh)
+
+	res, err := batch.Run(context.Background(), az, m, opts)
+	if err != nil {
+		return err
+	}
+	if err := res.WriteFile(*result); err != nil {
+		return err
+	}
+
+	for _, p := range res.Prompts {
+		switch {
+		case p.Error != "":
+			fmt.Fprintf(os.Stderr, "%s: %s\n", p.ID, p.Error)
+		case p.Skipped:
+			fmt.Printf("%s: unchanged\n", p.ID)
+		default:
+			fmt.Printf("%s: %s (%d bytes, %dms)\n", p.ID, p.File, p.Size, p.ElapsedMS)
+		}
+	}
+	if n := res.Failed(); n > 0 {
+		return fmt.Errorf("%d of %d prompts failed", n, len(res.Prompts))
+	}
+	return nil
+}
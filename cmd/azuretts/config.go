@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults applied when a setting is given nowhere else.
+const (
+	defaultRegion = "westus2"
+	defaultVoice  = "en-US-AriaNeural"
+	defaultFormat = "AUDIO16khz32kbitrateMonoMP3"
+)
+
+// config holds the settings shared by all commands.
+type config struct {
+	Key      string `yaml:"key"`
+	Region   string `yaml:"region"`
+	Proxy    string `yaml:"proxy"`
+	Voice    string `yaml:"voice"`
+	Format   string `yaml:"format"`
+	CacheDir string `yaml:"cache_dir"`
+
+	path string // config file named on the command line.
+}
+
+// addConfigFlags registers the shared flags on fs. The returned config only holds values given on the command
+// line, call resolve after parsing to merge in the config file and environment.
+func addConfigFlags(fs *flag.FlagSet) *config {
+	c := &config{}
+	fs.StringVar(&c.path, "config", "", "config file (default $AZURETTS_CONFIG or <user config dir>/azuretts/config.yaml)")
+	fs.StringVar(&c.Region, "region", "", "Azure region of the subscription (default "+defaultRegion+")")
+	fs.StringVar(&c.Proxy, "proxy", "", "HTTP proxy URL")
+	fs.StringVar(&c.Voice, "voice", "", "voice short name (default "+defaultVoice+")")
+	fs.StringVar(&c.Format, "format", "", "audio format name or header value (default "+defaultFormat+")")
+	fs.StringVar(&c.CacheDir, "cache", "", "directory caching synthesized audio")
+	return c
+}
+
+// resolve merges the config file, the environment and the command line flags held in c, later sources taking
+// precedence.
+func (c *config) resolve() (*config, error) {
+	file, err := loadConfig(c.path)
+	if err != nil {
+		return nil, err
+	}
+	return &config{
+		Key:      firstOf(os.Getenv("AZUREKEY"), file.Key),
+		Region:   firstOf(c.Region, os.Getenv("AZUREREGION"), file.Region, defaultRegion),
+		Proxy:    firstOf(c.Proxy, file.Proxy),
+		Voice:    firstOf(c.Voice, file.Voice, defaultVoice),
+		Format:   firstOf(c.Format, file.Format, defaultFormat),
+		CacheDir: firstOf(c.CacheDir, file.CacheDir),
+	}, nil
+}
+
+// loadConfig reads the config file at path. When path is empty the default locations are tried and a missing
+// file is not an error.
+func loadConfig(path string) (*config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv("AZURETTS_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return &config{}, nil
+		}
+		path = filepath.Join(dir, "azuretts", "config.yaml")
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c config
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("unable to decode config file %s, %v", path, err)
+	}
+	return &c, nil
+}
+
+func firstOf(v ...string) string {
+	for _, s := range v {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
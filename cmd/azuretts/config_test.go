@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "azuretts-config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(p, []byte("key: FROMFILE\nregion: eastus\nvoice: en-GB-RyanNeural\n"), 0644))
+
+	os.Setenv("AZUREKEY", "SYS64738")
+	os.Setenv("AZUREREGION", "westeurope")
+	defer os.Unsetenv("AZUREKEY")
+	defer os.Unsetenv("AZUREREGION")
+
+	c, err := (&config{path: p}).resolve()
+	assert.NoError(t, err)
+	assert.Equal(t, "SYS64738", c.Key, "environment should override the config file")
+	assert.Equal(t, "westeurope", c.Region)
+	assert.Equal(t, "en-GB-RyanNeural", c.Voice)
+	assert.Equal(t, defaultFormat, c.Format)
+
+	c, err = (&config{path: p, Region: "uksouth", Voice: "en-US-GuyNeural"}).resolve()
+	assert.NoError(t, err)
+	assert.Equal(t, "uksouth", c.Region, "flags should override the environment")
+	assert.Equal(t, "en-US-GuyNeural", c.Voice)
+
+	_, err = (&config{path: filepath.Join(dir, "missing.yaml")}).resolve()
+	assert.Error(t, err, "an explicitly named config file must exist")
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "azuretts-keys")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "keys.yaml")
+	assert.NoError(t, ioutil.WriteFile(p, []byte("billing: k1\nsupport: k2\n"), 0644))
+	keys, err := loadKeys(p)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"k1": "billing", "k2": "support"}, keys)
+
+	assert.NoError(t, ioutil.WriteFile(p, []byte("billing: k1\nsupport: k1\n"), 0644))
+	_, err = loadKeys(p)
+	assert.Error(t, err, "shared key")
+
+	keys, err = loadKeys("")
+	assert.NoError(t, err)
+	assert.Nil(t, keys)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+func runFormats(args []string) error {
+	fs := flag.NewFlagSet("formats", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the formats as JSON")
+	fs.Parse(args)
+
+	formats := tts.Formats()
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(formats)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tHEADER\tCONTAINER\tCODEC\tRATE\tBITRATE\tEXTENSION\tMIME")
+	for _, f := range formats {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n", f.Name, f.Header, f.Container, f.Codec, f.SampleRate, f.Bitrate, f.Extension, f.MIMEType)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,88 @@
+// Command azuretts is a command line client for the Azure text-to-speech service.
+//
+// Settings are taken, in increasing order of precedence, from a YAML config file
+// ($AZURETTS_CONFIG or <user config dir>/azuretts/config.yaml), the AZUREKEY and AZUREREGION environment
+// variables, and command line flags.
+//
+//	azuretts speak -voice en-US-AriaNeural -o hello.mp3 "Hello world"
+//	azuretts speak -play "Hello world"
+//	azuretts voices -locale en-US
+//	azuretts formats
+//	azuretts batch -out prompts/ prompts.yaml
+//	azuretts serve -addr :8080 -keys keys.yaml
+package main
+
+import (
+	"fmt"
+	"os"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+const usage = `usage: azuretts <command> [flags] [args]
+
+commands:
+  speak     synthesize text or SSML from an argument, file or stdin
+  voices    list the voices available in a region
+  formats   list the supported audio output formats
+  batch     synthesize every prompt of a YAML or JSON manifest
+  serve     run an HTTP gateway exposing synthesis to other services
+
+Run "azuretts <command> -h" for the flags of a command.
+`
+
+func exit(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %+v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func([]string) error{
+		"speak":   runSpeak,
+		"voices":  runVoices,
+		"formats": runFormats,
+		"batch":   runBatch,
+		"serve":   runServe,
+	}
+	switch cmd, ok := commands[os.Args[1]]; {
+	case ok:
+		exit(cmd(os.Args[2:]))
+	case os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "-help" || os.Args[1] == "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+// newClient creates a client from the resolved configuration.
+func newClient(c *config) (*tts.AzureCSTextToSpeech, error) {
+	if c.Key == "" {
+		return nil, fmt.Errorf("Please set your AZUREKEY environment variable")
+	}
+	r, err := tts.RegionString(c.Region)
+	if err != nil {
+		return nil, err
+	}
+	az, err := tts.New(c.Key, r, c.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new client, received %v", err)
+	}
+	if c.CacheDir != "" {
+		fc, err := tts.NewFileCache(c.CacheDir)
+		if err != nil {
+			close(az.TokenRefreshDoneCh)
+			return nil, err
+		}
+		az.Cache = fc
+	}
+	return az, nil
+}
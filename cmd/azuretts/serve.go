@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/server"
+	"gopkg.in/yaml.v3"
+)
+
+// shutdownTimeout bounds the wait for requests in flight when the server is stopped.
+const shutdownTimeout = 30 * time.Second
+
+// Timeouts of the connections of callers. Responses have no write timeout, since audio streams for as long as it
+// renders.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	keys := fs.String("keys", "", "YAML file mapping caller names to their API keys; requests are not authenticated without it")
+	maxBody := fs.Int64("max-body", server.DefaultMaxBodyBytes, "maximum size of a synthesis request in bytes")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: azuretts serve [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	cfg, err := flags.resolve()
+	if err != nil {
+		return err
+	}
+
+	callers, err := loadKeys(*keys)
+	if err != nil {
+		return err
+	}
+	az, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer close(az.TokenRefreshDoneCh)
+	// the server caches complete responses itself, streaming the others through.
+	cache := az.Cache
+	az.Cache = nil
+
+	s, err := server.New(server.Config{
+		Synthesizer:   tts.Wrap(az, tts.RetryMiddleware(3, 200*time.Millisecond)),
+		Keys:          callers,
+		MaxBodyBytes:  *maxBody,
+		DefaultVoice:  cfg.Voice,
+		DefaultFormat: cfg.Format,
+		Cache:         cache,
+	})
+	if err != nil {
+		return err
+	}
+	hs := &http.Server{
+		Addr:              *addr,
+		Handler:           s,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		stopped <- hs.Shutdown(ctx)
+	}()
+
+	log.Printf("listening on %s", *addr)
+	if err := hs.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return <-stopped
+}
+
+// loadKeys reads the API keys of the callers of the server from a YAML file mapping caller names to keys, and
+// returns them keyed by API key as server.Config.Keys expects.
+func loadKeys(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var byCaller map[string]string
+	if err := yaml.Unmarshal(b, &byCaller); err != nil {
+		return nil, fmt.Errorf("unable to decode keys file %s, %v", path, err)
+	}
+	keys := make(map[string]string, len(byCaller))
+	for name, key := range byCaller {
+		if key == "" {
+			return nil, fmt.Errorf("no API key for caller %s in %s", name, path)
+		}
+		if other, ok := keys[key]; ok {
+			return nil, fmt.Errorf("callers %s and %s share an API key in %s", other, name, path)
+		}
+		keys[key] = name
+	}
+	return keys, nil
+}
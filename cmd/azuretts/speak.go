@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/sink"
+)
+
+func runSpeak(args []string) error {
+	fs := flag.NewFlagSet("speak", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	in := fs.String("i", "", `read text or SSML from file ("-" for stdin)`)
+	out := fs.String("o", "", `write audio to file ("-" for stdout, the default unless -play is set)`)
+	play := fs.Bool("play", false, "stream the audio to a local player (aplay or ffplay)")
+	ssml := fs.Bool("ssml", false, "treat the input as SSML (implied when it starts with <speak)")
+	pitch := fs.String("pitch", "0%", "prosody pitch")
+	rate := fs.String("rate", "0%", "prosody rate")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: azuretts speak [flags] [text]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	cfg, err := flags.resolve()
+	if err != nil {
+		return err
+	}
+
+	text, err := speakInput(fs.Args(), *in)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("nothing to synthesize")
+	}
+	format, err := tts.AudioOutputString(cfg.Format)
+	if err != nil {
+		return err
+	}
+	if !*ssml && !strings.HasPrefix(strings.TrimSpace(text), "<speak") {
+		text = tts.VoiceSSML(text, cfg.Voice, *pitch, *rate)
+	}
+
+	az, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer close(az.TokenRefreshDoneCh)
+
+	ctx := context.Background()
+	sinks, err := speakSinks(ctx, *out, *play, format)
+	if err != nil {
+		return err
+	}
+
+	body, err := az.SynthesizeStreamWithContext(ctx, text, format)
+	if err == nil {
+		writers := make([]io.Writer, len(sinks))
+		for i, s := range sinks {
+			writers[i] = s
+		}
+		_, err = io.Copy(io.MultiWriter(writers...), body)
+		body.Close()
+	}
+	for _, s := range sinks {
+		if cerr := s.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		if *out != "" && *out != "-" {
+			os.Remove(*out)
+		}
+		return fmt.Errorf("unable to synthesize, received: %v", err)
+	}
+	return nil
+}
+
+// speakSinks opens the destinations selected by the -o and -play flags.
+func speakSinks(ctx context.Context, out string, play bool, format tts.AudioOutput) ([]sink.AudioSink, error) {
+	var sinks []sink.AudioSink
+	switch {
+	case out == "-" || (out == "" && !play):
+		sinks = append(sinks, sink.Stdout())
+	case out != "":
+		f, err := sink.File(out)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, f)
+	}
+	if play {
+		p, err := sink.Player(ctx, format)
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, p)
+	}
+	return sinks, nil
+}
+
+// speakInput returns the text to synthesize from the positional arguments, the named file or stdin.
+func speakInput(args []string, in string) (string, error) {
+	switch {
+	case len(args) > 0 && in != "":
+		return "", fmt.Errorf("text arguments and -i are mutually exclusive")
+	case len(args) > 0:
+		return strings.Join(args, " "), nil
+	case in != "" && in != "-":
+		b, err := ioutil.ReadFile(in)
+		return string(b), err
+	}
+	b, err := ioutil.ReadAll(os.Stdin)
+	return string(b), err
+}
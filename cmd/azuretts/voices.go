@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+func runVoices(args []string) error {
+	fs := flag.NewFlagSet("voices", flag.ExitOnError)
+	flags := addConfigFlags(fs)
+	locale := fs.String("locale", "", "only list voices of this locale, e.g. en-US")
+	gender := fs.String("gender", "", "only list voices of this gender (Male, Female)")
+	voiceType := fs.String("type", "", "only list voices of this type (Standard, Neural)")
+	name := fs.String("name", "", "only list voices whose short name contains this string")
+	asJSON := fs.Bool("json", false, "print the catalog as JSON")
+	fs.Parse(args)
+	cfg, err := flags.resolve()
+	if err != nil {
+		return err
+	}
+
+	az, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer close(az.TokenRefreshDoneCh)
+
+	voices, err := az.VoicesWithContext(context.Background())
+	if err != nil {
+		return err
+	}
+	var matched []tts.Voice
+	for _, v := range voices {
+		if (*locale == "" || strings.EqualFold(v.Locale, *locale)) &&
+			(*gender == "" || strings.EqualFold(v.Gender, *gender)) &&
+			(*voiceType == "" || strings.EqualFold(v.VoiceType, *voiceType)) &&
+			(*name == "" || strings.Contains(strings.ToLower(v.ShortName), strings.ToLower(*name))) {
+			matched = append(matched, v)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(matched)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SHORTNAME\tLOCALE\tGENDER\tTYPE\tSTYLES")
+	for _, v := range matched {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", v.ShortName, v.Locale, v.Gender, v.VoiceType, strings.Join(v.StyleList, ","))
+	}
+	return tw.Flush()
+}
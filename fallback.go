@@ -0,0 +1,218 @@
+package azuretexttospeech
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// Backend is a synthesizer tried by FallbackSynthesizer.
+type Backend struct {
+	Name        string // reported in Served
+	Synthesizer Synthesizer
+	// Voices maps the voice names of requests to voices of the backend. Voices missing from the table are sent
+	// unchanged.
+	Voices map[string]string
+	// Formats lists the output formats the backend produces, nil for all of them. Other formats are requested in
+	// the first of Formats that FallbackSynthesizer.CanConvert accepts and converted with FallbackSynthesizer.Convert.
+	Formats []AudioOutput
+}
+
+// Served describes how a request was answered by a FallbackSynthesizer.
+type Served struct {
+	Backend   string      // name of the backend answering, empty when all of them failed
+	Format    AudioOutput // format requested from the backend
+	Converted bool        // whether the audio was converted from Format
+	Errors    []error     // errors of the backends tried before, in order
+}
+
+// FallbackSynthesizer tries its backends in order until one of them answers, so that requests are still served,
+// possibly by a lower quality engine, while the first is failing. A backend is skipped when the SSML cannot be
+// rendered in the requested format. Attempts stop when the context of the call is done.
+type FallbackSynthesizer struct {
+	Backends []Backend
+	// Convert renders audio of one format in another for backends that cannot produce the requested format,
+	// typically audio.Convert. Without it those backends are skipped.
+	Convert func(audio []byte, from, to AudioOutput) ([]byte, error)
+	// CanConvert reports whether Convert turns audio of one format into another, typically audio.CanConvert, so
+	// that backends are asked for a format that converts. Without it the first of their Formats is requested.
+	CanConvert func(from, to AudioOutput) bool
+	// OnServed, when not nil, is called with the outcome of every synthesis request.
+	OnServed func(Served)
+}
+
+var _ EventSynthesizer = (*FallbackSynthesizer)(nil)
+
+// SynthesizeSSMLWithBackend renders ssml with the first backend answering and reports which one it was.
+func (f *FallbackSynthesizer) SynthesizeSSMLWithBackend(ctx context.Context, ssml string, audioOutput AudioOutput) ([]byte, Served, error) {
+	var served Served
+	for _, b := range f.Backends {
+		format, convert, err := f.format(b, audioOutput)
+		if err == nil {
+			var audio []byte
+			audio, err = b.Synthesizer.SynthesizeSSMLWithContext(ctx, mapVoices(ssml, b.Voices), format)
+			if err == nil && convert {
+				audio, err = f.Convert(audio, format, audioOutput)
+			}
+			if err == nil {
+				served.Backend, served.Format, served.Converted = b.Name, format, convert
+				f.report(served)
+				return audio, served, nil
+			}
+		}
+		served.Errors = append(served.Errors, fmt.Errorf("%s: %w", b.Name, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	f.report(served)
+	return nil, served, fallbackError(served.Errors)
+}
+
+// SynthesizeSSMLWithContext implements Synthesizer, see SynthesizeSSMLWithBackend.
+func (f *FallbackSynthesizer) SynthesizeSSMLWithContext(ctx context.Context, ssml string, audioOutput AudioOutput) ([]byte, error) {
+	audio, _, err := f.SynthesizeSSMLWithBackend(ctx, ssml, audioOutput)
+	return audio, err
+}
+
+// SynthesizeStreamWithContext implements Synthesizer. Backends producing the requested format stream it; audio
+// needing conversion is converted once complete. A backend failing after its stream was opened is not replaced.
+func (f *FallbackSynthesizer) SynthesizeStreamWithContext(ctx context.Context, ssml string, audioOutput AudioOutput) (io.ReadCloser, error) {
+	var served Served
+	for _, b := range f.Backends {
+		format, convert, err := f.format(b, audioOutput)
+		if err == nil {
+			if !convert {
+				var r io.ReadCloser
+				r, err = b.Synthesizer.SynthesizeStreamWithContext(ctx, mapVoices(ssml, b.Voices), format)
+				if err == nil {
+					served.Backend, served.Format = b.Name, format
+					f.report(served)
+					return r, nil
+				}
+			} else {
+				var audio []byte
+				audio, err = b.Synthesizer.SynthesizeSSMLWithContext(ctx, mapVoices(ssml, b.Voices), format)
+				if err == nil {
+					audio, err = f.Convert(audio, format, audioOutput)
+				}
+				if err == nil {
+					served.Backend, served.Format, served.Converted = b.Name, format, true
+					f.report(served)
+					return ioutil.NopCloser(bytes.NewReader(audio)), nil
+				}
+			}
+		}
+		served.Errors = append(served.Errors, fmt.Errorf("%s: %w", b.Name, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	f.report(served)
+	return nil, fallbackError(served.Errors)
+}
+
+// SynthesizeEventsWithContext implements EventSynthesizer. Only backends reporting events and producing the
+// requested format are tried; the others fail with ErrNoEvents. A backend failing after it wrote audio or reported
+// an event is not replaced.
+func (f *FallbackSynthesizer) SynthesizeEventsWithContext(ctx context.Context, ssml string, audioOutput AudioOutput, w io.Writer, onEvent func(Event)) error {
+	var served Served
+	out := &eventOutput{w: w, onEvent: onEvent}
+	for _, b := range f.Backends {
+		format, convert, err := f.format(b, audioOutput)
+		if err == nil && convert {
+			err = ErrNoEvents
+		}
+		if err == nil {
+			err = synthesizeEvents(ctx, b.Synthesizer, mapVoices(ssml, b.Voices), format, out, out.event)
+			if err == nil {
+				served.Backend, served.Format = b.Name, format
+				f.report(served)
+				return nil
+			}
+		}
+		served.Errors = append(served.Errors, fmt.Errorf("%s: %w", b.Name, err))
+		if ctx.Err() != nil || out.delivered() {
+			break
+		}
+	}
+	f.report(served)
+	return fallbackError(served.Errors)
+}
+
+// VoicesWithContext implements Synthesizer, listing the voices of the first backend answering.
+func (f *FallbackSynthesizer) VoicesWithContext(ctx context.Context) ([]Voice, error) {
+	var errs []error
+	for _, b := range f.Backends {
+		voices, err := b.Synthesizer.VoicesWithContext(ctx)
+		if err == nil {
+			return voices, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fallbackError(errs)
+}
+
+// format returns the format to request from b for audio in audioOutput and whether it must be converted.
+func (f *FallbackSynthesizer) format(b Backend, audioOutput AudioOutput) (AudioOutput, bool, error) {
+	if len(b.Formats) == 0 {
+		return audioOutput, false, nil
+	}
+	for _, format := range b.Formats {
+		if format == audioOutput {
+			return audioOutput, false, nil
+		}
+	}
+	if f.Convert != nil {
+		for _, format := range b.Formats {
+			if f.CanConvert == nil || f.CanConvert(format, audioOutput) {
+				return format, true, nil
+			}
+		}
+	}
+	return 0, false, fmt.Errorf("%s audio is not supported", audioOutput)
+}
+
+func (f *FallbackSynthesizer) report(s Served) {
+	if f.OnServed != nil {
+		f.OnServed(s)
+	}
+}
+
+// fallbackError reports the failure of every backend tried. The last error is wrapped, so that Temporary and
+// errors.As see it.
+func fallbackError(errs []error) error {
+	if len(errs) == 0 {
+		return fmt.Errorf("no synthesizer backend configured")
+	}
+	var earlier strings.Builder
+	for _, err := range errs[:len(errs)-1] {
+		earlier.WriteString(err.Error() + "; ")
+	}
+	return fmt.Errorf("all synthesizer backends failed; %s%w", earlier.String(), errs[len(errs)-1])
+}
+
+var voiceName = regexp.MustCompile(`(<voice\b[^>]*?\bname\s*=\s*)("[^"]*"|'[^']*')`)
+
+// mapVoices rewrites the name attributes of the voice elements of ssml through voices.
+func mapVoices(ssml string, voices map[string]string) string {
+	if len(voices) == 0 {
+		return ssml
+	}
+	return voiceName.ReplaceAllStringFunc(ssml, func(m string) string {
+		parts := voiceName.FindStringSubmatch(m)
+		quoted := parts[2]
+		name := quoted[1 : len(quoted)-1]
+		if to, ok := voices[name]; ok {
+			return parts[1] + quoted[:1] + to + quoted[:1]
+		}
+		return m
+	})
+}
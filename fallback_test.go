@@ -0,0 +1,155 @@
+package azuretexttospeech
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallbackSynthesizer(t *testing.T) {
+	azure := &stubSynthesizer{errs: []error{synthesizeError(http.StatusServiceUnavailable), synthesizeError(http.StatusServiceUnavailable)}}
+	local := &stubSynthesizer{}
+	var served []Served
+	f := &FallbackSynthesizer{
+		Backends: []Backend{
+			{Name: "azure", Synthesizer: azure},
+			{Name: "local", Synthesizer: local, Voices: map[string]string{"en-US-JennyNeural": "en-us+f3"}, Formats: []AudioOutput{RIFF16khz16bitMonoPCM}},
+		},
+		Convert: func(audio []byte, from, to AudioOutput) ([]byte, error) {
+			return []byte(from.String() + ">" + to.String() + ":" + string(audio)), nil
+		},
+		OnServed: func(s Served) { served = append(served, s) },
+	}
+	ctx := context.Background()
+	ssml := `<speak version="1.0" xml:lang="en-US"><voice xml:lang='en-US' name='en-US-JennyNeural'>Hi</voice><voice name="en-US-GuyNeural">Bye</voice></speak>`
+
+	b, s, err := f.SynthesizeSSMLWithBackend(ctx, ssml, RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, "local", s.Backend)
+	assert.False(t, s.Converted)
+	assert.Len(t, s.Errors, 1)
+	assert.Contains(t, string(b), `name='en-us+f3'`, "voices are mapped")
+	assert.Contains(t, string(b), `name="en-US-GuyNeural"`, "voices missing from the table are kept")
+
+	// the local engine only renders 16 kHz PCM, converted to the format asked for.
+	r, err := f.SynthesizeStreamWithContext(ctx, ssml, RAW8khz8bitMonoMulaw)
+	if assert.NoError(t, err) {
+		b, _ := ioutil.ReadAll(r)
+		assert.True(t, strings.HasPrefix(string(b), "riff-16khz-16bit-mono-pcm>raw-8khz-8bit-mono-mulaw:"), string(b))
+	}
+	assert.Equal(t, RIFF16khz16bitMonoPCM, local.formats[len(local.formats)-1])
+
+	// azure is back.
+	_, err = f.SynthesizeSSMLWithContext(ctx, ssml, RAW8khz8bitMonoMulaw)
+	assert.NoError(t, err)
+	if assert.Len(t, served, 3) {
+		assert.Equal(t, "local", served[1].Backend)
+		assert.True(t, served[1].Converted)
+		assert.Equal(t, Served{Backend: "azure", Format: RAW8khz8bitMonoMulaw}, served[2])
+	}
+
+	// without a converter the local engine cannot help.
+	azure.errs = []error{synthesizeError(http.StatusBadGateway)}
+	f.Convert = nil
+	_, s, err = f.SynthesizeSSMLWithBackend(ctx, ssml, RAW8khz8bitMonoMulaw)
+	if assert.Error(t, err) {
+		assert.Equal(t, "", s.Backend)
+		assert.Len(t, s.Errors, 2)
+		assert.Contains(t, err.Error(), "azure: 502")
+		assert.Contains(t, err.Error(), "local: raw-8khz-8bit-mono-mulaw audio is not supported")
+	}
+
+	// a cancelled request is not passed on.
+	azure.errs = []error{context.Canceled}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = f.SynthesizeSSMLWithContext(cancelled, ssml, RIFF16khz16bitMonoPCM)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	voices, err := f.VoicesWithContext(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, voices, 1)
+}
+
+func TestFallbackEvents(t *testing.T) {
+	azure := &eventStub{stubSynthesizer: stubSynthesizer{errs: []error{synthesizeError(http.StatusServiceUnavailable)}}}
+	var served []Served
+	f := &FallbackSynthesizer{
+		Backends: []Backend{
+			{Name: "local", Synthesizer: &stubSynthesizer{}},
+			{Name: "azure", Synthesizer: azure},
+			{Name: "backup", Synthesizer: &eventStub{}, Voices: map[string]string{"en-US-JennyNeural": "en-US-AriaNeural"}},
+		},
+		OnServed: func(s Served) { served = append(served, s) },
+	}
+	ctx := context.Background()
+	ssml := `<speak><voice name="en-US-JennyNeural">Hi</voice></speak>`
+
+	var audio bytes.Buffer
+	var events int
+	err := f.SynthesizeEventsWithContext(ctx, ssml, RAW16khz16bitMonoPCM, &audio, func(Event) { events++ })
+	assert.NoError(t, err)
+	assert.Contains(t, audio.String(), "en-US-AriaNeural")
+	assert.Equal(t, 1, events)
+	if assert.Len(t, served, 1) {
+		assert.Equal(t, "backup", served[0].Backend)
+		if assert.Len(t, served[0].Errors, 2) {
+			assert.True(t, errors.Is(served[0].Errors[0], ErrNoEvents))
+		}
+	}
+
+	// a backend failing after it wrote audio is not replaced.
+	azure.partial = true
+	azure.errs = []error{synthesizeError(http.StatusBadGateway)}
+	audio.Reset()
+	err = f.SynthesizeEventsWithContext(ctx, ssml, RAW16khz16bitMonoPCM, &audio, func(Event) { events++ })
+	assert.Error(t, err)
+	assert.Equal(t, ssml, audio.String())
+	assert.Len(t, served[1].Errors, 2)
+}
+
+func TestFallbackConvertibleFormat(t *testing.T) {
+	local := &stubSynthesizer{}
+	f := &FallbackSynthesizer{
+		Backends: []Backend{{Name: "local", Synthesizer: local, Formats: []AudioOutput{AUDIO16khz32kbitrateMonoMP3, RAW16khz16bitMonoPCM}}},
+		Convert: func(audio []byte, from, to AudioOutput) ([]byte, error) {
+			if from.Info().Codec != CodecPCM {
+				return nil, errors.New("cannot decode " + from.String())
+			}
+			return audio, nil
+		},
+		CanConvert: func(from, to AudioOutput) bool { return from.Info().Codec == CodecPCM && to.Info().Codec == CodecMulaw },
+	}
+	ctx := context.Background()
+
+	_, s, err := f.SynthesizeSSMLWithBackend(ctx, "<speak/>", RAW8khz8bitMonoMulaw)
+	assert.NoError(t, err)
+	assert.Equal(t, Served{Backend: "local", Format: RAW16khz16bitMonoPCM, Converted: true}, s)
+	assert.Equal(t, []AudioOutput{RAW16khz16bitMonoPCM}, local.formats, "one request, in a format that converts")
+
+	local.formats = nil
+	r, err := f.SynthesizeStreamWithContext(ctx, "<speak/>", RAW8khz8bitMonoMulaw)
+	if assert.NoError(t, err) {
+		r.Close()
+	}
+	assert.Equal(t, []AudioOutput{RAW16khz16bitMonoPCM}, local.formats)
+
+	// without a format that converts, the backend is not asked at all.
+	local.formats = nil
+	_, err = f.SynthesizeSSMLWithContext(ctx, "<speak/>", OGG24khz16bitMonoOpus)
+	assert.Error(t, err)
+	assert.Empty(t, local.formats)
+
+	// formats produced directly are not converted.
+	local.formats = nil
+	_, s, err = f.SynthesizeSSMLWithBackend(ctx, "<speak/>", AUDIO16khz32kbitrateMonoMP3)
+	assert.NoError(t, err)
+	assert.False(t, s.Converted)
+	assert.Equal(t, []AudioOutput{AUDIO16khz32kbitrateMonoMP3}, local.formats)
+}
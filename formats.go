@@ -0,0 +1,170 @@
+package azuretexttospeech
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Containers used by FormatInfo.Container.
+const (
+	ContainerRaw  = "raw"  // headerless sample data
+	ContainerRIFF = "riff" // RIFF/WAVE
+	ContainerMP3  = "mp3"  // MPEG audio elementary stream
+	ContainerOgg  = "ogg"
+	ContainerWebM = "webm"
+	ContainerAMR  = "amr" // AMR storage format (RFC 4867 section 5)
+)
+
+// Codecs used by FormatInfo.Codec.
+const (
+	CodecPCM      = "pcm" // signed little-endian linear PCM
+	CodecMulaw    = "mulaw"
+	CodecAlaw     = "alaw"
+	CodecMP3      = "mp3"
+	CodecOpus     = "opus"
+	CodecTruesilk = "truesilk"
+	CodecAMRWB    = "amr-wb"
+	CodecG722     = "g722"
+	CodecSiren    = "siren"
+)
+
+// FormatInfo describes the encoding of an AudioOutput.
+type FormatInfo struct {
+	Name       string // Go constant name, e.g. "RIFF16khz16bitMonoPCM"
+	Header     string // X-Microsoft-OutputFormat header value, e.g. "riff-16khz-16bit-mono-pcm"
+	Container  string // one of the Container constants
+	Codec      string // one of the Codec constants
+	SampleRate int    // samples per second
+	BitDepth   int    // bits per sample for sample based codecs, zero for compressed codecs
+	Bitrate    int    // bits per second, zero when variable or not published
+	Channels   int
+	Extension  string // file extension without the leading dot
+	MIMEType   string
+	Streamable bool // the audio can be consumed progressively, without a header describing its total length
+}
+
+// rawFormat describes a headerless format. For sample based codecs the bitrate is derived from rate and bits.
+func rawFormat(name, header, codec string, rate, bits int) FormatInfo {
+	mime := map[string]string{
+		CodecPCM:      "audio/pcm",
+		CodecMulaw:    "audio/basic",
+		CodecAlaw:     "audio/x-alaw-basic",
+		CodecTruesilk: "application/octet-stream",
+		CodecG722:     "audio/G722",
+		CodecSiren:    "application/octet-stream",
+	}[codec]
+	return FormatInfo{name, header, ContainerRaw, codec, rate, bits, rate * bits, 1, "raw", mime, true}
+}
+
+// riffFormat describes a RIFF/WAVE wrapped sample based format.
+func riffFormat(name, header, codec string, rate, bits int) FormatInfo {
+	return FormatInfo{name, header, ContainerRIFF, codec, rate, bits, rate * bits, 1, "wav", "audio/wav", false}
+}
+
+// mp3Format describes an MPEG audio format with a constant bitrate of kbps.
+func mp3Format(name, header string, rate, kbps int) FormatInfo {
+	return FormatInfo{name, header, ContainerMP3, CodecMP3, rate, 0, kbps * 1000, 1, "mp3", "audio/mpeg", true}
+}
+
+// opusFormat describes an Opus stream carried in an Ogg or WebM container, kbps is zero when the service does not
+// publish the bitrate.
+func opusFormat(name, header, container string, rate, kbps int) FormatInfo {
+	return FormatInfo{name, header, container, CodecOpus, rate, 0, kbps * 1000, 1, container, "audio/" + container, true}
+}
+
+// withBitrate overrides the derived bitrate of f, used by compressed codecs reported through rawFormat or
+// riffFormat.
+func withBitrate(f FormatInfo, kbps int) FormatInfo {
+	f.BitDepth = 0
+	f.Bitrate = kbps * 1000
+	return f
+}
+
+// formatInfos is indexed by AudioOutput, TestFormatCatalog ensures every constant has an entry.
+var formatInfos = [numAudioOutputs]FormatInfo{
+	RAW16khz16bitMonoPCM:          rawFormat("RAW16khz16bitMonoPCM", "raw-16khz-16bit-mono-pcm", CodecPCM, 16000, 16),
+	RAW24khz16bitMonoPCM:          rawFormat("RAW24khz16bitMonoPCM", "raw-24khz-16bit-mono-pcm", CodecPCM, 24000, 16),
+	RAW48khz16bitMonoPCM:          rawFormat("RAW48khz16bitMonoPCM", "raw-48khz-16bit-mono-pcm", CodecPCM, 48000, 16),
+	RAW8khz8bitMonoMulaw:          rawFormat("RAW8khz8bitMonoMulaw", "raw-8khz-8bit-mono-mulaw", CodecMulaw, 8000, 8),
+	RAW8khz8bitMonoAlaw:           rawFormat("RAW8khz8bitMonoAlaw", "raw-8khz-8bit-mono-alaw", CodecAlaw, 8000, 8),
+	AUDIO16khz32kbitrateMonoMP3:   mp3Format("AUDIO16khz32kbitrateMonoMP3", "audio-16khz-32kbitrate-mono-mp3", 16000, 32),
+	AUDIO16khz128kbitrateMonoMP3:  mp3Format("AUDIO16khz128kbitrateMonoMP3", "audio-16khz-128kbitrate-mono-mp3", 16000, 128),
+	AUDIO24khz96kbitrateMonoMP3:   mp3Format("AUDIO24khz96kbitrateMonoMP3", "audio-24khz-96kbitrate-mono-mp3", 24000, 96),
+	AUDIO48khz96kbitrateMonoMP3:   mp3Format("AUDIO48khz96kbitrateMonoMP3", "audio-48khz-96kbitrate-mono-mp3", 48000, 96),
+	RAW16khz16bitMonoTruesilk:     rawFormat("RAW16khz16bitMonoTruesilk", "raw-16khz-16bit-mono-truesilk", CodecTruesilk, 16000, 16),
+	WEBM16khz16bitMonoOpus:        opusFormat("WEBM16khz16bitMonoOpus", "webm-16khz-16bit-mono-opus", ContainerWebM, 16000, 0),
+	OGG16khz16bitMonoOpus:         opusFormat("OGG16khz16bitMonoOpus", "ogg-16khz-16bit-mono-opus", ContainerOgg, 16000, 0),
+	OGG48khz16bitMonoOpus:         opusFormat("OGG48khz16bitMonoOpus", "ogg-48khz-16bit-mono-opus", ContainerOgg, 48000, 0),
+	RIFF16khz16bitMonoPCM:         riffFormat("RIFF16khz16bitMonoPCM", "riff-16khz-16bit-mono-pcm", CodecPCM, 16000, 16),
+	RIFF24khz16bitMonoPCM:         riffFormat("RIFF24khz16bitMonoPCM", "riff-24khz-16bit-mono-pcm", CodecPCM, 24000, 16),
+	RIFF48khz16bitMonoPCM:         riffFormat("RIFF48khz16bitMonoPCM", "riff-48khz-16bit-mono-pcm", CodecPCM, 48000, 16),
+	RIFF8khz8bitMonoMulaw:         riffFormat("RIFF8khz8bitMonoMulaw", "riff-8khz-8bit-mono-mulaw", CodecMulaw, 8000, 8),
+	RIFF8khz8bitMonoAlaw:          riffFormat("RIFF8khz8bitMonoAlaw", "riff-8khz-8bit-mono-alaw", CodecAlaw, 8000, 8),
+	AUDIO16khz64kbitrateMonoMP3:   mp3Format("AUDIO16khz64kbitrateMonoMP3", "audio-16khz-64kbitrate-mono-mp3", 16000, 64),
+	AUDIO24khz48kbitrateMonoMP3:   mp3Format("AUDIO24khz48kbitrateMonoMP3", "audio-24khz-48kbitrate-mono-mp3", 24000, 48),
+	AUDIO24khz160kbitrateMonoMP3:  mp3Format("AUDIO24khz160kbitrateMonoMP3", "audio-24khz-160kbitrate-mono-mp3", 24000, 160),
+	AUDIO48khz192kbitrateMonoMP3:  mp3Format("AUDIO48khz192kbitrateMonoMP3", "audio-48khz-192kbitrate-mono-mp3", 48000, 192),
+	RAW24khz16bitMonoTruesilk:     rawFormat("RAW24khz16bitMonoTruesilk", "raw-24khz-16bit-mono-truesilk", CodecTruesilk, 24000, 16),
+	WEBM24khz16bitMonoOpus:        opusFormat("WEBM24khz16bitMonoOpus", "webm-24khz-16bit-mono-opus", ContainerWebM, 24000, 0),
+	OGG24khz16bitMonoOpus:         opusFormat("OGG24khz16bitMonoOpus", "ogg-24khz-16bit-mono-opus", ContainerOgg, 24000, 0),
+	RAW8khz16bitMonoPCM:           rawFormat("RAW8khz16bitMonoPCM", "raw-8khz-16bit-mono-pcm", CodecPCM, 8000, 16),
+	RAW22050hz16bitMonoPCM:        rawFormat("RAW22050hz16bitMonoPCM", "raw-22050hz-16bit-mono-pcm", CodecPCM, 22050, 16),
+	RAW44100hz16bitMonoPCM:        rawFormat("RAW44100hz16bitMonoPCM", "raw-44100hz-16bit-mono-pcm", CodecPCM, 44100, 16),
+	RIFF8khz16bitMonoPCM:          riffFormat("RIFF8khz16bitMonoPCM", "riff-8khz-16bit-mono-pcm", CodecPCM, 8000, 16),
+	RIFF22050hz16bitMonoPCM:       riffFormat("RIFF22050hz16bitMonoPCM", "riff-22050hz-16bit-mono-pcm", CodecPCM, 22050, 16),
+	RIFF44100hz16bitMonoPCM:       riffFormat("RIFF44100hz16bitMonoPCM", "riff-44100hz-16bit-mono-pcm", CodecPCM, 44100, 16),
+	AUDIO16khz16bit32kbpsMonoOpus: opusFormat("AUDIO16khz16bit32kbpsMonoOpus", "audio-16khz-16bit-32kbps-mono-opus", ContainerOgg, 16000, 32),
+	AUDIO24khz16bit24kbpsMonoOpus: opusFormat("AUDIO24khz16bit24kbpsMonoOpus", "audio-24khz-16bit-24kbps-mono-opus", ContainerOgg, 24000, 24),
+	AUDIO24khz16bit48kbpsMonoOpus: opusFormat("AUDIO24khz16bit48kbpsMonoOpus", "audio-24khz-16bit-48kbps-mono-opus", ContainerOgg, 24000, 48),
+	WEBM24khz16bit24kbpsMonoOpus:  opusFormat("WEBM24khz16bit24kbpsMonoOpus", "webm-24khz-16bit-24kbps-mono-opus", ContainerWebM, 24000, 24),
+	AMRWB16000hz:                  {"AMRWB16000hz", "amr-wb-16000hz", ContainerAMR, CodecAMRWB, 16000, 0, 0, 1, "amr", "audio/AMR-WB", true},
+	G722_16khz64kbps:              withBitrate(rawFormat("G722_16khz64kbps", "g722-16khz-64kbps", CodecG722, 16000, 0), 64),
+	RIFF16khz16kbpsMonoSiren:      withBitrate(riffFormat("RIFF16khz16kbpsMonoSiren", "riff-16khz-16kbps-mono-siren", CodecSiren, 16000, 0), 16),
+	AUDIO16khz16kbpsMonoSiren:     withBitrate(rawFormat("AUDIO16khz16kbpsMonoSiren", "audio-16khz-16kbps-mono-siren", CodecSiren, 16000, 0), 16),
+}
+
+// formatsByHeader maps each X-Microsoft-OutputFormat header value to its AudioOutput.
+var formatsByHeader = func() map[string]AudioOutput {
+	m := make(map[string]AudioOutput, len(formatInfos))
+	for i, f := range formatInfos {
+		m[f.Header] = AudioOutput(i)
+	}
+	return m
+}()
+
+// Valid reports whether a is a known AudioOutput.
+func (a AudioOutput) Valid() bool {
+	return a >= 0 && int(a) < len(formatInfos)
+}
+
+// Info returns the encoding details of a, or the zero FormatInfo when a is not a known AudioOutput.
+func (a AudioOutput) Info() FormatInfo {
+	if !a.Valid() {
+		return FormatInfo{}
+	}
+	return formatInfos[a]
+}
+
+// Formats returns the details of every AudioOutput, ordered by value.
+func Formats() []FormatInfo {
+	return append([]FormatInfo(nil), formatInfos[:]...)
+}
+
+// AudioOutputByHeader returns the AudioOutput for an X-Microsoft-OutputFormat header value, the inverse of
+// AudioOutput.String. The lookup is case insensitive.
+func AudioOutputByHeader(header string) (AudioOutput, bool) {
+	a, ok := formatsByHeader[strings.ToLower(header)]
+	return a, ok
+}
+
+// AudioOutputString resolves either a constant name (e.g. "RIFF16khz16bitMonoPCM") or an Azure
+// X-Microsoft-OutputFormat header value (e.g. "riff-16khz-16bit-mono-pcm") to an AudioOutput.
+func AudioOutputString(s string) (AudioOutput, error) {
+	if a, ok := MapAudioToFormatid[s]; ok {
+		return a, nil
+	}
+	if a, ok := AudioOutputByHeader(s); ok {
+		return a, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to AudioOutput values", s)
+}
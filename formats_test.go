@@ -0,0 +1,107 @@
+package azuretexttospeech
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatInfo(t *testing.T) {
+	info := RIFF8khz8bitMonoMulaw.Info()
+	assert.Equal(t, "riff-8khz-8bit-mono-mulaw", info.Header)
+	assert.Equal(t, ContainerRIFF, info.Container)
+	assert.Equal(t, CodecMulaw, info.Codec)
+	assert.Equal(t, 8000, info.SampleRate)
+	assert.Equal(t, 64000, info.Bitrate)
+	assert.Equal(t, "wav", info.Extension)
+	assert.False(t, info.Streamable)
+
+	info = AUDIO24khz48kbitrateMonoMP3.Info()
+	assert.Equal(t, 48000, info.Bitrate)
+	assert.Equal(t, "audio/mpeg", info.MIMEType)
+	assert.True(t, info.Streamable)
+
+	// both Ogg formats share an extension, as do both WebM formats.
+	assert.Equal(t, "ogg", MapAudioFileExtensions["OGG16khz16bitMonoOpus"])
+	assert.Equal(t, "ogg", MapAudioFileExtensions["OGG24khz16bitMonoOpus"])
+	assert.Equal(t, "webm", MapAudioFileExtensions["WEBM16khz16bitMonoOpus"])
+	assert.Equal(t, "webm", MapAudioFileExtensions["WEBM24khz16bitMonoOpus"])
+}
+
+func TestFormatLookup(t *testing.T) {
+	for _, f := range Formats() {
+		a, ok := AudioOutputByHeader(f.Header)
+		assert.True(t, ok, f.Header)
+		assert.Equal(t, f.Header, a.String())
+		assert.Equal(t, f.Name, a.Info().Name)
+
+		b, err := AudioOutputString(f.Name)
+		assert.NoError(t, err)
+		assert.Equal(t, a, b)
+	}
+
+	a, ok := AudioOutputByHeader("RIFF-16KHZ-16BIT-MONO-PCM")
+	assert.True(t, ok)
+	assert.Equal(t, RIFF16khz16bitMonoPCM, a)
+
+	_, ok = AudioOutputByHeader("riff-11khz-4bit-mono-pcm")
+	assert.False(t, ok)
+	_, err := AudioOutputString("nope")
+	assert.Error(t, err)
+}
+
+func TestAudioOutputOutOfRange(t *testing.T) {
+	assert.Equal(t, "AudioOutput(-1)", AudioOutput(-1).String())
+	assert.Equal(t, "AudioOutput(1000)", AudioOutput(1000).String())
+	assert.False(t, AudioOutput(1000).Valid())
+	assert.Equal(t, FormatInfo{}, AudioOutput(1000).Info())
+}
+
+// TestFormatCatalog ensures every AudioOutput constant has a name, header, extension and complete FormatInfo.
+func TestFormatCatalog(t *testing.T) {
+	names := make(map[string]bool)
+	headers := make(map[string]bool)
+	for a := AudioOutput(0); a < numAudioOutputs; a++ {
+		info := a.Info()
+		assert.NotEmpty(t, info.Name, "AudioOutput(%d) has no name", int(a))
+		assert.NotEmpty(t, info.Header, "%s has no header", info.Name)
+		assert.NotEmpty(t, info.Container, "%s has no container", info.Name)
+		assert.NotEmpty(t, info.Codec, "%s has no codec", info.Name)
+		assert.NotZero(t, info.SampleRate, "%s has no sample rate", info.Name)
+		assert.NotZero(t, info.Channels, "%s has no channel count", info.Name)
+		assert.NotEmpty(t, info.MIMEType, "%s has no MIME type", info.Name)
+		assert.NotEmpty(t, info.Extension, "%s has no extension", info.Name)
+
+		assert.False(t, names[info.Name], "duplicate name %s", info.Name)
+		assert.False(t, headers[info.Header], "duplicate header %s", info.Header)
+		names[info.Name] = true
+		headers[info.Header] = true
+
+		assert.Equal(t, info.Extension, MapAudioFileExtensions[info.Name])
+		assert.Equal(t, a, MapAudioToFormatid[info.Name])
+		if info.Codec == CodecPCM || info.Codec == CodecMulaw || info.Codec == CodecAlaw {
+			assert.NotZero(t, info.BitDepth, "%s has no bit depth", info.Name)
+		}
+	}
+	assert.Len(t, MapAudioToFormatid, int(numAudioOutputs))
+}
+
+func TestAudioOutputMarshaling(t *testing.T) {
+	type request struct {
+		Format AudioOutput `json:"format"`
+	}
+	b, err := json.Marshal(request{Format: G722_16khz64kbps})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"format":"g722-16khz-64kbps"}`, string(b))
+
+	var r request
+	assert.NoError(t, json.Unmarshal([]byte(`{"format":"riff-44100hz-16bit-mono-pcm"}`), &r))
+	assert.Equal(t, RIFF44100hz16bitMonoPCM, r.Format)
+	assert.NoError(t, json.Unmarshal([]byte(`{"format":"AMRWB16000hz"}`), &r))
+	assert.Equal(t, AMRWB16000hz, r.Format)
+	assert.Error(t, json.Unmarshal([]byte(`{"format":"riff-11khz"}`), &r))
+
+	_, err = json.Marshal(request{Format: numAudioOutputs})
+	assert.Error(t, err, "out of range values cannot be marshaled")
+}
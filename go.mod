@@ -3,8 +3,9 @@ module github.com/linexjlin/azuretexttospeech
 go 1.14
 
 require (
-	github.com/nbio/st v0.0.0-20140626010706-e9e8d9816f32 // indirect
-	github.com/stretchr/testify v1.6.1
-	golang.org/x/net v0.0.0-20211123203042-d83791d6bcd9 // indirect
-	gopkg.in/h2non/gentleman.v2 v2.0.5
+	github.com/gorilla/websocket v1.5.0
+	github.com/stretchr/testify v1.7.0
+	google.golang.org/grpc v1.41.0
+	google.golang.org/protobuf v1.27.1
+	gopkg.in/yaml.v3 v3.0.1
 )
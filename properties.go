@@ -1,8 +1,13 @@
 package azuretexttospeech
 
+import (
+	"fmt"
+	"strings"
+)
+
 // AudioOutput types represent the supported audio encoding formats for the text-to-speech endpoint.
 // This type is required when requesting to azuretexttospeech.Synthesize text-to-speed request.
-// Each incorporates a bitrate and encoding type. The Speech service supports 24 kHz, 16 kHz, and 8 kHz audio outputs.
+// Each incorporates a bitrate and encoding type, see FormatInfo for the details of each format.
 // See: https://docs.microsoft.com/en-us/azure/cognitive-services/speech-service/rest-text-to-speech#audio-outputs
 
 type AudioOutput int
@@ -33,128 +38,68 @@ const (
 	RAW24khz16bitMonoTruesilk
 	WEBM24khz16bitMonoOpus
 	OGG24khz16bitMonoOpus
-)
+	RAW8khz16bitMonoPCM
+	RAW22050hz16bitMonoPCM
+	RAW44100hz16bitMonoPCM
+	RIFF8khz16bitMonoPCM
+	RIFF22050hz16bitMonoPCM
+	RIFF44100hz16bitMonoPCM
+	AUDIO16khz16bit32kbpsMonoOpus
+	AUDIO24khz16bit24kbpsMonoOpus
+	AUDIO24khz16bit48kbpsMonoOpus
+	WEBM24khz16bit24kbpsMonoOpus
+	AMRWB16000hz
+	G722_16khz64kbps
+	RIFF16khz16kbpsMonoSiren  // legacy, kept for existing deployments
+	AUDIO16khz16kbpsMonoSiren // legacy, kept for existing deployments
 
-var MapAudioFileExtensions = map[string]string{
-	"RAW16khz16bitMonoPCM":         "raw",
-	"RAW24khz16bitMonoPCM":         "raw",
-	"RAW48khz16bitMonoPCM":         "raw",
-	"RAW8khz8bitMonoMulaw":         "raw",
-	"RAW8khz8bitMonoAlaw":          "raw",
-	"AUDIO16khz32kbitrateMonoMP3":  "mp3",
-	"AUDIO16khz128kbitrateMonoMP3": "mp3",
-	"AUDIO24khz96kbitrateMonoMP3":  "mp3",
-	"AUDIO48khz96kbitrateMonoMP3":  "mp3",
-	"RAW16khz16bitMonoTruesilk":    "raw",
-	"WEBM16khz16bitMonoOpus":       "opus",
-	"OGG16khz16bitMonoOpus":        "opus",
-	"OGG48khz16bitMonoOpus":        "opus",
-	"RIFF16khz16bitMonoPCM":        "wav",
-	"RIFF24khz16bitMonoPCM":        "wav",
-	"RIFF48khz16bitMonoPCM":        "wav",
-	"RIFF8khz8bitMonoMulaw":        "wav",
-	"RIFF8khz8bitMonoAlaw":         "wav",
-	"AUDIO16khz64kbitrateMonoMP3":  "mp3",
-	"AUDIO24khz48kbitrateMonoMP3":  "mp3",
-	"AUDIO24khz160kbitrateMonoMP3": "mp3",
-	"AUDIO48khz192kbitrateMonoMP3": "mp3",
-	"RAW24khz16bitMonoTruesilk":    "raw",
-	"WEBM24khz16bitMonoOpus":       "webm",
-	"OGG24khz16bitMonoOpus":        "ogg",
-}
+	numAudioOutputs // must remain last, sizes the format table
+)
 
-var MapAudioToFormatid = map[string]AudioOutput{
-	"RAW16khz16bitMonoPCM":         RAW16khz16bitMonoPCM,
-	"RAW24khz16bitMonoPCM":         RAW24khz16bitMonoPCM,
-	"RAW48khz16bitMonoPCM":         RAW48khz16bitMonoPCM,
-	"RAW8khz8bitMonoMulaw":         RAW8khz8bitMonoMulaw,
-	"RAW8khz8bitMonoAlaw":          RAW8khz8bitMonoAlaw,
-	"AUDIO16khz32kbitrateMonoMP3":  AUDIO16khz32kbitrateMonoMP3,
-	"AUDIO16khz128kbitrateMonoMP3": AUDIO16khz128kbitrateMonoMP3,
-	"AUDIO24khz96kbitrateMonoMP3":  AUDIO24khz96kbitrateMonoMP3,
-	"AUDIO48khz96kbitrateMonoMP3":  AUDIO48khz96kbitrateMonoMP3,
-	"RAW16khz16bitMonoTruesilk":    RAW16khz16bitMonoTruesilk,
-	"WEBM16khz16bitMonoOpus":       WEBM16khz16bitMonoOpus,
-	"OGG16khz16bitMonoOpus":        OGG16khz16bitMonoOpus,
-	"OGG48khz16bitMonoOpus":        OGG48khz16bitMonoOpus,
-	"RIFF16khz16bitMonoPCM":        RIFF16khz16bitMonoPCM,
-	"RIFF24khz16bitMonoPCM":        RIFF24khz16bitMonoPCM,
-	"RIFF48khz16bitMonoPCM":        RIFF48khz16bitMonoPCM,
-	"RIFF8khz8bitMonoMulaw":        RIFF8khz8bitMonoMulaw,
-	"RIFF8khz8bitMonoAlaw":         RIFF8khz8bitMonoAlaw,
-	"AUDIO16khz64kbitrateMonoMP3":  AUDIO16khz64kbitrateMonoMP3,
-	"AUDIO24khz48kbitrateMonoMP3":  AUDIO24khz48kbitrateMonoMP3,
-	"AUDIO24khz160kbitrateMonoMP3": AUDIO24khz160kbitrateMonoMP3,
-	"AUDIO48khz192kbitrateMonoMP3": AUDIO48khz192kbitrateMonoMP3,
-	"RAW24khz16bitMonoTruesilk":    RAW24khz16bitMonoTruesilk,
-	"WEBM24khz16bitMonoOpus":       WEBM24khz16bitMonoOpus,
-	"OGG24khz16bitMonoOpus":        OGG24khz16bitMonoOpus,
-}
+// MapAudioFileExtensions maps each AudioOutput constant name to the file extension of its container, see
+// FormatInfo.Extension.
+var MapAudioFileExtensions = func() map[string]string {
+	m := make(map[string]string, len(formatInfos))
+	for _, f := range formatInfos {
+		m[f.Name] = f.Extension
+	}
+	return m
+}()
 
-// const (
-// 	AudioRIFF8Bit8kHzMonoPCM AudioOutput = iota
-// 	AudioRIFF16Bit16kHzMonoPCM
-// 	AudioRIFF16khz16kbpsMonoSiren
-// 	AudioRIFF24khz16bitMonoPcm
-// 	AudioRAW8Bit8kHzMonoMulaw
-// 	AudioRAW16Bit16kHzMonoMulaw
-// 	AudioRAW24khz16bitMonoPcm
-// 	AudioSsml16khz16bitMonoTts
-// 	Audio16khz16kbpsMonoSiren
-// 	Audio16khz32kbitrateMonoMp3
-// 	Audio6khz64kbitrateMonoMp3
-// 	Audio16khz128kbitrateMonoMp3
-// 	Audio24khz48kbitrateMonoMp3
-// 	Audio24khz96kbitrateMonoMp3
-// )
+// MapAudioToFormatid maps each AudioOutput constant name to its value.
+var MapAudioToFormatid = func() map[string]AudioOutput {
+	m := make(map[string]AudioOutput, len(formatInfos))
+	for i, f := range formatInfos {
+		m[f.Name] = AudioOutput(i)
+	}
+	return m
+}()
 
+// String returns the X-Microsoft-OutputFormat header value for a.
 func (a AudioOutput) String() string {
-	return []string{
-		"raw-16khz-16bit-mono-pcm",
-		"raw-24khz-16bit-mono-pcm",
-		"raw-48khz-16bit-mono-pcm",
-		"raw-8khz-8bit-mono-mulaw",
-		"raw-8khz-8bit-mono-alaw",
-		"audio-16khz-32kbitrate-mono-mp3",
-		"audio-16khz-128kbitrate-mono-mp3",
-		"audio-24khz-96kbitrate-mono-mp3",
-		"audio-48khz-96kbitrate-mono-mp3",
-		"raw-16khz-16bit-mono-truesilk",
-		"webm-16khz-16bit-mono-opus",
-		"ogg-16khz-16bit-mono-opus",
-		"ogg-48khz-16bit-mono-opus",
-		"riff-16khz-16bit-mono-pcm",
-		"riff-24khz-16bit-mono-pcm",
-		"riff-48khz-16bit-mono-pcm",
-		"riff-8khz-8bit-mono-mulaw",
-		"riff-8khz-8bit-mono-alaw",
-		"audio-16khz-64kbitrate-mono-mp3",
-		"audio-24khz-48kbitrate-mono-mp3",
-		"audio-24khz-160kbitrate-mono-mp3",
-		"audio-48khz-192kbitrate-mono-mp3",
-		"raw-24khz-16bit-mono-truesilk",
-		"webm-24khz-16bit-mono-opus",
-		"ogg-24khz-16bit-mono-opus",
-	}[a]
+	if !a.Valid() {
+		return fmt.Sprintf("AudioOutput(%d)", int(a))
+	}
+	return formatInfos[a].Header
 }
 
-// func (a AudioOutput) String() string {
-// 	return []string{"riff-8khz-8bit-mono-mulaw",
-// 		"riff-16khz-16bit-mono-pcm",
-// 		"riff-16khz-16kbps-mono-siren",
-// 		"riff-24khz-16bit-mono-pcm",
-// 		"raw-8khz-8bit-mono-mulaw",
-// 		"raw-16khz-16bit-mono-pcm",
-// 		"raw-24khz-16bit-mono-pcm",
-// 		"ssml-16khz-16bit-mono-tts",
-// 		"audio-16khz-16kbps-mono-siren",
-// 		"audio-16khz-32kbitrate-mono-mp3",
-// 		"audio-16khz-64kbitrate-mono-mp3",
-// 		"audio-16khz-128kbitrate-mono-mp3",
-// 		"audio-24khz-48kbitrate-mono-mp3",
-// 		"audio-24khz-96kbitrate-mono-mp3",
-// 	}[a]
-// }
+// MarshalText encodes a as its X-Microsoft-OutputFormat header value.
+func (a AudioOutput) MarshalText() ([]byte, error) {
+	if !a.Valid() {
+		return nil, fmt.Errorf("%s is not a valid AudioOutput", a)
+	}
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText decodes either a header value or a constant name, see AudioOutputString.
+func (a *AudioOutput) UnmarshalText(text []byte) error {
+	v, err := AudioOutputString(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
 
 // Gender type for the digitized language
 //go:generate enumer -type=Gender -linecomment -json
@@ -347,3 +292,13 @@ func (t Region) String() string {
 	}[t]
 
 }
+
+// RegionString returns the Region for an Azure region name such as "westus2".
+func RegionString(s string) (Region, error) {
+	for r := RegionAustraliaEast; r <= RegionWestUS2; r++ {
+		if r.String() == strings.ToLower(s) {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("%s does not belong to Region values", s)
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio/flac"
+	"github.com/linexjlin/azuretexttospeech/audio/wav"
+)
+
+// MaxSpeechInput is the longest input of a /v1/audio/speech request in characters, the limit of the OpenAI API.
+const MaxSpeechInput = 4096
+
+// DefaultVoiceAliases maps the voices of the OpenAI API to neural voices of a similar character.
+var DefaultVoiceAliases = map[string]string{
+	"alloy":   "en-US-AriaNeural",
+	"ash":     "en-US-AndrewNeural",
+	"ballad":  "en-US-BrianNeural",
+	"coral":   "en-US-AvaNeural",
+	"echo":    "en-US-GuyNeural",
+	"fable":   "en-GB-RyanNeural",
+	"nova":    "en-US-JennyNeural",
+	"onyx":    "en-US-DavisNeural",
+	"sage":    "en-US-EmmaNeural",
+	"shimmer": "en-US-SaraNeural",
+	"verse":   "en-US-TonyNeural",
+}
+
+// speechFormats maps the response formats of the OpenAI API to the format requested from the synthesizer. FLAC
+// is encoded locally from the WAVE audio. The service produces no AAC, so aac is answered with the nearest
+// compressed format, MP3, labelled audio/mpeg.
+var speechFormats = map[string]tts.AudioOutput{
+	"mp3":  tts.AUDIO24khz48kbitrateMonoMP3,
+	"opus": tts.OGG24khz16bitMonoOpus,
+	"wav":  tts.RIFF24khz16bitMonoPCM,
+	"pcm":  tts.RAW24khz16bitMonoPCM,
+	"flac": tts.RIFF24khz16bitMonoPCM,
+	"aac":  tts.AUDIO24khz48kbitrateMonoMP3,
+}
+
+var flacConversion = &conversion{name: "flac", contentType: "audio/flac", convert: func(audio []byte) ([]byte, error) {
+	a, err := wav.Decode(audio)
+	if err != nil {
+		return nil, err
+	}
+	return flac.Encode(a)
+}}
+
+// SpeechRequest is the JSON body of a /v1/audio/speech request, as in the OpenAI API. Model is accepted for
+// compatibility and ignored.
+type SpeechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`                     // alias of Config.VoiceAliases or short name
+	ResponseFormat string  `json:"response_format,omitempty"` // mp3, opus, aac, flac, wav or pcm; mp3 when empty
+	Speed          float64 `json:"speed,omitempty"`           // 0.25 to 4, 1 when zero
+}
+
+// openAIError is the JSON body of failed /v1/audio/speech requests, as in the OpenAI API.
+type openAIError struct {
+	Error struct {
+		Message string  `json:"message"`
+		Type    string  `json:"type"`
+		Param   *string `json:"param"`
+		Code    *string `json:"code"`
+	} `json:"error"`
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	var e openAIError
+	e.Error.Message = fmt.Sprintf(format, args...)
+	e.Error.Type = "invalid_request_error"
+	if status >= 500 {
+		e.Error.Type = "server_error"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
+
+// speechRate returns the prosody rate of an OpenAI speed, clamped to the 0.5 to 2 times the service supports.
+func speechRate(speed float64) string {
+	speed = math.Max(0.5, math.Min(2, speed))
+	return fmt.Sprintf("%+.0f%%", (speed-1)*100)
+}
+
+func (s *Server) speech(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes))
+	if err != nil {
+		writeOpenAIError(w, http.StatusRequestEntityTooLarge, "request body larger than %d bytes", s.config.MaxBodyBytes)
+		return
+	}
+	var req SpeechRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid JSON request, %v", err)
+		return
+	}
+
+	switch n := utf8.RuneCountInString(req.Input); {
+	case strings.TrimSpace(req.Input) == "":
+		writeOpenAIError(w, http.StatusBadRequest, "input is required")
+		return
+	case n > MaxSpeechInput:
+		writeOpenAIError(w, http.StatusBadRequest, "input of %d characters is longer than %d", n, MaxSpeechInput)
+		return
+	}
+	if req.Speed == 0 {
+		req.Speed = 1
+	}
+	if req.Speed < 0.25 || req.Speed > 4 {
+		writeOpenAIError(w, http.StatusBadRequest, "speed %g is not between 0.25 and 4", req.Speed)
+		return
+	}
+	name := strings.ToLower(firstOf(req.ResponseFormat, "mp3"))
+	format, ok := speechFormats[name]
+	if !ok {
+		writeOpenAIError(w, http.StatusBadRequest, "unsupported response_format %q, use mp3, opus, aac, flac, wav or pcm", req.ResponseFormat)
+		return
+	}
+	var conv *conversion
+	if name == "flac" {
+		conv = flacConversion
+	}
+
+	voice := firstOf(req.Voice, s.config.DefaultVoice)
+	if alias, ok := s.config.VoiceAliases[strings.ToLower(voice)]; ok {
+		voice = alias
+	}
+	ssml := tts.VoiceSSML(req.Input, voice, "0%", speechRate(req.Speed))
+	s.serve(w, r, ssml, format, conv, writeOpenAIError)
+}
@@ -0,0 +1,409 @@
+// Package server exposes a tts.Synthesizer over HTTP, so that services written in any language can synthesize
+// speech without an Azure SDK or subscription key of their own:
+//
+//	POST /synthesize  JSON {"text", "ssml", "voice", "format", "pitch", "rate"} or an SSML body, audio out
+//	GET  /voices      the voice list as JSON, optionally filtered with ?locale=
+//	GET  /formats     the supported output formats as JSON
+//	GET  /healthz     200 while the server runs
+//	GET  /readyz      200 while the voice list can be fetched
+//
+//	POST /v1/audio/speech  the speech endpoint of the OpenAI API, see SpeechRequest
+//
+// Audio is streamed through from the synthesizer as it renders, and cached when Config.Cache is set. Callers
+// authenticate with one of Config.Keys in an X-API-Key or Authorization: Bearer header.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+// DefaultMaxBodyBytes limits the size of synthesis requests when Config.MaxBodyBytes is zero.
+const DefaultMaxBodyBytes = 64 << 10
+
+// readyTimeout bounds the voice list request made by /readyz.
+const readyTimeout = 5 * time.Second
+
+// Config configures a Server.
+type Config struct {
+	Synthesizer tts.Synthesizer
+	// Keys maps the API keys accepted to the name of their caller, which is logged with every request. When
+	// empty, requests are not authenticated.
+	Keys map[string]string
+	// MaxBodyBytes limits the size of synthesis requests, DefaultMaxBodyBytes when zero.
+	MaxBodyBytes int64
+	// DefaultVoice speaks text requests naming no voice, tts.DefaultVoice when empty.
+	DefaultVoice string
+	// DefaultFormat is the format of requests naming none, a constant name or X-Microsoft-OutputFormat value;
+	// tts.DefaultAudioOutput when empty.
+	DefaultFormat string
+	// VoiceAliases maps the voice names of /v1/audio/speech requests to voice short names, DefaultVoiceAliases
+	// when nil. Names missing from the table are used as short names.
+	VoiceAliases map[string]string
+	// Cache, when not nil, stores the audio of completed requests for CacheTTL, see tts.CacheKey.
+	Cache    tts.Cache
+	CacheTTL time.Duration
+	// Logger logs every synthesis request, the standard logger when nil.
+	Logger *log.Logger
+}
+
+// Server is an http.Handler serving the endpoints of the package.
+type Server struct {
+	config Config
+	format tts.AudioOutput // default
+	mux    *http.ServeMux
+}
+
+// New returns a server for c. c.Synthesizer must be set.
+func New(c Config) (*Server, error) {
+	if c.Synthesizer == nil {
+		return nil, fmt.Errorf("no synthesizer configured")
+	}
+	format := tts.DefaultAudioOutput
+	if c.DefaultFormat != "" {
+		var err error
+		if format, err = tts.AudioOutputString(c.DefaultFormat); err != nil {
+			return nil, fmt.Errorf("invalid default format, %v", err)
+		}
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if c.DefaultVoice == "" {
+		c.DefaultVoice = tts.DefaultVoice
+	}
+	if c.VoiceAliases == nil {
+		c.VoiceAliases = DefaultVoiceAliases
+	}
+	if c.Logger == nil {
+		c.Logger = log.New(log.Writer(), log.Prefix(), log.Flags())
+	}
+	s := &Server{config: c, format: format, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/synthesize", s.authorized(s.synthesize))
+	s.mux.HandleFunc("/voices", s.authorized(s.voices))
+	s.mux.HandleFunc("/formats", s.authorized(s.formats))
+	s.mux.HandleFunc("/v1/audio/speech", s.authorized(s.speech))
+	s.mux.HandleFunc("/healthz", s.healthz)
+	s.mux.HandleFunc("/readyz", s.readyz)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Request is the JSON body of a synthesis request. Exactly one of Text and SSML is set.
+type Request struct {
+	Text   string `json:"text,omitempty"`
+	SSML   string `json:"ssml,omitempty"`
+	Voice  string `json:"voice,omitempty"`  // short name, for Text
+	Format string `json:"format,omitempty"` // constant name or X-Microsoft-OutputFormat value
+	Pitch  string `json:"pitch,omitempty"`  // prosody pitch for Text, e.g. "+10%"
+	Rate   string `json:"rate,omitempty"`   // prosody rate for Text
+}
+
+// errorResponse is the JSON body of failed requests.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{fmt.Sprintf(format, args...)})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+type callerKey struct{}
+
+// caller returns the name of the caller of a request passed by authorized.
+func caller(r *http.Request) string {
+	name, _ := r.Context().Value(callerKey{}).(string)
+	return name
+}
+
+// authorized checks the API key of requests to h, answering 401 when it is not one of Config.Keys.
+func (s *Server) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(s.config.Keys) == 0 {
+			h(w, r)
+			return
+		}
+		key := r.Header.Get("X-API-Key")
+		if key == "" {
+			key = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		name, ok := s.config.Keys[key]
+		if key == "" || !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, name)))
+	}
+}
+
+// parse reads the SSML and output format of a synthesis request, answering the request when it is invalid.
+func (s *Server) parse(w http.ResponseWriter, r *http.Request) (string, tts.AudioOutput, bool) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body larger than %d bytes", s.config.MaxBodyBytes)
+		return "", 0, false
+	}
+	var req Request
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON request, %v", err)
+			return "", 0, false
+		}
+	case "application/ssml+xml", "application/xml", "text/xml":
+		req.SSML = string(body)
+	case "text/plain":
+		req.Text = string(body)
+	default:
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported Content-Type %q, use application/json or application/ssml+xml", r.Header.Get("Content-Type"))
+		return "", 0, false
+	}
+	// settings missing from the body may be given in the query.
+	q := r.URL.Query()
+	req.Voice = firstOf(req.Voice, q.Get("voice"))
+	req.Format = firstOf(req.Format, q.Get("format"), r.Header.Get("X-Microsoft-OutputFormat"))
+	req.Pitch = firstOf(req.Pitch, q.Get("pitch"))
+	req.Rate = firstOf(req.Rate, q.Get("rate"))
+
+	format := s.format
+	if req.Format != "" {
+		if format, err = tts.AudioOutputString(req.Format); err != nil {
+			writeError(w, http.StatusBadRequest, "unsupported format %q, see /formats", req.Format)
+			return "", 0, false
+		}
+	}
+	switch {
+	case strings.TrimSpace(req.SSML) != "" && strings.TrimSpace(req.Text) != "":
+		writeError(w, http.StatusBadRequest, "set either text or ssml")
+		return "", 0, false
+	case strings.TrimSpace(req.SSML) != "":
+		return req.SSML, format, true
+	case strings.TrimSpace(req.Text) != "":
+		voice := firstOf(req.Voice, s.config.DefaultVoice)
+		return tts.VoiceSSML(req.Text, voice, firstOf(req.Pitch, "0%"), firstOf(req.Rate, "0%")), format, true
+	}
+	writeError(w, http.StatusBadRequest, "nothing to synthesize")
+	return "", 0, false
+}
+
+func (s *Server) synthesize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	ssml, format, ok := s.parse(w, r)
+	if !ok {
+		return
+	}
+	s.serve(w, r, ssml, format, nil, writeError)
+}
+
+// conversion renders the complete audio of the synthesizer in a format it does not produce.
+type conversion struct {
+	name        string // of the format, distinguishing its cache entries
+	contentType string
+	convert     func(audio []byte) ([]byte, error)
+}
+
+// errorWriter answers a failed request in the error shape of an endpoint.
+type errorWriter func(w http.ResponseWriter, status int, format string, args ...interface{})
+
+// serve answers a request with the audio of ssml in format, from the cache when possible. Audio streams through
+// as it renders, unless conv is not nil: the audio is then converted once complete.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, ssml string, format tts.AudioOutput, conv *conversion, fail errorWriter) {
+	start := time.Now()
+	key := tts.CacheKey(ssml, "", format)
+	w.Header().Set("Content-Type", format.Info().MIMEType)
+	if conv != nil {
+		key += "." + conv.name
+		w.Header().Set("Content-Type", conv.contentType)
+	}
+	if s.config.Cache != nil {
+		if b, ok := s.config.Cache.Get(key); ok {
+			w.Header().Set("X-Cache", "HIT")
+			w.Header().Set("Content-Length", fmt.Sprint(len(b)))
+			w.Write(b)
+			s.log(r, format, len(b), start, nil)
+			return
+		}
+		w.Header().Set("X-Cache", "MISS")
+	}
+
+	if conv != nil {
+		audio, err := s.config.Synthesizer.SynthesizeSSMLWithContext(r.Context(), ssml, format)
+		if err == nil {
+			if audio, err = conv.convert(audio); err != nil {
+				err = fmt.Errorf("failed to convert %s audio to %s, %v", format, conv.name, err)
+			}
+		}
+		if err != nil {
+			w.Header().Del("X-Cache")
+			status, message := upstreamError(err)
+			fail(w, status, "%s", message)
+			s.log(r, format, 0, start, err)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(audio)))
+		w.Write(audio)
+		s.store(key, audio)
+		s.log(r, format, len(audio), start, nil)
+		return
+	}
+
+	stream, err := s.config.Synthesizer.SynthesizeStreamWithContext(r.Context(), ssml, format)
+	if err != nil {
+		w.Header().Del("X-Cache")
+		status, message := upstreamError(err)
+		fail(w, status, "%s", message)
+		s.log(r, format, 0, start, err)
+		return
+	}
+	defer stream.Close()
+
+	var body io.Reader = stream
+	var audio bytes.Buffer
+	if s.config.Cache != nil {
+		body = io.TeeReader(stream, &audio)
+	}
+	n, err := io.Copy(flushWriter{w}, body)
+	if err == nil {
+		s.store(key, audio.Bytes())
+	}
+	s.log(r, format, int(n), start, err)
+}
+
+// store caches the audio of a completed request.
+func (s *Server) store(key string, audio []byte) {
+	if s.config.Cache == nil {
+		return
+	}
+	if err := s.config.Cache.Set(key, audio, s.config.CacheTTL); err != nil {
+		s.config.Logger.Printf("failed to store synthesis result in cache, %v", err)
+	}
+}
+
+// flushWriter flushes every write, so that audio reaches the caller as it renders.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if fl, ok := f.w.(http.Flusher); ok {
+		fl.Flush()
+	}
+	return n, err
+}
+
+func (s *Server) log(r *http.Request, format tts.AudioOutput, n int, start time.Time, err error) {
+	who := caller(r)
+	if who == "" {
+		who = r.RemoteAddr
+	}
+	if err != nil {
+		s.config.Logger.Printf("synthesize %s for %s failed after %s, %v", format, who, time.Since(start), err)
+		return
+	}
+	s.config.Logger.Printf("synthesize %s for %s: %d bytes in %s", format, who, n, time.Since(start))
+}
+
+// upstreamError maps an error of the synthesizer to the status answered: 400 for requests the service rejected,
+// 429 while it throttles, 503 for other temporary failures and 502 otherwise.
+func upstreamError(err error) (int, string) {
+	var status *tts.StatusError
+	switch {
+	case errors.As(err, &status) && status.StatusCode == http.StatusBadRequest:
+		return http.StatusBadRequest, err.Error()
+	case errors.As(err, &status) && status.StatusCode == http.StatusTooManyRequests:
+		return http.StatusTooManyRequests, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, err.Error()
+	case tts.Temporary(err):
+		return http.StatusServiceUnavailable, err.Error()
+	}
+	return http.StatusBadGateway, err.Error()
+}
+
+func (s *Server) voices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	voices, err := s.config.Synthesizer.VoicesWithContext(r.Context())
+	if err != nil {
+		status, message := upstreamError(err)
+		writeError(w, status, "%s", message)
+		return
+	}
+	if locale := r.URL.Query().Get("locale"); locale != "" {
+		var matched []tts.Voice
+		for _, v := range voices {
+			if strings.EqualFold(v.Locale, locale) {
+				matched = append(matched, v)
+			}
+		}
+		voices = matched
+	}
+	if voices == nil {
+		voices = []tts.Voice{}
+	}
+	writeJSON(w, voices)
+}
+
+func (s *Server) formats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	writeJSON(w, tts.Formats())
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if _, err := s.config.Synthesizer.VoicesWithContext(ctx); err != nil {
+		writeError(w, http.StatusServiceUnavailable, "%v", err)
+		return
+	}
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+func firstOf(v ...string) string {
+	for _, s := range v {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
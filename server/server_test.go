@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio"
+	"github.com/linexjlin/azuretexttospeech/azurettstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T, c Config) (*httptest.Server, *azurettstest.Fake) {
+	fake := azurettstest.NewFake()
+	c.Synthesizer = fake
+	c.DefaultVoice = "en-US-JennyNeural"
+	c.Logger = log.New(ioutil.Discard, "", 0)
+	s, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(s), fake
+}
+
+func post(t *testing.T, url, contentType, body string, header ...string) (*http.Response, []byte) {
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, _ := ioutil.ReadAll(res.Body)
+	return res, b
+}
+
+func TestSynthesize(t *testing.T) {
+	ts, fake := newTestServer(t, Config{Cache: tts.NewMemoryCache(1 << 20)})
+	defer ts.Close()
+
+	res, b := post(t, ts.URL+"/synthesize", "application/json", `{"text":"Hello world","format":"RIFF16khz16bitMonoPCM"}`)
+	assert.Equal(t, http.StatusOK, res.StatusCode, string(b))
+	assert.Equal(t, "audio/wav", res.Header.Get("Content-Type"))
+	assert.Equal(t, "MISS", res.Header.Get("X-Cache"))
+	d, err := audio.Duration(b, tts.RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, azurettstest.Duration("Hello world"), d)
+	if requests := fake.Requests(); assert.Len(t, requests, 1) {
+		assert.Contains(t, requests[0].SSML, "en-US-JennyNeural")
+	}
+
+	res, cached := post(t, ts.URL+"/synthesize", "application/json", `{"text":"Hello world","format":"riff-16khz-16bit-mono-pcm"}`)
+	assert.Equal(t, "HIT", res.Header.Get("X-Cache"))
+	assert.Equal(t, b, cached)
+	assert.Len(t, fake.Requests(), 1)
+
+	ssml := `<speak version="1.0" xml:lang="en-US"><voice name="en-US-GuyNeural">Hi</voice></speak>`
+	res, b = post(t, ts.URL+"/synthesize?format=ogg-24khz-16bit-mono-opus", "application/ssml+xml", ssml)
+	assert.Equal(t, http.StatusOK, res.StatusCode, string(b))
+	assert.Equal(t, "audio/ogg", res.Header.Get("Content-Type"))
+
+	// the default format.
+	res, _ = post(t, ts.URL+"/synthesize", "text/plain; charset=utf-8", "Hi")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "audio/mpeg", res.Header.Get("Content-Type"))
+
+	for _, c := range []struct {
+		contentType, body string
+		status            int
+	}{
+		{"application/json", `{"text":"Hi","format":"wav"}`, http.StatusBadRequest},
+		{"application/json", `{"text":"Hi","ssml":"<speak/>"}`, http.StatusBadRequest},
+		{"application/json", `{"text":"  "}`, http.StatusBadRequest},
+		{"application/json", `{"text":`, http.StatusBadRequest},
+		{"application/octet-stream", `Hi`, http.StatusUnsupportedMediaType},
+		{"application/json", `{"text":"Hi","voice":"xx-XX-NobodyNeural"}`, http.StatusBadRequest},
+		{"application/json", `{"text":"` + strings.Repeat("a", DefaultMaxBodyBytes) + `"}`, http.StatusRequestEntityTooLarge},
+	} {
+		res, b := post(t, ts.URL+"/synthesize", c.contentType, c.body)
+		assert.Equal(t, c.status, res.StatusCode, c.body)
+		var e errorResponse
+		assert.NoError(t, json.Unmarshal(b, &e))
+		assert.NotEmpty(t, e.Error)
+	}
+
+	fake.Errors = []error{&tts.StatusError{StatusCode: http.StatusTooManyRequests, Message: "slow down"}, &tts.StatusError{StatusCode: http.StatusUnauthorized}}
+	res, _ = post(t, ts.URL+"/synthesize", "text/plain", "Not cached")
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	res, _ = post(t, ts.URL+"/synthesize", "text/plain", "Not cached")
+	assert.Equal(t, http.StatusBadGateway, res.StatusCode, "the gateway's own credentials are not the caller's problem")
+}
+
+func TestKeys(t *testing.T) {
+	var logged bytes.Buffer
+	fake := azurettstest.NewFake()
+	s, err := New(Config{Synthesizer: fake, DefaultVoice: "en-US-JennyNeural", Keys: map[string]string{"k1": "billing"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	s.config.Logger.SetOutput(&logged)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, _ := post(t, ts.URL+"/synthesize", "text/plain", "Hi")
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res, _ = post(t, ts.URL+"/synthesize", "text/plain", "Hi", "X-API-Key", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res, _ = post(t, ts.URL+"/synthesize", "text/plain", "Hi", "X-API-Key", "k1")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res, _ = post(t, ts.URL+"/synthesize", "text/plain", "Hi", "Authorization", "Bearer k1")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, logged.String(), "for billing")
+
+	// health endpoints need no key.
+	res, err = http.Get(ts.URL + "/healthz")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	res, err = http.Get(ts.URL + "/voices")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	ts, fake := newTestServer(t, Config{})
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/voices?locale=en-us")
+	if assert.NoError(t, err) {
+		var voices []tts.Voice
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&voices))
+		res.Body.Close()
+		assert.Len(t, voices, 2)
+	}
+
+	res, err = http.Get(ts.URL + "/formats")
+	if assert.NoError(t, err) {
+		var formats []tts.FormatInfo
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&formats))
+		res.Body.Close()
+		assert.Equal(t, tts.Formats(), formats)
+	}
+
+	res, err = http.Get(ts.URL + "/readyz")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	fake.Errors = []error{&tts.StatusError{StatusCode: http.StatusServiceUnavailable}}
+	res, err = http.Get(ts.URL + "/readyz")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	}
+
+	res, err = http.Get(ts.URL + "/synthesize")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	}
+}
+
+func TestSpeech(t *testing.T) {
+	ts, fake := newTestServer(t, Config{Cache: tts.NewMemoryCache(1 << 20)})
+	defer ts.Close()
+
+	res, b := post(t, ts.URL+"/v1/audio/speech", "application/json", `{"model":"tts-1","input":"Hello world","voice":"nova"}`)
+	assert.Equal(t, http.StatusOK, res.StatusCode, string(b))
+	assert.Equal(t, "audio/mpeg", res.Header.Get("Content-Type"))
+	if requests := fake.Requests(); assert.Len(t, requests, 1) {
+		assert.Contains(t, requests[0].SSML, "en-US-JennyNeural")
+		assert.Contains(t, requests[0].SSML, `rate="+0%"`)
+		assert.Equal(t, tts.AUDIO24khz48kbitrateMonoMP3, requests[0].Format)
+	}
+
+	res, b = post(t, ts.URL+"/v1/audio/speech", "application/json", `{"input":"Hello world","voice":"echo","response_format":"wav","speed":1.5}`)
+	assert.Equal(t, http.StatusOK, res.StatusCode, string(b))
+	assert.Equal(t, "audio/wav", res.Header.Get("Content-Type"))
+	requests := fake.Requests()
+	assert.Contains(t, requests[len(requests)-1].SSML, "en-US-GuyNeural")
+	assert.Contains(t, requests[len(requests)-1].SSML, `rate="+50%"`)
+
+	res, b = post(t, ts.URL+"/v1/audio/speech", "application/json", `{"input":"Hello world","voice":"en-US-GuyNeural","response_format":"pcm","speed":4}`)
+	assert.Equal(t, http.StatusOK, res.StatusCode, string(b))
+	requests = fake.Requests()
+	assert.Contains(t, requests[len(requests)-1].SSML, `rate="+100%"`, "clamped to what the service supports")
+	assert.Equal(t, tts.RAW24khz16bitMonoPCM, requests[len(requests)-1].Format)
+
+	for i, cache := range []string{"MISS", "HIT"} {
+		res, b = post(t, ts.URL+"/v1/audio/speech", "application/json", `{"input":"Hello world","voice":"nova","response_format":"flac"}`)
+		assert.Equal(t, http.StatusOK, res.StatusCode, string(b))
+		assert.Equal(t, "audio/flac", res.Header.Get("Content-Type"))
+		assert.Equal(t, cache, res.Header.Get("X-Cache"), i)
+		assert.Equal(t, "fLaC", string(b[:4]))
+	}
+
+	// there is no AAC: the nearest format is served, labelled as what it is.
+	res, b = post(t, ts.URL+"/v1/audio/speech", "application/json", `{"input":"Hello there","voice":"nova","response_format":"aac"}`)
+	assert.Equal(t, http.StatusOK, res.StatusCode, string(b))
+	assert.Equal(t, "audio/mpeg", res.Header.Get("Content-Type"))
+	requests = fake.Requests()
+	assert.Equal(t, tts.AUDIO24khz48kbitrateMonoMP3, requests[len(requests)-1].Format)
+
+	for _, body := range []string{
+		`{"input":"Hi","voice":"nova","response_format":"m4a"}`,
+		`{"input":"Hi","voice":"nova","speed":5}`,
+		`{"input":"  ","voice":"nova"}`,
+		`{"input":"` + strings.Repeat("a", MaxSpeechInput+1) + `","voice":"nova"}`,
+		`{"input":"Hi","voice":"alloy"}`, // the fake has no en-US-AriaNeural
+		`{"input":`,
+	} {
+		res, b := post(t, ts.URL+"/v1/audio/speech", "application/json", body)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+		var e openAIError
+		assert.NoError(t, json.Unmarshal(b, &e))
+		assert.NotEmpty(t, e.Error.Message)
+		assert.Equal(t, "invalid_request_error", e.Error.Type)
+	}
+}
@@ -0,0 +1,114 @@
+// Package sink provides destinations for synthesized audio: files, stdout, and external players that receive the
+// audio on their standard input while it is still being rendered.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+// AudioSink receives synthesized audio as it arrives. Close must be called once all audio has been written; for
+// players it blocks until playback has finished.
+type AudioSink interface {
+	io.Writer
+	Close() error
+}
+
+// File returns a sink writing to the named file, truncating it when it exists.
+func File(path string) (AudioSink, error) {
+	return os.Create(path)
+}
+
+// stdoutSink writes to os.Stdout without closing it.
+type stdoutSink struct{}
+
+func (stdoutSink) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdoutSink) Close() error                { return nil }
+
+// Stdout returns a sink writing to the process' standard output.
+func Stdout() AudioSink {
+	return stdoutSink{}
+}
+
+// commandSink pipes audio into the standard input of a running command.
+type commandSink struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// Command starts name with args and returns a sink feeding the command's standard input. Both the stdout and the
+// stderr of the command go to the stderr of the current process, so that its output never mixes with audio written
+// to stdout.
+func Command(ctx context.Context, name string, args ...string) (AudioSink, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start %s, %v", name, err)
+	}
+	return &commandSink{cmd: cmd, stdin: stdin}, nil
+}
+
+func (c *commandSink) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+// Close signals end of input and waits for the command to exit.
+func (c *commandSink) Close() error {
+	c.stdin.Close()
+	return c.cmd.Wait()
+}
+
+// Player starts the first available local player able to decode format and returns a sink feeding it.
+func Player(ctx context.Context, format tts.AudioOutput) (AudioSink, error) {
+	for _, argv := range PlayerCommands(format) {
+		if _, err := exec.LookPath(argv[0]); err == nil {
+			return Command(ctx, argv[0], argv[1:]...)
+		}
+	}
+	return nil, fmt.Errorf("no player found for %s, install aplay or ffplay", format)
+}
+
+// PlayerCommands returns candidate player command lines for format in order of preference. Every command reads
+// the audio from its standard input. Headerless raw formats are described to the player explicitly, containers
+// (RIFF, MP3, Ogg, WebM) are probed by the player itself.
+func PlayerCommands(format tts.AudioOutput) [][]string {
+	info := format.Info()
+	ffplay := []string{"ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet"}
+
+	switch info.Container {
+	case "":
+		return nil
+	case tts.ContainerRIFF:
+		return [][]string{{"aplay", "-q", "-"}, append(ffplay, "-i", "-")}
+	case tts.ContainerRaw:
+	default:
+		return [][]string{append(ffplay, "-i", "-")}
+	}
+
+	// sample format names understood by aplay and ffplay respectively.
+	names, ok := map[string][2]string{
+		tts.CodecPCM:   {"S16_LE", "s16le"},
+		tts.CodecMulaw: {"MU_LAW", "mulaw"},
+		tts.CodecAlaw:  {"A_LAW", "alaw"},
+	}[info.Codec]
+	if !ok {
+		// truesilk and other proprietary raw codecs cannot be played locally.
+		return nil
+	}
+	rate := strconv.Itoa(info.SampleRate)
+	return [][]string{
+		{"aplay", "-q", "-t", "raw", "-f", names[0], "-r", rate, "-c", strconv.Itoa(info.Channels), "-"},
+		append(ffplay, "-f", names[1], "-ar", rate, "-i", "-"),
+	}
+}
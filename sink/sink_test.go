@@ -0,0 +1,86 @@
+package sink
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlayerCommands(t *testing.T) {
+	cmds := PlayerCommands(tts.RAW8khz8bitMonoMulaw)
+	assert.Len(t, cmds, 2)
+	assert.Equal(t, []string{"aplay", "-q", "-t", "raw", "-f", "MU_LAW", "-r", "8000", "-c", "1", "-"}, cmds[0])
+
+	cmds = PlayerCommands(tts.RAW24khz16bitMonoPCM)
+	assert.Contains(t, cmds[1], "24000")
+
+	cmds = PlayerCommands(tts.RIFF16khz16bitMonoPCM)
+	assert.Equal(t, "aplay", cmds[0][0], "aplay reads RIFF headers itself")
+
+	cmds = PlayerCommands(tts.AUDIO16khz32kbitrateMonoMP3)
+	assert.Len(t, cmds, 1)
+	assert.Equal(t, "ffplay", cmds[0][0])
+
+	assert.Empty(t, PlayerCommands(tts.RAW16khz16bitMonoTruesilk))
+}
+
+func TestCommandSink(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	dir, err := ioutil.TempDir("", "azuretts-sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "played")
+	s, err := Command(context.Background(), "sh", "-c", "cat > "+out)
+	assert.NoError(t, err)
+	s.Write([]byte("64 BASIC BYTES FREE. "))
+	s.Write([]byte("READY."))
+	assert.NoError(t, s.Close())
+
+	b, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "64 BASIC BYTES FREE. READY.", string(b))
+
+	s, err = Command(context.Background(), "sh", "-c", "exit 3")
+	assert.NoError(t, err)
+	assert.Error(t, s.Close(), "a failing player should be reported")
+
+	// what the player prints stays out of stdout, where audio may be written too.
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	os.Stdout, err = os.Create(filepath.Join(dir, "stdout"))
+	assert.NoError(t, err)
+	os.Stderr, err = os.Create(filepath.Join(dir, "stderr"))
+	assert.NoError(t, err)
+	s, err = Command(context.Background(), "sh", "-c", "echo Playing")
+	assert.NoError(t, err)
+	assert.NoError(t, s.Close())
+	os.Stdout.Close()
+	os.Stderr.Close()
+	b, _ = ioutil.ReadFile(filepath.Join(dir, "stdout"))
+	assert.Empty(t, b)
+	b, _ = ioutil.ReadFile(filepath.Join(dir, "stderr"))
+	assert.Equal(t, "Playing\n", string(b))
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "azuretts-sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "audio.wav")
+	s, err := File(p)
+	assert.NoError(t, err)
+	s.Write([]byte("RIFF"))
+	assert.NoError(t, s.Close())
+	b, _ := ioutil.ReadFile(p)
+	assert.Equal(t, "RIFF", string(b))
+}
@@ -0,0 +1,312 @@
+// Package subtitle builds SRT and WebVTT captions aligned with synthesized narration. Timing comes from the word
+// and sentence boundary events of the WebSocket protocol, see tts.Event, or is estimated from the duration of
+// each synthesized chunk of text when only the REST endpoint is used. Lines are broken between words, or between
+// characters for Chinese and Japanese, which are written without spaces.
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+// Defaults for the zero fields of Options.
+const (
+	DefaultMaxLineLength = 42
+	DefaultMaxLines      = 2
+	DefaultMaxDuration   = 7 * time.Second
+)
+
+// Options controls how words are grouped into cues.
+type Options struct {
+	MaxLineLength int           // in columns; Chinese, Japanese and Korean characters take two
+	MaxLines      int           // lines per cue
+	MaxDuration   time.Duration // longest time a cue stays on screen
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxLineLength <= 0 {
+		o.MaxLineLength = DefaultMaxLineLength
+	}
+	if o.MaxLines <= 0 {
+		o.MaxLines = DefaultMaxLines
+	}
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = DefaultMaxDuration
+	}
+	return o
+}
+
+// Cue is a caption shown from Start to End. Lines of Text are separated by "\n".
+type Cue struct {
+	Start, End time.Duration
+	Text       string
+}
+
+// Chunk is a piece of text synthesized on its own, with the duration of the resulting audio. Chunks are played
+// back to back.
+type Chunk struct {
+	Text     string
+	Duration time.Duration
+}
+
+// token is a word, or a single character of a language written without spaces, with its audio span.
+type token struct {
+	text        string
+	start, end  time.Duration
+	sentenceEnd bool // a cue must not run on past this token
+}
+
+// FromEvents builds cues from word boundary events, including punctuation, with cues broken at the ends of
+// sentences. When events holds sentence boundaries only, the timing of the words within a sentence is estimated.
+// Other events are ignored.
+func FromEvents(events []tts.Event, opts Options) []Cue {
+	var tokens []token
+	var sentences []tts.Event
+	for _, e := range events {
+		switch e.Type {
+		case tts.EventWordBoundary:
+			tokens = append(tokens, token{text: e.Text, start: e.Offset, end: e.Offset + e.Duration})
+		case tts.EventSentenceBoundary:
+			sentences = append(sentences, e)
+		}
+	}
+	if len(tokens) == 0 {
+		for _, s := range sentences {
+			tokens = append(tokens, spread(s.Text, s.Offset, s.Offset+s.Duration)...)
+		}
+		return group(tokens, opts.withDefaults())
+	}
+	for _, s := range sentences {
+		// the last token starting within the sentence ends it.
+		last := -1
+		for i, t := range tokens {
+			if t.start >= s.Offset && t.start < s.Offset+s.Duration {
+				last = i
+			}
+		}
+		if last >= 0 {
+			tokens[last].sentenceEnd = true
+		}
+	}
+	for i := range tokens {
+		if endsSentence(tokens[i].text) {
+			tokens[i].sentenceEnd = true
+		}
+	}
+	return group(tokens, opts.withDefaults())
+}
+
+// Estimate builds cues for chunks of text whose audio durations are known but not the timing of their words. The
+// duration of each chunk is shared among its words in proportion to their width.
+func Estimate(chunks []Chunk, opts Options) []Cue {
+	var tokens []token
+	var offset time.Duration
+	for _, c := range chunks {
+		tokens = append(tokens, spread(c.Text, offset, offset+c.Duration)...)
+		offset += c.Duration
+	}
+	return group(tokens, opts.withDefaults())
+}
+
+// spread splits text into tokens sharing the span from start to end in proportion to their width.
+func spread(text string, start, end time.Duration) []token {
+	words := split(text)
+	total := 0
+	for _, w := range words {
+		total += width(w)
+	}
+	tokens := make([]token, len(words))
+	at := 0
+	for i, w := range words {
+		tokens[i].text = w
+		tokens[i].start = start + (end-start)*time.Duration(at)/time.Duration(total)
+		at += width(w)
+		tokens[i].end = start + (end-start)*time.Duration(at)/time.Duration(total)
+		tokens[i].sentenceEnd = endsSentence(w)
+	}
+	if len(tokens) > 0 {
+		tokens[len(tokens)-1].sentenceEnd = true
+	}
+	return tokens
+}
+
+// split breaks text into words at spaces, and further into single characters for scripts written without
+// spaces. Punctuation stays attached to the word it follows or precedes.
+func split(text string) []string {
+	var words []string
+	for _, field := range strings.Fields(text) {
+		var word []rune
+		for _, r := range field {
+			if len(word) > 0 && (isCJK(r) || isCJK(word[len(word)-1])) && !closing(r) && !opening(word[len(word)-1]) {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+			word = append(word, r)
+		}
+		if len(word) > 0 {
+			words = append(words, string(word))
+		}
+	}
+	return words
+}
+
+// group packs tokens into cues of at most opts.MaxLines lines of opts.MaxLineLength columns, shown for at most
+// opts.MaxDuration and ending at the end of a sentence.
+func group(tokens []token, opts Options) []Cue {
+	var cues []Cue
+	var lines []string
+	var line string
+	var cue *Cue
+	flush := func() {
+		if cue == nil {
+			return
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		cue.Text = strings.Join(lines, "\n")
+		if cue.End <= cue.Start {
+			cue.End = cue.Start + time.Millisecond
+		}
+		cues = append(cues, *cue)
+		cue, lines, line = nil, nil, ""
+	}
+
+	for _, t := range tokens {
+		if cue != nil && t.end-cue.Start > opts.MaxDuration && !attaches(t.text) {
+			flush()
+		}
+		if cue == nil {
+			cue = &Cue{Start: t.start}
+		}
+		next := join(line, t.text)
+		if line != "" && width(next) > opts.MaxLineLength && !attaches(t.text) {
+			if len(lines)+1 >= opts.MaxLines {
+				flush()
+				cue = &Cue{Start: t.start}
+			} else {
+				lines = append(lines, line)
+			}
+			next = t.text
+		}
+		line = next
+		if t.end > cue.End {
+			cue.End = t.end
+		}
+		if t.sentenceEnd {
+			flush()
+		}
+	}
+	flush()
+
+	// a cue must leave the screen before the next appears.
+	for i := 1; i < len(cues); i++ {
+		if cues[i-1].End > cues[i].Start {
+			cues[i-1].End = cues[i].Start
+		}
+	}
+	return cues
+}
+
+// join appends a word to a line, with a space unless either side belongs to a script written without spaces or
+// the word is punctuation that attaches to the one before.
+func join(line, word string) string {
+	if line == "" {
+		return word
+	}
+	last, _ := utf8.DecodeLastRuneInString(line)
+	first, _ := utf8.DecodeRuneInString(word)
+	if isCJK(last) && isCJK(first) || closing(first) || opening(last) {
+		return line + word
+	}
+	return line + " " + word
+}
+
+// attaches reports whether a word is punctuation which must stay with the word before, on the same line and in the
+// same cue.
+func attaches(word string) bool {
+	r, _ := utf8.DecodeRuneInString(word)
+	return closing(r)
+}
+
+// endsSentence reports whether a word ends with sentence final punctuation.
+func endsSentence(word string) bool {
+	word = strings.TrimRightFunc(word, func(r rune) bool { return strings.ContainsRune(`"')]」』）`, r) })
+	r, _ := utf8.DecodeLastRuneInString(word)
+	return strings.ContainsRune(".!?。！？", r)
+}
+
+// closing reports whether r is punctuation that is written straight after the preceding word and must not start a
+// line.
+func closing(r rune) bool {
+	return strings.ContainsRune(`.,!?;:%)]}…、。，．！？；：」』）〕】〉》ー々ぁぃぅぇぉっゃゅょァィゥェォッャュョ`, r)
+}
+
+// opening reports whether r is punctuation that is written straight before the following word and must not end a
+// line.
+func opening(r rune) bool {
+	return strings.ContainsRune(`([{「『（〔【〈《`, r)
+}
+
+// isCJK reports whether r belongs to a script written without spaces between words.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || r >= 0x3000 && r <= 0x303f || r >= 0xff00 && r <= 0xffef
+}
+
+// width returns the number of columns s takes, counting wide characters twice.
+func width(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || r >= 0x3000 && r <= 0x303f || r >= 0xff00 && r <= 0xff60 {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteSRT writes cues as a SubRip file.
+func WriteSRT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for i, c := range cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1, timestamp(c.Start, ','), timestamp(c.End, ','), c.Text)
+	}
+	return bw.Flush()
+}
+
+// WriteVTT writes cues as a WebVTT file.
+func WriteVTT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n\n")
+	for _, c := range cues {
+		fmt.Fprintf(bw, "%s --> %s\n%s\n\n", timestamp(c.Start, '.'), timestamp(c.End, '.'), vttText(c.Text))
+	}
+	return bw.Flush()
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// vttText escapes the text of a cue, which WebVTT parses for entities and tags, and drops its empty lines, which
+// would end the cue.
+func vttText(text string) string {
+	var lines []string
+	for _, line := range strings.Split(vttEscaper.Replace(text), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// timestamp formats d as hours:minutes:seconds with milliseconds after sep.
+func timestamp(d time.Duration, sep byte) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,90 @@
+package subtitle
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/stretchr/testify/assert"
+)
+
+// words returns word boundary events for text, one word every 300 ms.
+func words(offset time.Duration, text ...string) []tts.Event {
+	var events []tts.Event
+	for i, w := range text {
+		boundary := "WordBoundary"
+		if attaches(w) {
+			boundary = "PunctuationBoundary"
+		}
+		events = append(events, tts.Event{Type: tts.EventWordBoundary, Offset: offset + time.Duration(i)*300*time.Millisecond, Duration: 250 * time.Millisecond, Text: w, Boundary: boundary})
+	}
+	return events
+}
+
+func TestFromEvents(t *testing.T) {
+	events := words(0, "Welcome", "to", "the", "course", ".")
+	events = append(events, words(2*time.Second, "Today", "we", "cover", "subtitles", ",", "captions", "and", "timing", ".")...)
+	events = append(events, tts.Event{Type: tts.EventBookmark, Offset: time.Second, Bookmark: "x"})
+
+	cues := FromEvents(events, Options{MaxLineLength: 20})
+	assert.Equal(t, []Cue{
+		{Start: 0, End: 1450 * time.Millisecond, Text: "Welcome to the\ncourse."},
+		{Start: 2 * time.Second, End: 3750 * time.Millisecond, Text: "Today we cover\nsubtitles, captions"},
+		{Start: 3800 * time.Millisecond, End: 4650 * time.Millisecond, Text: "and timing."},
+	}, cues)
+
+	// overlapping cues are shortened.
+	cues = FromEvents(append(words(0, "a."), words(100*time.Millisecond, "b.")...), Options{})
+	assert.Equal(t, 100*time.Millisecond, cues[0].End)
+
+	// a cue is cut when it would stay on screen too long.
+	cues = FromEvents(words(0, "one", "two", "three", "four", "five"), Options{MaxDuration: 700 * time.Millisecond})
+	assert.Equal(t, []string{"one two", "three four", "five"}, texts(cues))
+
+	// sentence boundaries alone spread the sentence over its duration.
+	cues = FromEvents([]tts.Event{{Type: tts.EventSentenceBoundary, Offset: time.Second, Duration: 2 * time.Second, Text: "aaaa bbbb cccc dddd"}}, Options{MaxLineLength: 9, MaxLines: 1})
+	assert.Equal(t, []Cue{
+		{Start: time.Second, End: 2 * time.Second, Text: "aaaa bbbb"},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "cccc dddd"},
+	}, cues)
+}
+
+func texts(cues []Cue) []string {
+	var s []string
+	for _, c := range cues {
+		s = append(s, c.Text)
+	}
+	return s
+}
+
+func TestEstimate(t *testing.T) {
+	cues := Estimate([]Chunk{
+		{Text: "First chunk.", Duration: time.Second},
+		{Text: "今日は良い天気です。明日も晴れるでしょう。", Duration: 4 * time.Second},
+	}, Options{MaxLineLength: 16, MaxLines: 1})
+	assert.Equal(t, []string{"First chunk.", "今日は良い天気で", "す。", "明日も晴れるで", "しょう。"}, texts(cues))
+	assert.Equal(t, time.Second, cues[0].End)
+	assert.Equal(t, time.Second, cues[1].Start)
+	assert.Equal(t, 5*time.Second, cues[4].End)
+}
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []string{"Hello,", "世", "界！", "(「今", "日」)"}, split("Hello, 世界！ (「今日」)"))
+	assert.Equal(t, []string{"안녕하세요", "세계"}, split("안녕하세요 세계"))
+	assert.Equal(t, 4, width("世界"))
+}
+
+func TestWrite(t *testing.T) {
+	cues := []Cue{
+		{Start: 500 * time.Millisecond, End: 2*time.Second + 250*time.Millisecond, Text: "Hello\nworld"},
+		{Start: time.Hour + 61*time.Second, End: time.Hour + 62*time.Second, Text: "a --> b"},
+		{Start: 2 * time.Hour, End: 2*time.Hour + time.Second, Text: "Q&A: x < y"},
+	}
+	var srt, vtt bytes.Buffer
+	assert.NoError(t, WriteSRT(&srt, cues))
+	assert.Equal(t, "1\n00:00:00,500 --> 00:00:02,250\nHello\nworld\n\n2\n01:01:01,000 --> 01:01:02,000\na --> b\n\n3\n02:00:00,000 --> 02:00:01,000\nQ&A: x < y\n\n", srt.String())
+	assert.NoError(t, WriteVTT(&vtt, cues))
+	assert.Equal(t, "WEBVTT\n\n00:00:00.500 --> 00:00:02.250\nHello\nworld\n\n01:01:01.000 --> 01:01:02.000\na --&gt; b\n\n02:00:00.000 --> 02:00:01.000\nQ&amp;A: x &lt; y\n\n", vtt.String())
+	assert.Equal(t, "&lt;b&gt;\nend", vttText("<b>\n\n  \nend"), "empty lines are dropped")
+}
@@ -0,0 +1,314 @@
+package azuretexttospeech
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"time"
+)
+
+// Synthesizer renders SSML documents and lists voices. AzureCSTextToSpeech implements it; depend on it instead to
+// swap providers, add middleware with Wrap or use a fake such as azurettstest.Fake in unit tests.
+type Synthesizer interface {
+	SynthesizeSSMLWithContext(ctx context.Context, ssml string, audioOutput AudioOutput) ([]byte, error)
+	SynthesizeStreamWithContext(ctx context.Context, ssml string, audioOutput AudioOutput) (io.ReadCloser, error)
+	VoicesWithContext(ctx context.Context) ([]Voice, error)
+}
+
+// EventSynthesizer is a Synthesizer also reporting the boundary events of the audio it renders, see
+// AzureCSTextToSpeech.SynthesizeEventsWithContext. The synthesizers returned by middleware implement it by
+// forwarding the call, failing with ErrNoEvents when the synthesizer they wrap does not.
+type EventSynthesizer interface {
+	Synthesizer
+	SynthesizeEventsWithContext(ctx context.Context, ssml string, audioOutput AudioOutput, w io.Writer, onEvent func(Event)) error
+}
+
+// ErrNoEvents is returned by SynthesizeEventsWithContext when the synthesizer called cannot report events. Nothing
+// has been written by then, so callers may fall back to SynthesizeStreamWithContext.
+var ErrNoEvents = errors.New("synthesizer does not report events")
+
+var (
+	_ Synthesizer      = (*AzureCSTextToSpeech)(nil)
+	_ EventSynthesizer = (*AzureCSTextToSpeech)(nil)
+)
+
+// synthesizeEvents calls the SynthesizeEventsWithContext method of s, failing with ErrNoEvents when it has none.
+func synthesizeEvents(ctx context.Context, s Synthesizer, ssml string, audioOutput AudioOutput, w io.Writer, onEvent func(Event)) error {
+	es, ok := s.(EventSynthesizer)
+	if !ok {
+		return ErrNoEvents
+	}
+	return es.SynthesizeEventsWithContext(ctx, ssml, audioOutput, w, onEvent)
+}
+
+// eventOutput passes the audio and events of a SynthesizeEventsWithContext call on, counting them.
+type eventOutput struct {
+	w       io.Writer
+	onEvent func(Event)
+	bytes   int
+	events  int
+}
+
+func (o *eventOutput) Write(p []byte) (int, error) {
+	n, err := o.w.Write(p)
+	o.bytes += n
+	return n, err
+}
+
+func (o *eventOutput) event(e Event) {
+	o.events++
+	o.onEvent(e)
+}
+
+// delivered reports whether anything reached the caller, after which a call cannot be repeated.
+func (o *eventOutput) delivered() bool {
+	return o.bytes > 0 || o.events > 0
+}
+
+// Middleware decorates a Synthesizer.
+type Middleware func(next Synthesizer) Synthesizer
+
+// Wrap decorates s with middleware. The first middleware is the outermost and sees every call first.
+func Wrap(s Synthesizer, middleware ...Middleware) Synthesizer {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		s = middleware[i](s)
+	}
+	return s
+}
+
+// synthesizerFuncs implements EventSynthesizer with functions, falling back to next for those not set.
+type synthesizerFuncs struct {
+	next       Synthesizer
+	synthesize func(ctx context.Context, ssml string, audioOutput AudioOutput) ([]byte, error)
+	stream     func(ctx context.Context, ssml string, audioOutput AudioOutput) (io.ReadCloser, error)
+	events     func(ctx context.Context, ssml string, audioOutput AudioOutput, w io.Writer, onEvent func(Event)) error
+	voices     func(ctx context.Context) ([]Voice, error)
+}
+
+func (s *synthesizerFuncs) SynthesizeSSMLWithContext(ctx context.Context, ssml string, audioOutput AudioOutput) ([]byte, error) {
+	if s.synthesize == nil {
+		return s.next.SynthesizeSSMLWithContext(ctx, ssml, audioOutput)
+	}
+	return s.synthesize(ctx, ssml, audioOutput)
+}
+
+func (s *synthesizerFuncs) SynthesizeStreamWithContext(ctx context.Context, ssml string, audioOutput AudioOutput) (io.ReadCloser, error) {
+	if s.stream == nil {
+		return s.next.SynthesizeStreamWithContext(ctx, ssml, audioOutput)
+	}
+	return s.stream(ctx, ssml, audioOutput)
+}
+
+func (s *synthesizerFuncs) SynthesizeEventsWithContext(ctx context.Context, ssml string, audioOutput AudioOutput, w io.Writer, onEvent func(Event)) error {
+	if s.events == nil {
+		return synthesizeEvents(ctx, s.next, ssml, audioOutput, w, onEvent)
+	}
+	return s.events(ctx, ssml, audioOutput, w, onEvent)
+}
+
+func (s *synthesizerFuncs) VoicesWithContext(ctx context.Context) ([]Voice, error) {
+	if s.voices == nil {
+		return s.next.VoicesWithContext(ctx)
+	}
+	return s.voices(ctx)
+}
+
+// CacheMiddleware serves rendered audio from c, storing results for ttl, keyed by CacheKey. Streams are served from
+// the cache when present but are not stored, and calls reporting events always reach next. Unlike the Cache field of
+// the client, concurrent identical requests are not collapsed.
+func CacheMiddleware(c Cache, ttl time.Duration) Middleware {
+	return func(next Synthesizer) Synthesizer {
+		return &synthesizerFuncs{
+			next: next,
+			synthesize: func(ctx context.Context, ssml string, audioOutput AudioOutput) ([]byte, error) {
+				key := CacheKey(ssml, "", audioOutput)
+				if b, ok := c.Get(key); ok {
+					return b, nil
+				}
+				b, err := next.SynthesizeSSMLWithContext(ctx, ssml, audioOutput)
+				if err != nil {
+					return nil, err
+				}
+				if err := c.Set(key, b, ttl); err != nil {
+					log.Printf("failed to store synthesis result in cache, %v", err)
+				}
+				return b, nil
+			},
+			stream: func(ctx context.Context, ssml string, audioOutput AudioOutput) (io.ReadCloser, error) {
+				if b, ok := c.Get(CacheKey(ssml, "", audioOutput)); ok {
+					return ioutil.NopCloser(bytes.NewReader(b)), nil
+				}
+				return next.SynthesizeStreamWithContext(ctx, ssml, audioOutput)
+			},
+		}
+	}
+}
+
+// RetryMiddleware retries calls failing with a temporary error, such as a 429 or 5xx status or a network timeout,
+// up to attempts calls in total. The wait starts at backoff and doubles after every attempt; ctx ends it early.
+// Calls reporting events are only retried until audio or an event was passed on.
+func RetryMiddleware(attempts int, backoff time.Duration) Middleware {
+	retry := func(ctx context.Context, retryable func(error) bool, call func() error) error {
+		wait := backoff
+		for i := 1; ; i++ {
+			err := call()
+			if err == nil || i >= attempts || !retryable(err) {
+				return err
+			}
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return err
+			}
+			wait *= 2
+		}
+	}
+	return func(next Synthesizer) Synthesizer {
+		return &synthesizerFuncs{
+			next: next,
+			synthesize: func(ctx context.Context, ssml string, audioOutput AudioOutput) (b []byte, err error) {
+				err = retry(ctx, Temporary, func() error {
+					b, err = next.SynthesizeSSMLWithContext(ctx, ssml, audioOutput)
+					return err
+				})
+				return b, err
+			},
+			stream: func(ctx context.Context, ssml string, audioOutput AudioOutput) (r io.ReadCloser, err error) {
+				err = retry(ctx, Temporary, func() error {
+					r, err = next.SynthesizeStreamWithContext(ctx, ssml, audioOutput)
+					return err
+				})
+				return r, err
+			},
+			events: func(ctx context.Context, ssml string, audioOutput AudioOutput, w io.Writer, onEvent func(Event)) error {
+				out := &eventOutput{w: w, onEvent: onEvent}
+				retryable := func(err error) bool { return !out.delivered() && Temporary(err) }
+				return retry(ctx, retryable, func() error {
+					return synthesizeEvents(ctx, next, ssml, audioOutput, out, out.event)
+				})
+			},
+			voices: func(ctx context.Context) (v []Voice, err error) {
+				err = retry(ctx, Temporary, func() error {
+					v, err = next.VoicesWithContext(ctx)
+					return err
+				})
+				return v, err
+			},
+		}
+	}
+}
+
+// Temporary reports whether a call failing with err may succeed when retried.
+func Temporary(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Temporary()
+	}
+	var closed *CloseError
+	if errors.As(err, &closed) {
+		return closed.Temporary()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Call describes a completed Synthesizer call, see MetricsMiddleware.
+type Call struct {
+	Method      string        // SynthesizeSSML, SynthesizeStream, SynthesizeEvents or Voices
+	AudioOutput AudioOutput   // not set for Voices
+	Duration    time.Duration // until the stream was closed for SynthesizeStream
+	Bytes       int           // audio returned, read from the stream or written
+	Err         error
+}
+
+// MetricsMiddleware calls observe with every completed call, for example to update request counters and latency
+// histograms.
+func MetricsMiddleware(observe func(Call)) Middleware {
+	return func(next Synthesizer) Synthesizer {
+		return &synthesizerFuncs{
+			next: next,
+			synthesize: func(ctx context.Context, ssml string, audioOutput AudioOutput) ([]byte, error) {
+				start := time.Now()
+				b, err := next.SynthesizeSSMLWithContext(ctx, ssml, audioOutput)
+				observe(Call{Method: "SynthesizeSSML", AudioOutput: audioOutput, Duration: time.Since(start), Bytes: len(b), Err: err})
+				return b, err
+			},
+			stream: func(ctx context.Context, ssml string, audioOutput AudioOutput) (io.ReadCloser, error) {
+				start := time.Now()
+				r, err := next.SynthesizeStreamWithContext(ctx, ssml, audioOutput)
+				if err != nil {
+					observe(Call{Method: "SynthesizeStream", AudioOutput: audioOutput, Duration: time.Since(start), Err: err})
+					return nil, err
+				}
+				return &observedStream{ReadCloser: r, done: func(n int, err error) {
+					observe(Call{Method: "SynthesizeStream", AudioOutput: audioOutput, Duration: time.Since(start), Bytes: n, Err: err})
+				}}, nil
+			},
+			events: func(ctx context.Context, ssml string, audioOutput AudioOutput, w io.Writer, onEvent func(Event)) error {
+				start := time.Now()
+				out := &eventOutput{w: w, onEvent: onEvent}
+				err := synthesizeEvents(ctx, next, ssml, audioOutput, out, out.event)
+				observe(Call{Method: "SynthesizeEvents", AudioOutput: audioOutput, Duration: time.Since(start), Bytes: out.bytes, Err: err})
+				return err
+			},
+			voices: func(ctx context.Context) ([]Voice, error) {
+				start := time.Now()
+				v, err := next.VoicesWithContext(ctx)
+				observe(Call{Method: "Voices", Duration: time.Since(start), Err: err})
+				return v, err
+			},
+		}
+	}
+}
+
+// observedStream counts the bytes read from a stream and reports them, with the first read error other than
+// io.EOF, when it is closed.
+type observedStream struct {
+	io.ReadCloser
+	n    int
+	err  error
+	done func(n int, err error)
+}
+
+func (s *observedStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	s.n += n
+	if err != nil && err != io.EOF && s.err == nil {
+		s.err = err
+	}
+	return n, err
+}
+
+func (s *observedStream) Close() error {
+	err := s.ReadCloser.Close()
+	if s.done != nil {
+		s.done(s.n, s.err)
+		s.done = nil
+	}
+	return err
+}
+
+// LogMiddleware logs every call to l, or the standard logger when l is nil.
+func LogMiddleware(l *log.Logger) Middleware {
+	printf := log.Printf
+	if l != nil {
+		printf = l.Printf
+	}
+	return MetricsMiddleware(func(c Call) {
+		call := c.Method
+		if c.Method != "Voices" {
+			call += " " + c.AudioOutput.String()
+		}
+		if c.Err != nil {
+			printf("%s failed after %s, %v", call, c.Duration, c.Err)
+			return
+		}
+		printf("%s returned %d bytes in %s", call, c.Bytes, c.Duration)
+	})
+}
@@ -0,0 +1,203 @@
+package azuretexttospeech
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSynthesizer answers with the SSML it was given, failing with the queued errors first.
+type stubSynthesizer struct {
+	errs    []error
+	calls   int
+	formats []AudioOutput // requested
+}
+
+func (s *stubSynthesizer) next() error {
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func (s *stubSynthesizer) SynthesizeSSMLWithContext(ctx context.Context, ssml string, audioOutput AudioOutput) ([]byte, error) {
+	s.formats = append(s.formats, audioOutput)
+	if err := s.next(); err != nil {
+		return nil, err
+	}
+	return []byte(ssml), nil
+}
+
+func (s *stubSynthesizer) SynthesizeStreamWithContext(ctx context.Context, ssml string, audioOutput AudioOutput) (io.ReadCloser, error) {
+	s.formats = append(s.formats, audioOutput)
+	if err := s.next(); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(strings.NewReader(ssml)), nil
+}
+
+func (s *stubSynthesizer) VoicesWithContext(ctx context.Context) ([]Voice, error) {
+	if err := s.next(); err != nil {
+		return nil, err
+	}
+	return []Voice{{ShortName: "en-US-JennyNeural"}}, nil
+}
+
+// eventStub is a stubSynthesizer reporting a word boundary after the audio of every events call. When partial, the
+// queued errors are returned after the audio was written.
+type eventStub struct {
+	stubSynthesizer
+	partial bool
+}
+
+func (s *eventStub) SynthesizeEventsWithContext(ctx context.Context, ssml string, audioOutput AudioOutput, w io.Writer, onEvent func(Event)) error {
+	s.formats = append(s.formats, audioOutput)
+	if s.partial {
+		io.WriteString(w, ssml)
+		return s.next()
+	}
+	if err := s.next(); err != nil {
+		return err
+	}
+	io.WriteString(w, ssml)
+	onEvent(Event{Type: EventWordBoundary, Text: "hello"})
+	return nil
+}
+
+func TestEventMiddleware(t *testing.T) {
+	ctx := context.Background()
+	var calls []Call
+	cache := NewMemoryCache(1 << 20)
+	cache.Set(CacheKey("<speak>hello</speak>", "", RAW16khz16bitMonoPCM), []byte("cached"), 0)
+	stub := &eventStub{stubSynthesizer: stubSynthesizer{errs: []error{synthesizeError(http.StatusServiceUnavailable)}}}
+	s := Wrap(stub,
+		MetricsMiddleware(func(c Call) { calls = append(calls, c) }),
+		CacheMiddleware(cache, 0),
+		RetryMiddleware(3, time.Millisecond)).(EventSynthesizer)
+
+	var audio bytes.Buffer
+	var events []Event
+	err := s.SynthesizeEventsWithContext(ctx, "<speak>hello</speak>", RAW16khz16bitMonoPCM, &audio, func(e Event) { events = append(events, e) })
+	assert.NoError(t, err)
+	assert.Equal(t, "<speak>hello</speak>", audio.String(), "not served from the cache")
+	assert.Equal(t, []Event{{Type: EventWordBoundary, Text: "hello"}}, events)
+	assert.Equal(t, 2, stub.calls, "retried")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, Call{Method: "SynthesizeEvents", AudioOutput: RAW16khz16bitMonoPCM, Duration: calls[0].Duration, Bytes: 20}, calls[0])
+	}
+
+	stub = &eventStub{stubSynthesizer: stubSynthesizer{errs: []error{synthesizeError(http.StatusServiceUnavailable)}}, partial: true}
+	audio.Reset()
+	err = Wrap(stub, RetryMiddleware(3, time.Millisecond)).(EventSynthesizer).SynthesizeEventsWithContext(ctx, "<speak/>", RAW16khz16bitMonoPCM, &audio, func(Event) {})
+	assert.Error(t, err)
+	assert.Equal(t, 1, stub.calls, "not retried once audio was written")
+	assert.Equal(t, "<speak/>", audio.String())
+
+	err = Wrap(&stubSynthesizer{}, RetryMiddleware(3, time.Millisecond)).(EventSynthesizer).SynthesizeEventsWithContext(ctx, "<speak/>", RAW16khz16bitMonoPCM, &audio, func(Event) {})
+	assert.Equal(t, ErrNoEvents, err)
+}
+
+func TestWrap(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Synthesizer) Synthesizer {
+			return &synthesizerFuncs{next: next, voices: func(ctx context.Context) ([]Voice, error) {
+				order = append(order, name)
+				return next.VoicesWithContext(ctx)
+			}}
+		}
+	}
+	s := Wrap(&stubSynthesizer{}, trace("outer"), trace("inner"))
+	_, err := s.VoicesWithContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"outer", "inner"}, order)
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	stub := &stubSynthesizer{}
+	s := Wrap(stub, CacheMiddleware(NewMemoryCache(1<<20), 0))
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		b, err := s.SynthesizeSSMLWithContext(ctx, "<speak>hello</speak>", RIFF16khz16bitMonoPCM)
+		assert.NoError(t, err)
+		assert.Equal(t, "<speak>hello</speak>", string(b))
+	}
+	assert.Equal(t, 1, stub.calls)
+
+	r, err := s.SynthesizeStreamWithContext(ctx, "\n<speak>hello</speak>\n", RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(r)
+	assert.Equal(t, "<speak>hello</speak>", string(b), "served from the cache")
+	assert.Equal(t, 1, stub.calls)
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	ctx := context.Background()
+	stub := &stubSynthesizer{errs: []error{synthesizeError(http.StatusTooManyRequests), synthesizeError(http.StatusServiceUnavailable)}}
+	s := Wrap(stub, RetryMiddleware(3, time.Millisecond))
+	_, err := s.SynthesizeSSMLWithContext(ctx, "<speak/>", RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stub.calls)
+
+	stub = &stubSynthesizer{errs: []error{synthesizeError(http.StatusInternalServerError), synthesizeError(http.StatusInternalServerError)}}
+	_, err = Wrap(stub, RetryMiddleware(2, time.Millisecond)).VoicesWithContext(ctx)
+	assert.Error(t, err, "out of attempts")
+	assert.Equal(t, 2, stub.calls)
+
+	stub = &stubSynthesizer{errs: []error{fmt.Errorf("wrapped, %w", synthesizeError(http.StatusUnauthorized))}}
+	_, err = Wrap(stub, RetryMiddleware(3, time.Millisecond)).SynthesizeStreamWithContext(ctx, "<speak/>", RIFF16khz16bitMonoPCM)
+	assert.Error(t, err)
+	assert.Equal(t, 1, stub.calls, "a 401 is not retried")
+
+	assert.True(t, Temporary(synthesizeError(http.StatusBadGateway)))
+	assert.False(t, Temporary(synthesizeError(http.StatusBadRequest)))
+	assert.False(t, Temporary(errors.New("boom")))
+	assert.Equal(t, "429 - You have exceeded the quota or rate of requests allowed for your subscription", synthesizeError(http.StatusTooManyRequests).Error())
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	var calls []Call
+	var logged bytes.Buffer
+	s := Wrap(&stubSynthesizer{errs: []error{nil, nil, synthesizeError(http.StatusBadGateway)}},
+		LogMiddleware(log.New(&logged, "", 0)),
+		MetricsMiddleware(func(c Call) { calls = append(calls, c) }))
+	ctx := context.Background()
+
+	_, err := s.SynthesizeSSMLWithContext(ctx, "<speak/>", RIFF16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	r, err := s.SynthesizeStreamWithContext(ctx, "<speak>stream</speak>", RAW16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	ioutil.ReadAll(r)
+	assert.Len(t, calls, 1, "a stream is reported once closed")
+	r.Close()
+	_, err = s.VoicesWithContext(ctx)
+	assert.Error(t, err)
+
+	if assert.Len(t, calls, 3) {
+		assert.Equal(t, "SynthesizeSSML", calls[0].Method)
+		assert.Equal(t, 8, calls[0].Bytes)
+		assert.Equal(t, "SynthesizeStream", calls[1].Method)
+		assert.Equal(t, RAW16khz16bitMonoPCM, calls[1].AudioOutput)
+		assert.Equal(t, 21, calls[1].Bytes)
+		assert.Equal(t, "Voices", calls[2].Method)
+		assert.Error(t, calls[2].Err)
+	}
+	lines := strings.Split(strings.TrimSpace(logged.String()), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.True(t, strings.HasPrefix(lines[0], "SynthesizeSSML riff-16khz-16bit-mono-pcm returned 8 bytes"), lines[0])
+		assert.True(t, strings.HasPrefix(lines[2], "Voices failed after"), lines[2])
+	}
+}
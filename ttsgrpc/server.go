@@ -0,0 +1,280 @@
+// Package ttsgrpc serves a tts.Synthesizer over gRPC, see the TextToSpeech service of ttspb/tts.proto:
+//
+//	Synthesize        the complete audio of a request
+//	SynthesizeStream  the audio as it renders, interleaved with its boundary events
+//	ListVoices        the voice list, optionally filtered by locale
+//
+// The deadline of a call bounds its request to the service, and failures are answered with the closest status
+// code: InvalidArgument for requests the service rejected, ResourceExhausted while it throttles, Unavailable for
+// other temporary failures and DeadlineExceeded or Canceled when the call ends first. Register a Server on a
+// grpc.Server with ttspb.RegisterTextToSpeechServer.
+package ttsgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/ttsgrpc/ttspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// DefaultTimeout bounds the calls made without a deadline when Config.Timeout is zero.
+const DefaultTimeout = time.Minute
+
+// Config configures a Server.
+type Config struct {
+	// Synthesizer renders the requests, usually a client decorated with tts.Wrap. SynthesizeStream reports events
+	// when it implements tts.EventSynthesizer, as the client and the synthesizers returned by middleware do, and
+	// only sends audio otherwise.
+	Synthesizer tts.Synthesizer
+	// DefaultVoice speaks text requests naming no voice, tts.DefaultVoice when empty.
+	DefaultVoice string
+	// DefaultFormat is the format of requests naming none, a constant name or X-Microsoft-OutputFormat value;
+	// tts.DefaultAudioOutput when empty.
+	DefaultFormat string
+	// Timeout bounds the calls made without a deadline, DefaultTimeout when zero.
+	Timeout time.Duration
+}
+
+// Server implements ttspb.TextToSpeechServer.
+type Server struct {
+	ttspb.UnimplementedTextToSpeechServer
+	config Config
+	format tts.AudioOutput // default
+}
+
+var _ ttspb.TextToSpeechServer = (*Server)(nil)
+
+// New returns a server for c. c.Synthesizer must be set.
+func New(c Config) (*Server, error) {
+	if c.Synthesizer == nil {
+		return nil, fmt.Errorf("no synthesizer configured")
+	}
+	format := tts.DefaultAudioOutput
+	if c.DefaultFormat != "" {
+		var err error
+		if format, err = tts.AudioOutputString(c.DefaultFormat); err != nil {
+			return nil, fmt.Errorf("invalid default format, %v", err)
+		}
+	}
+	if c.DefaultVoice == "" {
+		c.DefaultVoice = tts.DefaultVoice
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	return &Server{config: c, format: format}, nil
+}
+
+// Synthesize implements ttspb.TextToSpeechServer.
+func (s *Server) Synthesize(ctx context.Context, req *ttspb.SynthesizeRequest) (*ttspb.SynthesizeResponse, error) {
+	ssml, format, err := s.parse(req)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := s.withDeadline(ctx)
+	defer cancel()
+	audio, err := s.config.Synthesizer.SynthesizeSSMLWithContext(ctx, ssml, format)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return &ttspb.SynthesizeResponse{Audio: audio, ContentType: format.Info().MIMEType}, nil
+}
+
+// SynthesizeStream implements ttspb.TextToSpeechServer. Audio is sent in the pieces the service renders it in,
+// events as soon as they are reported. Synthesizers unable to report events only send audio.
+func (s *Server) SynthesizeStream(req *ttspb.SynthesizeRequest, stream ttspb.TextToSpeech_SynthesizeStreamServer) error {
+	ssml, format, err := s.parse(req)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.withDeadline(stream.Context())
+	defer cancel()
+
+	// events are reported from the goroutine writing the audio, so sends are never concurrent.
+	var sendErr error
+	send := func(res *ttspb.SynthesizeStreamResponse) error {
+		if sendErr == nil {
+			if sendErr = stream.Send(res); sendErr != nil {
+				cancel()
+			}
+		}
+		return sendErr
+	}
+	w := writerFunc(func(p []byte) (int, error) {
+		chunk := make([]byte, len(p))
+		copy(chunk, p)
+		if err := send(&ttspb.SynthesizeStreamResponse{Chunk: &ttspb.SynthesizeStreamResponse_Audio{Audio: chunk}}); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	})
+	err = tts.ErrNoEvents
+	if es, ok := s.config.Synthesizer.(tts.EventSynthesizer); ok {
+		err = es.SynthesizeEventsWithContext(ctx, ssml, format, w, func(e tts.Event) {
+			send(&ttspb.SynthesizeStreamResponse{Chunk: &ttspb.SynthesizeStreamResponse_Event{Event: event(e)}})
+		})
+	}
+	if errors.Is(err, tts.ErrNoEvents) {
+		err = s.stream(ctx, ssml, format, w)
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		return statusError(ctx, err)
+	}
+	return nil
+}
+
+// stream copies the audio of ssml to w, without events.
+func (s *Server) stream(ctx context.Context, ssml string, format tts.AudioOutput, w io.Writer) error {
+	r, err := s.config.Synthesizer.SynthesizeStreamWithContext(ctx, ssml, format)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// ListVoices implements ttspb.TextToSpeechServer.
+func (s *Server) ListVoices(ctx context.Context, req *ttspb.ListVoicesRequest) (*ttspb.ListVoicesResponse, error) {
+	ctx, cancel := s.withDeadline(ctx)
+	defer cancel()
+	voices, err := s.config.Synthesizer.VoicesWithContext(ctx)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	res := &ttspb.ListVoicesResponse{}
+	for _, v := range voices {
+		if req.Locale == "" || strings.EqualFold(v.Locale, req.Locale) {
+			res.Voices = append(res.Voices, voice(v))
+		}
+	}
+	return res, nil
+}
+
+// withDeadline returns ctx bounded by Config.Timeout when the caller set no deadline.
+func (s *Server) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.config.Timeout)
+}
+
+// parse returns the SSML and output format of a synthesis request.
+func (s *Server) parse(req *ttspb.SynthesizeRequest) (string, tts.AudioOutput, error) {
+	format := s.format
+	if req.Format != "" {
+		var err error
+		if format, err = tts.AudioOutputString(req.Format); err != nil {
+			return "", 0, status.Errorf(codes.InvalidArgument, "unsupported format %q", req.Format)
+		}
+	}
+	switch input := req.Input.(type) {
+	case *ttspb.SynthesizeRequest_Ssml:
+		if strings.TrimSpace(input.Ssml) != "" {
+			return input.Ssml, format, nil
+		}
+	case *ttspb.SynthesizeRequest_Text:
+		if strings.TrimSpace(input.Text) != "" {
+			voice := firstOf(req.Voice, s.config.DefaultVoice)
+			return tts.VoiceSSML(input.Text, voice, firstOf(req.Pitch, "0%"), firstOf(req.Rate, "0%")), format, nil
+		}
+	}
+	return "", 0, status.Error(codes.InvalidArgument, "nothing to synthesize")
+}
+
+// statusError maps an error of the client to the status of the call made with ctx.
+func statusError(ctx context.Context, err error) error {
+	var statusErr *tts.StatusError
+	var closed *tts.CloseError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled:
+		return status.Error(codes.Canceled, err.Error())
+	case errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusBadRequest ||
+		statusErr.StatusCode == http.StatusRequestEntityTooLarge || statusErr.StatusCode == http.StatusUnsupportedMediaType):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.As(err, &closed) && closed.Code == websocket.CloseInvalidFramePayloadData:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case tts.Temporary(err):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.As(err, &statusErr):
+		// the server's own credentials or a response it does not understand, not the caller's problem.
+		return status.Error(codes.Internal, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+var eventTypes = map[tts.EventType]ttspb.Event_Type{
+	tts.EventWordBoundary:     ttspb.Event_WORD_BOUNDARY,
+	tts.EventSentenceBoundary: ttspb.Event_SENTENCE_BOUNDARY,
+	tts.EventBookmark:         ttspb.Event_BOOKMARK,
+	tts.EventViseme:           ttspb.Event_VISEME,
+}
+
+func event(e tts.Event) *ttspb.Event {
+	out := &ttspb.Event{
+		Type:      eventTypes[e.Type],
+		Offset:    durationpb.New(e.Offset),
+		Text:      e.Text,
+		Boundary:  e.Boundary,
+		Bookmark:  e.Bookmark,
+		VisemeId:  int32(e.VisemeID),
+		Animation: e.Animation,
+	}
+	if e.Duration != 0 {
+		out.Duration = durationpb.New(e.Duration)
+	}
+	return out
+}
+
+func voice(v tts.Voice) *ttspb.Voice {
+	rate, _ := strconv.Atoi(v.SampleRateHertz)
+	wpm, _ := strconv.Atoi(v.WordsPerMinute)
+	return &ttspb.Voice{
+		Name:             v.Name,
+		ShortName:        v.ShortName,
+		DisplayName:      v.DisplayName,
+		LocalName:        v.LocalName,
+		Gender:           v.Gender,
+		Locale:           v.Locale,
+		SecondaryLocales: v.SecondaryLocaleList,
+		Styles:           v.StyleList,
+		Roles:            v.RolePlayList,
+		Status:           v.Status,
+		SampleRateHertz:  int32(rate),
+		VoiceType:        v.VoiceType,
+		WordsPerMinute:   int32(wpm),
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func firstOf(v ...string) string {
+	for _, s := range v {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,224 @@
+package ttsgrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/linexjlin/azuretexttospeech/audio"
+	"github.com/linexjlin/azuretexttospeech/azurettstest"
+	"github.com/linexjlin/azuretexttospeech/ttsgrpc/ttspb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the client of a fake service over an in-memory connection.
+func newTestClient(t *testing.T) (ttspb.TextToSpeechClient, *azurettstest.Server, func()) {
+	fake := azurettstest.NewServer()
+	az, err := fake.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, stop := serve(t, az)
+	return client, fake, func() {
+		stop()
+		close(az.TokenRefreshDoneCh)
+		fake.Close()
+	}
+}
+
+// serve serves s over an in-memory connection.
+func serve(t *testing.T, synthesizer tts.Synthesizer) (ttspb.TextToSpeechClient, func()) {
+	s, err := New(Config{Synthesizer: synthesizer, DefaultVoice: "en-US-JennyNeural"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	ttspb.RegisterTextToSpeechServer(g, s)
+	go g.Serve(listener)
+
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ttspb.NewTextToSpeechClient(conn), func() {
+		conn.Close()
+		g.Stop()
+	}
+}
+
+// receive reads a stream to its end, returning the audio and events received.
+func receive(t *testing.T, stream ttspb.TextToSpeech_SynthesizeStreamClient) ([]byte, []*ttspb.Event, error) {
+	var audio []byte
+	var events []*ttspb.Event
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return audio, events, nil
+		}
+		if err != nil {
+			return audio, events, err
+		}
+		switch chunk := res.Chunk.(type) {
+		case *ttspb.SynthesizeStreamResponse_Audio:
+			audio = append(audio, chunk.Audio...)
+		case *ttspb.SynthesizeStreamResponse_Event:
+			events = append(events, chunk.Event)
+		}
+	}
+}
+
+func TestSynthesize(t *testing.T) {
+	client, fake, done := newTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	res, err := client.Synthesize(ctx, &ttspb.SynthesizeRequest{Input: &ttspb.SynthesizeRequest_Text{Text: "Hello world"}, Format: "riff-16khz-16bit-mono-pcm"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "audio/wav", res.ContentType)
+		d, err := audio.Duration(res.Audio, tts.RIFF16khz16bitMonoPCM)
+		assert.NoError(t, err)
+		assert.Equal(t, azurettstest.Duration("Hello world"), d)
+	}
+	if requests := fake.Requests(); assert.Len(t, requests, 1) {
+		assert.Contains(t, requests[0].SSML, "en-US-JennyNeural")
+	}
+
+	for _, c := range []struct {
+		req  *ttspb.SynthesizeRequest
+		code codes.Code
+	}{
+		{&ttspb.SynthesizeRequest{}, codes.InvalidArgument},
+		{&ttspb.SynthesizeRequest{Input: &ttspb.SynthesizeRequest_Text{Text: "Hi"}, Format: "wav"}, codes.InvalidArgument},
+		{&ttspb.SynthesizeRequest{Input: &ttspb.SynthesizeRequest_Ssml{Ssml: "<speak>Hi</speak>"}}, codes.InvalidArgument},
+	} {
+		_, err := client.Synthesize(ctx, c.req)
+		assert.Equal(t, c.code, status.Code(err), err)
+	}
+
+	fake.Inject(azurettstest.Fault{Path: azurettstest.SynthesisPath, Status: http.StatusTooManyRequests, Count: 1})
+	_, err = client.Synthesize(ctx, &ttspb.SynthesizeRequest{Input: &ttspb.SynthesizeRequest_Text{Text: "Throttled"}})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), err)
+
+	fake.Inject(azurettstest.Fault{Path: azurettstest.SynthesisPath, Delay: time.Second, Count: 1})
+	short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = client.Synthesize(short, &ttspb.SynthesizeRequest{Input: &ttspb.SynthesizeRequest_Text{Text: "Slow"}})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err), err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second), "the deadline reaches the request to the service")
+}
+
+func TestSynthesizeStream(t *testing.T) {
+	client, fake, done := newTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	ssml := `<speak version="1.0" xml:lang="en-US"><voice name="en-US-GuyNeural">Hello world. <bookmark mark="next"/>How are you?</voice></speak>`
+	stream, err := client.SynthesizeStream(ctx, &ttspb.SynthesizeRequest{Input: &ttspb.SynthesizeRequest_Ssml{Ssml: ssml}, Format: "raw-16khz-16bit-mono-pcm"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	pcm, events, err := receive(t, stream)
+	if !assert.NoError(t, err) {
+		return
+	}
+	d, err := audio.Duration(pcm, tts.RAW16khz16bitMonoPCM)
+	assert.NoError(t, err)
+	assert.Equal(t, azurettstest.Duration("Hello world. How are you?"), d)
+	if requests := fake.Requests(); assert.Len(t, requests, 1) {
+		assert.Equal(t, azurettstest.WebSocketPath, requests[0].Path)
+	}
+
+	var words, bookmarks int
+	for _, e := range events {
+		switch e.Type {
+		case ttspb.Event_WORD_BOUNDARY:
+			words++
+			assert.NotEmpty(t, e.Text)
+			assert.NotNil(t, e.Duration)
+		case ttspb.Event_BOOKMARK:
+			bookmarks++
+			assert.Equal(t, "next", e.Bookmark)
+			assert.Equal(t, azurettstest.Duration("Hello world. "), e.Offset.AsDuration())
+		}
+	}
+	assert.Equal(t, 5, words)
+	assert.Equal(t, 1, bookmarks)
+
+	stream, err = client.SynthesizeStream(ctx, &ttspb.SynthesizeRequest{Input: &ttspb.SynthesizeRequest_Text{Text: "Hi"}, Voice: "xx-XX-NobodyNeural"})
+	if assert.NoError(t, err) {
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), err)
+	}
+}
+
+func TestSynthesizeStreamMiddleware(t *testing.T) {
+	ctx := context.Background()
+	req := &ttspb.SynthesizeRequest{Input: &ttspb.SynthesizeRequest_Text{Text: "Hello world"}, Format: "raw-16khz-16bit-mono-pcm"}
+	want := azurettstest.Duration("Hello world")
+
+	fake := azurettstest.NewFake()
+	fake.Errors = []error{&tts.StatusError{StatusCode: http.StatusServiceUnavailable}}
+	var calls []tts.Call
+	client, stop := serve(t, tts.Wrap(fake,
+		tts.MetricsMiddleware(func(c tts.Call) { calls = append(calls, c) }),
+		tts.RetryMiddleware(2, time.Millisecond)))
+	defer stop()
+	stream, err := client.SynthesizeStream(ctx, req)
+	if assert.NoError(t, err) {
+		pcm, events, err := receive(t, stream)
+		assert.NoError(t, err)
+		d, _ := audio.Duration(pcm, tts.RAW16khz16bitMonoPCM)
+		assert.Equal(t, want, d)
+		assert.NotEmpty(t, events, "events pass through the middleware")
+	}
+	assert.Empty(t, fake.Errors, "retried")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "SynthesizeEvents", calls[0].Method)
+	}
+
+	// a synthesizer reporting no events still streams audio.
+	client, stop = serve(t, tts.Wrap(struct{ tts.Synthesizer }{azurettstest.NewFake()}, tts.RetryMiddleware(2, time.Millisecond)))
+	defer stop()
+	stream, err = client.SynthesizeStream(ctx, req)
+	if assert.NoError(t, err) {
+		pcm, events, err := receive(t, stream)
+		assert.NoError(t, err)
+		d, _ := audio.Duration(pcm, tts.RAW16khz16bitMonoPCM)
+		assert.Equal(t, want, d)
+		assert.Empty(t, events)
+	}
+}
+
+func TestListVoices(t *testing.T) {
+	client, fake, done := newTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	res, err := client.ListVoices(ctx, &ttspb.ListVoicesRequest{})
+	if assert.NoError(t, err) {
+		assert.Len(t, res.Voices, len(azurettstest.DefaultVoices()))
+	}
+	res, err = client.ListVoices(ctx, &ttspb.ListVoicesRequest{Locale: "en-us"})
+	if assert.NoError(t, err) && assert.Len(t, res.Voices, 2) {
+		v := res.Voices[0]
+		assert.Equal(t, "en-US", v.Locale)
+		assert.NotEmpty(t, v.ShortName)
+		assert.NotZero(t, v.SampleRateHertz)
+	}
+
+	fake.Inject(azurettstest.Fault{Path: azurettstest.VoicesPath, Status: http.StatusServiceUnavailable, Count: 1})
+	_, err = client.ListVoices(ctx, &ttspb.ListVoicesRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err), err)
+}
@@ -0,0 +1,209 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package ttspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// TextToSpeechClient is the client API for TextToSpeech service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TextToSpeechClient interface {
+	// Synthesize returns the complete audio of a request.
+	Synthesize(ctx context.Context, in *SynthesizeRequest, opts ...grpc.CallOption) (*SynthesizeResponse, error)
+	// SynthesizeStream streams the audio of a request as it renders, interleaved with the word boundaries, sentence
+	// boundaries, bookmarks and visemes reported for it.
+	SynthesizeStream(ctx context.Context, in *SynthesizeRequest, opts ...grpc.CallOption) (TextToSpeech_SynthesizeStreamClient, error)
+	// ListVoices lists the voices of the service.
+	ListVoices(ctx context.Context, in *ListVoicesRequest, opts ...grpc.CallOption) (*ListVoicesResponse, error)
+}
+
+type textToSpeechClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTextToSpeechClient(cc grpc.ClientConnInterface) TextToSpeechClient {
+	return &textToSpeechClient{cc}
+}
+
+func (c *textToSpeechClient) Synthesize(ctx context.Context, in *SynthesizeRequest, opts ...grpc.CallOption) (*SynthesizeResponse, error) {
+	out := new(SynthesizeResponse)
+	err := c.cc.Invoke(ctx, "/azuretts.v1.TextToSpeech/Synthesize", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *textToSpeechClient) SynthesizeStream(ctx context.Context, in *SynthesizeRequest, opts ...grpc.CallOption) (TextToSpeech_SynthesizeStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TextToSpeech_ServiceDesc.Streams[0], "/azuretts.v1.TextToSpeech/SynthesizeStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &textToSpeechSynthesizeStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TextToSpeech_SynthesizeStreamClient interface {
+	Recv() (*SynthesizeStreamResponse, error)
+	grpc.ClientStream
+}
+
+type textToSpeechSynthesizeStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *textToSpeechSynthesizeStreamClient) Recv() (*SynthesizeStreamResponse, error) {
+	m := new(SynthesizeStreamResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *textToSpeechClient) ListVoices(ctx context.Context, in *ListVoicesRequest, opts ...grpc.CallOption) (*ListVoicesResponse, error) {
+	out := new(ListVoicesResponse)
+	err := c.cc.Invoke(ctx, "/azuretts.v1.TextToSpeech/ListVoices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TextToSpeechServer is the server API for TextToSpeech service.
+// All implementations must embed UnimplementedTextToSpeechServer
+// for forward compatibility
+type TextToSpeechServer interface {
+	// Synthesize returns the complete audio of a request.
+	Synthesize(context.Context, *SynthesizeRequest) (*SynthesizeResponse, error)
+	// SynthesizeStream streams the audio of a request as it renders, interleaved with the word boundaries, sentence
+	// boundaries, bookmarks and visemes reported for it.
+	SynthesizeStream(*SynthesizeRequest, TextToSpeech_SynthesizeStreamServer) error
+	// ListVoices lists the voices of the service.
+	ListVoices(context.Context, *ListVoicesRequest) (*ListVoicesResponse, error)
+	mustEmbedUnimplementedTextToSpeechServer()
+}
+
+// UnimplementedTextToSpeechServer must be embedded to have forward compatible implementations.
+type UnimplementedTextToSpeechServer struct {
+}
+
+func (UnimplementedTextToSpeechServer) Synthesize(context.Context, *SynthesizeRequest) (*SynthesizeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Synthesize not implemented")
+}
+func (UnimplementedTextToSpeechServer) SynthesizeStream(*SynthesizeRequest, TextToSpeech_SynthesizeStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method SynthesizeStream not implemented")
+}
+func (UnimplementedTextToSpeechServer) ListVoices(context.Context, *ListVoicesRequest) (*ListVoicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVoices not implemented")
+}
+func (UnimplementedTextToSpeechServer) mustEmbedUnimplementedTextToSpeechServer() {}
+
+// UnsafeTextToSpeechServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TextToSpeechServer will
+// result in compilation errors.
+type UnsafeTextToSpeechServer interface {
+	mustEmbedUnimplementedTextToSpeechServer()
+}
+
+func RegisterTextToSpeechServer(s grpc.ServiceRegistrar, srv TextToSpeechServer) {
+	s.RegisterService(&TextToSpeech_ServiceDesc, srv)
+}
+
+func _TextToSpeech_Synthesize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SynthesizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TextToSpeechServer).Synthesize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/azuretts.v1.TextToSpeech/Synthesize",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TextToSpeechServer).Synthesize(ctx, req.(*SynthesizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TextToSpeech_SynthesizeStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SynthesizeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TextToSpeechServer).SynthesizeStream(m, &textToSpeechSynthesizeStreamServer{stream})
+}
+
+type TextToSpeech_SynthesizeStreamServer interface {
+	Send(*SynthesizeStreamResponse) error
+	grpc.ServerStream
+}
+
+type textToSpeechSynthesizeStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *textToSpeechSynthesizeStreamServer) Send(m *SynthesizeStreamResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TextToSpeech_ListVoices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVoicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TextToSpeechServer).ListVoices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/azuretts.v1.TextToSpeech/ListVoices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TextToSpeechServer).ListVoices(ctx, req.(*ListVoicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TextToSpeech_ServiceDesc is the grpc.ServiceDesc for TextToSpeech service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TextToSpeech_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "azuretts.v1.TextToSpeech",
+	HandlerType: (*TextToSpeechServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Synthesize",
+			Handler:    _TextToSpeech_Synthesize_Handler,
+		},
+		{
+			MethodName: "ListVoices",
+			Handler:    _TextToSpeech_ListVoices_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SynthesizeStream",
+			Handler:       _TextToSpeech_SynthesizeStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "tts.proto",
+}
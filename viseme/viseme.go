@@ -0,0 +1,176 @@
+// Package viseme prepares viseme events for lip-sync animation. Viseme IDs are always reported by the WebSocket
+// protocol, see tts.Event; SSML rewritten with Request additionally asks the service for animation data, which
+// for FacialExpression is a sequence of blend shape frames at 60 frames per second. Both can be resampled to the
+// frame rate of an animation.
+package viseme
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+)
+
+// Type selects the animation data requested with the visemes.
+type Type string
+
+// Animation types supported by the service.
+const (
+	FacialExpression Type = "FacialExpression" // blend shape frames, see Animation
+	RedLipsFront     Type = "redlips_front"    // SVG images of a mouth
+)
+
+// FrameRate is the rate of the blend shape frames reported by the service.
+const FrameRate = 60
+
+// msttsNamespace is the namespace of the Microsoft SSML extensions.
+const msttsNamespace = "http://www.w3.org/2001/mstts"
+
+var (
+	speakTag = regexp.MustCompile(`<speak\b[^>]*>`)
+	voiceTag = regexp.MustCompile(`<voice\b[^>]*>`)
+)
+
+// Request rewrites an SSML document to ask for animation data of type t with the visemes of every voice, declaring
+// the mstts namespace on the speak element where needed.
+func Request(ssml string, t Type) (string, error) {
+	speak := speakTag.FindStringIndex(ssml)
+	if speak == nil {
+		return "", fmt.Errorf("no <speak> element")
+	}
+	if !voiceTag.MatchString(ssml) {
+		return "", fmt.Errorf("no <voice> element")
+	}
+	ssml = voiceTag.ReplaceAllStringFunc(ssml, func(tag string) string {
+		return tag + fmt.Sprintf(`<mstts:viseme type="%s"/>`, t)
+	})
+	if tag := ssml[speak[0]:speak[1]]; !strings.Contains(tag, "xmlns:mstts") {
+		at := speak[1] - 1
+		if strings.HasSuffix(tag, "/>") {
+			at--
+		}
+		ssml = ssml[:at] + fmt.Sprintf(` xmlns:mstts="%s"`, msttsNamespace) + ssml[at:]
+	}
+	return ssml, nil
+}
+
+// Viseme is a mouth position reached at an offset into the audio.
+type Viseme struct {
+	ID     int
+	Offset time.Duration
+}
+
+// Animation holds the blend shape frames of a FacialExpression animation, at FrameRate frames per second from
+// the start of the audio. Each frame holds the weights of the blend shapes, from 0 to 1, in the order documented
+// by the service.
+type Animation struct {
+	Frames [][]float64
+}
+
+// animationChunk is the JSON carried by the viseme events of a FacialExpression animation.
+type animationChunk struct {
+	FrameIndex  int
+	BlendShapes [][]float64
+}
+
+// Collect gathers the visemes and the FacialExpression animation of events. Animation chunks are placed by their
+// frame index, so events may arrive in any order. Events other than visemes are ignored.
+func Collect(events []tts.Event) ([]Viseme, *Animation, error) {
+	var visemes []Viseme
+	a := &Animation{}
+	for _, e := range events {
+		if e.Type != tts.EventViseme {
+			continue
+		}
+		visemes = append(visemes, Viseme{ID: e.VisemeID, Offset: e.Offset})
+		if e.Animation == "" {
+			continue
+		}
+		var chunk animationChunk
+		if err := json.Unmarshal([]byte(e.Animation), &chunk); err != nil {
+			// SVG animations are not JSON and are left to the caller.
+			if strings.HasPrefix(strings.TrimSpace(e.Animation), "<") {
+				continue
+			}
+			return nil, nil, fmt.Errorf("invalid viseme animation, %v", err)
+		}
+		if chunk.FrameIndex < 0 {
+			return nil, nil, fmt.Errorf("invalid viseme animation frame index %d", chunk.FrameIndex)
+		}
+		if end := chunk.FrameIndex + len(chunk.BlendShapes); end > len(a.Frames) {
+			a.Frames = append(a.Frames, make([][]float64, end-len(a.Frames))...)
+		}
+		copy(a.Frames[chunk.FrameIndex:], chunk.BlendShapes)
+	}
+	sort.SliceStable(visemes, func(i, j int) bool { return visemes[i].Offset < visemes[j].Offset })
+	return visemes, a, nil
+}
+
+// frames returns the number of frames at fps covering duration.
+func frames(duration time.Duration, fps float64) int {
+	return int(math.Ceil(duration.Seconds() * fps))
+}
+
+// Track returns the viseme ID shown in every frame of an animation at fps frames per second lasting duration,
+// typically the duration of the audio: the last viseme reached by the start of the frame, or 0 (silence) before
+// the first.
+func Track(visemes []Viseme, fps float64, duration time.Duration) []int {
+	ids := make([]int, frames(duration, fps))
+	next := 0
+	id := 0
+	for f := range ids {
+		t := time.Duration(float64(f) / fps * float64(time.Second))
+		for next < len(visemes) && visemes[next].Offset <= t {
+			id = visemes[next].ID
+			next++
+		}
+		ids[f] = id
+	}
+	return ids
+}
+
+// Resample returns the frames of the animation at fps frames per second for an animation lasting duration,
+// interpolating linearly between the frames reported by the service. Frames missing from the animation are
+// interpolated over, and the last frame is held to the end.
+func (a *Animation) Resample(fps float64, duration time.Duration) [][]float64 {
+	// the frames actually reported, in order.
+	var index []int
+	for i, f := range a.Frames {
+		if f != nil {
+			index = append(index, i)
+		}
+	}
+	out := make([][]float64, frames(duration, fps))
+	if len(index) == 0 {
+		return out
+	}
+	k := 0
+	for f := range out {
+		pos := float64(f) / fps * FrameRate
+		for k+1 < len(index) && float64(index[k+1]) <= pos {
+			k++
+		}
+		from := a.Frames[index[k]]
+		if k+1 == len(index) || pos <= float64(index[k]) {
+			out[f] = append([]float64(nil), from...)
+			continue
+		}
+		to := a.Frames[index[k+1]]
+		w := (pos - float64(index[k])) / float64(index[k+1]-index[k])
+		frame := make([]float64, len(from))
+		for i := range frame {
+			v := from[i]
+			if i < len(to) {
+				v += (to[i] - v) * w
+			}
+			frame[i] = v
+		}
+		out[f] = frame
+	}
+	return out
+}
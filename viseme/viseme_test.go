@@ -0,0 +1,47 @@
+package viseme
+
+import (
+	"testing"
+	"time"
+
+	tts "github.com/linexjlin/azuretexttospeech"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequest(t *testing.T) {
+	ssml, err := Request(`<speak version='1.0' xml:lang='en-US'><voice name='a'>Hi</voice><voice name="b">there</voice></speak>`, FacialExpression)
+	assert.NoError(t, err)
+	assert.Equal(t, `<speak version='1.0' xml:lang='en-US' xmlns:mstts="http://www.w3.org/2001/mstts"><voice name='a'><mstts:viseme type="FacialExpression"/>Hi</voice><voice name="b"><mstts:viseme type="FacialExpression"/>there</voice></speak>`, ssml)
+
+	ssml, err = Request(`<speak xmlns:mstts="http://www.w3.org/2001/mstts"><voice name='a'>Hi</voice></speak>`, RedLipsFront)
+	assert.NoError(t, err)
+	assert.Equal(t, `<speak xmlns:mstts="http://www.w3.org/2001/mstts"><voice name='a'><mstts:viseme type="redlips_front"/>Hi</voice></speak>`, ssml)
+
+	_, err = Request(`<speak>Hi</speak>`, FacialExpression)
+	assert.Error(t, err)
+}
+
+func TestCollect(t *testing.T) {
+	events := []tts.Event{
+		{Type: tts.EventWordBoundary, Offset: 0, Text: "Hi"},
+		{Type: tts.EventViseme, Offset: 100 * time.Millisecond, VisemeID: 12, Animation: `{"FrameIndex":2,"BlendShapes":[[0.5,1],[1,1]]}`},
+		{Type: tts.EventViseme, Offset: 50 * time.Millisecond, VisemeID: 0, Animation: `{"FrameIndex":0,"BlendShapes":[[0,0]]}`},
+		{Type: tts.EventViseme, Offset: 150 * time.Millisecond, VisemeID: 19},
+	}
+	visemes, a, err := Collect(events)
+	assert.NoError(t, err)
+	assert.Equal(t, []Viseme{{0, 50 * time.Millisecond}, {12, 100 * time.Millisecond}, {19, 150 * time.Millisecond}}, visemes)
+	assert.Equal(t, [][]float64{{0, 0}, nil, {0.5, 1}, {1, 1}}, a.Frames)
+
+	// 120 fps over 50 ms: frame 1 falls on the missing source frame, frames beyond the last hold it.
+	assert.Equal(t, [][]float64{{0, 0}, {0.125, 0.25}, {0.25, 0.5}, {0.375, 0.75}, {0.5, 1}, {0.75, 1}}, a.Resample(120, 50*time.Millisecond))
+	assert.Equal(t, [][]float64{{0, 0}, {0.5, 1}, {1, 1}, {1, 1}}, a.Resample(30, 130*time.Millisecond))
+
+	assert.Equal(t, []int{0, 0, 0, 0, 12, 12, 19}, Track(visemes, 40, 155*time.Millisecond))
+
+	_, _, err = Collect([]tts.Event{{Type: tts.EventViseme, Animation: `{"FrameIndex":`}})
+	assert.Error(t, err)
+	_, a, err = Collect([]tts.Event{{Type: tts.EventViseme, Animation: `<svg/>`}})
+	assert.NoError(t, err)
+	assert.Empty(t, a.Frames)
+}
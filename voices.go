@@ -1,14 +1,10 @@
 package azuretexttospeech
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-
-	"gopkg.in/h2non/gentleman.v2"
-	"gopkg.in/h2non/gentleman.v2/plugins/proxy"
-	gtls "gopkg.in/h2non/gentleman.v2/plugins/tls"
 )
 
 // voiceListAPI is the source for supported voice list to region mapping
@@ -27,7 +23,8 @@ type ExtendedPropertyMap struct {
 	IsHighQuality48K string `json:"IsHighQuality48K,omitempty"`
 }
 
-type regionVoiceListResponse struct {
+// Voice describes a single entry of the voice list API.
+type Voice struct {
 	Name                string   `json:"Name"`
 	ShortName           string   `json:"ShortName"`
 	DisplayName         string   `json:"DisplayName"`
@@ -51,68 +48,66 @@ type supportedVoices struct {
 
 type RegionVoiceMap map[supportedVoices]string
 
-func (az *AzureCSTextToSpeech) buildVoiceToRegionMap() (RegionVoiceMap, error) {
-
-	v, err := az.fetchVoiceList()
+// VoicesWithContext returns the full catalog of voices available in the client's region.
+func (az *AzureCSTextToSpeech) VoicesWithContext(ctx context.Context) ([]Voice, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, az.voiceServiceListURL, nil)
 	if err != nil {
 		return nil, err
 	}
+	request.Header.Set("Authorization", "Bearer "+az.token())
 
-	m := make(map[supportedVoices]string)
-	for _, x := range v {
-		if x.VoiceType == "Neural" {
-			m[supportedVoices{Gender: x.Gender, Locale: x.Locale}] = x.ShortName
-		}
-	}
-	return m, err
-}
-
-func (az *AzureCSTextToSpeech) fetchVoiceList() ([]regionVoiceListResponse, error) {
-
-	// Create a new client
-	cli := gentleman.New()
-
-	// Define base URL
-	cli.URL(az.voiceServiceListURL)
-
-	if az.HttpProxy != "" {
-
-		servers := map[string]string{"http": az.HttpProxy, "https": az.HttpProxy}
-		cli.Use(proxy.Set(servers))
-	}
-
-	cli.Use(gtls.Config(&tls.Config{InsecureSkipVerify: true}))
-
-	// Create a new request based on the current client
-	req := cli.Request()
-
-	// Set a new header field
-	req.SetHeader("Authorization", "Bearer "+az.accessToken)
-
-	// Perform the request
-	res, err := req.Send()
+	client, err := az.httpClient()
 	if err != nil {
 		return nil, err
 	}
-	if !res.Ok {
+	res, err := client.Do(request)
+	if err != nil {
 		return nil, err
 	}
+	defer res.Body.Close()
 
 	switch res.StatusCode {
 	case http.StatusOK:
-		var r []regionVoiceListResponse
-		if err := json.Unmarshal(res.Bytes(), &r); err != nil {
+		var r []Voice
+		if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
 			return nil, fmt.Errorf("unable to decode voice list response body, %v", err)
 		}
 		return r, nil
 	case http.StatusBadRequest:
-		return nil, fmt.Errorf("%d - A required parameter is missing, empty, or null. Or, the value passed to either a required or optional parameter is invalid. A common issue is a header that is too long", res.StatusCode)
+		return nil, &StatusError{res.StatusCode, "A required parameter is missing, empty, or null. Or, the value passed to either a required or optional parameter is invalid. A common issue is a header that is too long"}
 	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("%d - The request is not authorized. Check to make sure your subscription key or token is valid and in the correct region", res.StatusCode)
+		return nil, &StatusError{res.StatusCode, "The request is not authorized. Check to make sure your subscription key or token is valid and in the correct region"}
 	case http.StatusTooManyRequests:
-		return nil, fmt.Errorf("%d - You have exceeded the quota or rate of requests allowed for your subscription", res.StatusCode)
+		return nil, &StatusError{res.StatusCode, "You have exceeded the quota or rate of requests allowed for your subscription"}
 	case http.StatusBadGateway:
-		return nil, fmt.Errorf("%d - Network or server-side issue. May also indicate invalid headers", res.StatusCode)
+		return nil, &StatusError{res.StatusCode, "Network or server-side issue. May also indicate invalid headers"}
 	}
-	return nil, fmt.Errorf("%d - unexpected response code from voice list API", res.StatusCode)
+	return nil, &StatusError{res.StatusCode, "unexpected response code from voice list API"}
+}
+
+// Voices directs to VoicesWithContext using a timeout of synthesizeActionTimeout.
+func (az *AzureCSTextToSpeech) Voices() ([]Voice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), synthesizeActionTimeout)
+	defer cancel()
+	return az.VoicesWithContext(ctx)
+}
+
+func (az *AzureCSTextToSpeech) fetchVoiceList() ([]Voice, error) {
+	return az.Voices()
+}
+
+func (az *AzureCSTextToSpeech) buildVoiceToRegionMap() (RegionVoiceMap, error) {
+
+	v, err := az.fetchVoiceList()
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[supportedVoices]string)
+	for _, x := range v {
+		if x.VoiceType == "Neural" {
+			m[supportedVoices{Gender: x.Gender, Locale: x.Locale}] = x.ShortName
+		}
+	}
+	return m, err
 }
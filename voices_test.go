@@ -29,6 +29,18 @@ func TestFetchVoiceList(t *testing.T) {
 
 }
 
+func TestVoicesUnauthorized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	az := &AzureCSTextToSpeech{accessToken: "SYS49152", voiceServiceListURL: ts.URL}
+	vl, err := az.Voices()
+	assert.Error(t, err, "a 401 response should be reported")
+	assert.Nil(t, vl)
+}
+
 // sample response taken from https://docs.microsoft.com/en-us/azure/cognitive-services/speech-service/rest-text-to-speech#sample-response
 const voiceListAPIGoodResponse string = `[
     {
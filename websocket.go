@@ -0,0 +1,311 @@
+package azuretexttospeech
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// webSocketAPI is the endpoint of the WebSocket protocol used by the Speech SDKs. Unlike the REST endpoint it
+// reports the timing of words, sentences, bookmarks and visemes alongside the audio.
+const webSocketAPI = "wss://%s.tts.speech.microsoft.com/cognitiveservices/websocket/v1"
+
+// webSocketHandshakeTimeout bounds the opening handshake of a WebSocket connection.
+const webSocketHandshakeTimeout = time.Second * 15
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+// Event types reported by the WebSocket protocol.
+const (
+	EventWordBoundary     EventType = "WordBoundary"
+	EventSentenceBoundary EventType = "SentenceBoundary"
+	EventBookmark         EventType = "Bookmark"
+	EventViseme           EventType = "Viseme"
+)
+
+// Event is a piece of timing metadata reported while audio is rendered. Offsets are measured from the start of the
+// audio.
+type Event struct {
+	Type     EventType
+	Offset   time.Duration
+	Duration time.Duration // boundary events only
+	Text     string        // the word, punctuation or sentence of a boundary event
+	Boundary string        // WordBoundary, PunctuationBoundary or SentenceBoundary
+	Bookmark string        // name of the <bookmark> element reached
+	VisemeID int           // mouth position, see https://learn.microsoft.com/azure/cognitive-services/speech-service/how-to-speech-synthesis-viseme
+	// Animation holds the JSON blend shapes or SVG of a viseme event when the SSML requests them with
+	// <mstts:viseme type="..."/>.
+	Animation string
+}
+
+// SynthesizeEventsWithContext renders an SSML document over the WebSocket protocol. The audio is written to w as it
+// arrives, and onEvent, when not nil, is called with every word boundary, sentence boundary, bookmark and viseme
+// in the order the service reports them, from the goroutine running the request. Results are not cached.
+func (az *AzureCSTextToSpeech) SynthesizeEventsWithContext(ctx context.Context, ssml string, audioOutput AudioOutput, w io.Writer, onEvent func(Event)) error {
+	connectionID := newRequestID()
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+az.token())
+	header.Set("X-ConnectionId", connectionID)
+	header.Set("User-Agent", "azuretts")
+
+	dialer, err := az.webSocketDialer()
+	if err != nil {
+		return err
+	}
+	conn, response, err := dialer.DialContext(ctx, az.webSocketURL+"?X-ConnectionId="+connectionID, header)
+	if err != nil {
+		if response != nil && response.StatusCode != http.StatusSwitchingProtocols {
+			return synthesizeError(response.StatusCode)
+		}
+		return err
+	}
+	defer conn.Close()
+
+	// unblock reads once ctx is done.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	requestID := newRequestID()
+	config, _ := json.Marshal(synthesisContext(audioOutput))
+	for _, m := range [][]byte{
+		textMessage("speech.config", "", "application/json", `{"context":{"system":{"name":"SpeechSDK","version":"1.0.0","build":"Go","lang":"Go"}}}`),
+		textMessage("synthesis.context", requestID, "application/json", string(config)),
+		textMessage("ssml", requestID, "application/ssml+xml", ssml),
+	} {
+		if err := conn.WriteMessage(websocket.TextMessage, m); err != nil {
+			return webSocketError(ctx, err)
+		}
+	}
+
+	for {
+		kind, message, err := conn.ReadMessage()
+		if err != nil {
+			return webSocketError(ctx, err)
+		}
+		headers, body, err := parseMessage(kind, message)
+		if err != nil {
+			return err
+		}
+		switch headers["path"] {
+		case "audio":
+			if len(body) == 0 {
+				continue
+			}
+			if _, err := w.Write(body); err != nil {
+				return err
+			}
+		case "audio.metadata":
+			events, err := parseMetadata(body)
+			if err != nil {
+				return err
+			}
+			for _, e := range events {
+				if onEvent != nil {
+					onEvent(e)
+				}
+			}
+		case "turn.end":
+			return nil
+		}
+	}
+}
+
+// SynthesizeSSMLWithEvents renders an SSML document over the WebSocket protocol and returns the audio together with
+// the events reported for it, using a timeout of synthesizeActionTimeout. See SynthesizeEventsWithContext.
+func (az *AzureCSTextToSpeech) SynthesizeSSMLWithEvents(ssml string, audioOutput AudioOutput) ([]byte, []Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), synthesizeActionTimeout)
+	defer cancel()
+	var audio bytes.Buffer
+	var events []Event
+	err := az.SynthesizeEventsWithContext(ctx, ssml, audioOutput, &audio, func(e Event) {
+		events = append(events, e)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return audio.Bytes(), events, nil
+}
+
+// webSocketDialer returns the dialer for the WebSocket endpoint. It verifies the certificate of the service and, like
+// httpClient, connects through HttpProxy when set and never through the proxy of the environment.
+func (az *AzureCSTextToSpeech) webSocketDialer() (*websocket.Dialer, error) {
+	proxy, err := az.proxy()
+	if err != nil {
+		return nil, err
+	}
+	return &websocket.Dialer{
+		HandshakeTimeout: webSocketHandshakeTimeout,
+		Proxy:            proxy,
+	}, nil
+}
+
+// webSocketError reports a failed read or write, preferring the reason the service gave for closing the connection
+// and the cancellation of ctx, which closes the connection too.
+func webSocketError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if e, ok := err.(*websocket.CloseError); ok {
+		return &CloseError{e.Code, e.Text}
+	}
+	return err
+}
+
+// CloseError is returned when the service closes the WebSocket connection of a request, with code 1007 for SSML it
+// rejects.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("%d - %s", e.Code, e.Text)
+}
+
+// Temporary reports whether the request may succeed when retried: the service failed, is restarting or overloaded.
+func (e *CloseError) Temporary() bool {
+	return e.Code == websocket.CloseInternalServerErr || e.Code == websocket.CloseServiceRestart || e.Code == websocket.CloseTryAgainLater
+}
+
+// synthesisContext returns the synthesis.context message body, which selects the output format and enables the
+// events.
+func synthesisContext(audioOutput AudioOutput) interface{} {
+	type metadataOptions struct {
+		BookmarkEnabled            bool `json:"bookmarkEnabled"`
+		PunctuationBoundaryEnabled bool `json:"punctuationBoundaryEnabled"`
+		SentenceBoundaryEnabled    bool `json:"sentenceBoundaryEnabled"`
+		WordBoundaryEnabled        bool `json:"wordBoundaryEnabled"`
+		VisemeEnabled              bool `json:"visemeEnabled"`
+	}
+	type audio struct {
+		MetadataOptions metadataOptions `json:"metadataOptions"`
+		OutputFormat    string          `json:"outputFormat"`
+	}
+	type language struct {
+		AutoDetection bool `json:"autoDetection"`
+	}
+	type synthesis struct {
+		Audio    audio    `json:"audio"`
+		Language language `json:"language"`
+	}
+	return struct {
+		Synthesis synthesis `json:"synthesis"`
+	}{synthesis{
+		Audio: audio{
+			MetadataOptions: metadataOptions{BookmarkEnabled: true, PunctuationBoundaryEnabled: true, SentenceBoundaryEnabled: true, WordBoundaryEnabled: true, VisemeEnabled: true},
+			OutputFormat:    audioOutput.String(),
+		},
+	}}
+}
+
+// textMessage encodes a text message of the protocol: HTTP style headers, an empty line and the body.
+func textMessage(path, requestID, contentType, body string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "X-Timestamp:%s\r\n", time.Now().UTC().Format("2006-01-02T15:04:05.000Z"))
+	fmt.Fprintf(&b, "Path:%s\r\n", path)
+	if requestID != "" {
+		fmt.Fprintf(&b, "X-RequestId:%s\r\n", requestID)
+	}
+	fmt.Fprintf(&b, "Content-Type:%s\r\n\r\n", contentType)
+	b.WriteString(body)
+	return []byte(b.String())
+}
+
+// parseMessage splits a text or binary message of the protocol into its headers, keyed in lower case, and body.
+func parseMessage(kind int, message []byte) (map[string]string, []byte, error) {
+	var head, body []byte
+	switch kind {
+	case websocket.BinaryMessage:
+		if len(message) < 2 {
+			return nil, nil, fmt.Errorf("truncated binary message")
+		}
+		n := int(binary.BigEndian.Uint16(message))
+		if len(message) < 2+n {
+			return nil, nil, fmt.Errorf("truncated binary message")
+		}
+		head, body = message[2:2+n], message[2+n:]
+	default:
+		i := bytes.Index(message, []byte("\r\n\r\n"))
+		if i < 0 {
+			head = message
+		} else {
+			head, body = message[:i], message[i+4:]
+		}
+	}
+	headers := map[string]string{}
+	for _, line := range strings.Split(string(head), "\r\n") {
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			headers[strings.ToLower(strings.TrimSpace(line[:i]))] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	return headers, body, nil
+}
+
+// parseMetadata decodes the events of an audio.metadata message. Offsets and durations are given in ticks of
+// 100 ns. Metadata of other types, such as SessionEnd, is skipped.
+func parseMetadata(body []byte) ([]Event, error) {
+	var m struct {
+		Metadata []struct {
+			Type string
+			Data struct {
+				Offset   int64
+				Duration int64
+				Text     struct {
+					Text         string
+					BoundaryType string
+				}
+				Bookmark       string
+				VisemeID       int `json:"VisemeId"`
+				AnimationChunk string
+			}
+		}
+	}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("invalid audio.metadata message, %v", err)
+	}
+	var events []Event
+	for _, md := range m.Metadata {
+		t := EventType(md.Type)
+		switch t {
+		case EventWordBoundary, EventSentenceBoundary, EventBookmark, EventViseme:
+		default:
+			continue
+		}
+		events = append(events, Event{
+			Type:      t,
+			Offset:    time.Duration(md.Data.Offset) * 100,
+			Duration:  time.Duration(md.Data.Duration) * 100,
+			Text:      md.Data.Text.Text,
+			Boundary:  md.Data.Text.BoundaryType,
+			Bookmark:  md.Data.Bookmark,
+			VisemeID:  md.Data.VisemeID,
+			Animation: md.Data.AnimationChunk,
+		})
+	}
+	return events, nil
+}
+
+// newRequestID returns a random identifier in the form the service expects: 32 hex digits.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,140 @@
+package azuretexttospeech
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// webSocketStandIn serves the WebSocket protocol: it checks the three request messages and replies with two audio
+// chunks, the events given and turn.end.
+func webSocketStandIn(t *testing.T, metadata string) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer SYS49152" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var requestID string
+		for _, path := range []string{"speech.config", "synthesis.context", "ssml"} {
+			kind, m, err := conn.ReadMessage()
+			if !assert.NoError(t, err) {
+				return
+			}
+			headers, body, err := parseMessage(kind, m)
+			assert.NoError(t, err)
+			assert.Equal(t, path, headers["path"])
+			switch path {
+			case "synthesis.context":
+				var c struct{ Synthesis struct{ Audio struct{ OutputFormat string } } }
+				assert.NoError(t, json.Unmarshal(body, &c))
+				assert.Equal(t, "audio-16khz-32kbitrate-mono-mp3", c.Synthesis.Audio.OutputFormat)
+				requestID = headers["x-requestid"]
+			case "ssml":
+				assert.Equal(t, requestID, headers["x-requestid"])
+				if string(body) == "<speak>fail</speak>" {
+					conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(1007, "Unsupported SSML"))
+					return
+				}
+			}
+		}
+
+		reply := func(kind int, m []byte) {
+			assert.NoError(t, conn.WriteMessage(kind, m))
+		}
+		reply(websocket.TextMessage, textMessage("turn.start", requestID, "application/json", "{}"))
+		reply(websocket.BinaryMessage, binaryMessage("X-RequestId:"+requestID+"\r\nPath:audio\r\nContent-Type:audio/mpeg", []byte("SYS")))
+		reply(websocket.TextMessage, textMessage("audio.metadata", requestID, "application/json", metadata))
+		reply(websocket.BinaryMessage, binaryMessage("X-RequestId:"+requestID+"\r\nPath:audio\r\nContent-Type:audio/mpeg", []byte("4096")))
+		reply(websocket.BinaryMessage, binaryMessage("X-RequestId:"+requestID+"\r\nPath:audio", nil))
+		reply(websocket.TextMessage, textMessage("turn.end", requestID, "application/json", "{}"))
+	}))
+}
+
+// binaryMessage encodes a binary message of the protocol: the length of the headers as a big endian uint16, the
+// headers and the body.
+func binaryMessage(headers string, body []byte) []byte {
+	b := make([]byte, 2, 2+len(headers)+len(body))
+	binary.BigEndian.PutUint16(b, uint16(len(headers)))
+	return append(append(b, headers...), body...)
+}
+
+func TestSynthesizeEvents(t *testing.T) {
+	metadata := `{"Metadata":[
+		{"Type":"WordBoundary","Data":{"Offset":500000,"Duration":3250000,"text":{"Text":"Hello","Length":5,"BoundaryType":"WordBoundary"}}},
+		{"Type":"Bookmark","Data":{"Offset":3750000,"Bookmark":"mark1"}},
+		{"Type":"Viseme","Data":{"Offset":4000000,"VisemeId":21,"IsLastAnimation":false,"AnimationChunk":""}},
+		{"Type":"SessionEnd","Data":{"Offset":9000000}}]}`
+	ts := webSocketStandIn(t, metadata)
+	defer ts.Close()
+	az := &AzureCSTextToSpeech{accessToken: "SYS49152", webSocketURL: "ws" + strings.TrimPrefix(ts.URL, "http")}
+
+	var audio bytes.Buffer
+	var events []Event
+	err := az.SynthesizeEventsWithContext(context.Background(), "<speak/>", AUDIO16khz32kbitrateMonoMP3, &audio, func(e Event) {
+		events = append(events, e)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "SYS4096", audio.String())
+	assert.Equal(t, []Event{
+		{Type: EventWordBoundary, Offset: 50 * time.Millisecond, Duration: 325 * time.Millisecond, Text: "Hello", Boundary: "WordBoundary"},
+		{Type: EventBookmark, Offset: 375 * time.Millisecond, Bookmark: "mark1"},
+		{Type: EventViseme, Offset: 400 * time.Millisecond, VisemeID: 21},
+	}, events)
+
+	b, events, err := az.SynthesizeSSMLWithEvents("<speak/>", AUDIO16khz32kbitrateMonoMP3)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("SYS4096"), b)
+	assert.Len(t, events, 3)
+
+	_, _, err = az.SynthesizeSSMLWithEvents("<speak>fail</speak>", AUDIO16khz32kbitrateMonoMP3)
+	assert.EqualError(t, err, "1007 - Unsupported SSML")
+	var closed *CloseError
+	if assert.True(t, errors.As(err, &closed)) {
+		assert.Equal(t, websocket.CloseInvalidFramePayloadData, closed.Code)
+		assert.False(t, Temporary(err))
+	}
+
+	az.accessToken = "expired"
+	_, _, err = az.SynthesizeSSMLWithEvents("<speak/>", AUDIO16khz32kbitrateMonoMP3)
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "401 - "))
+}
+
+func TestWebSocketDialer(t *testing.T) {
+	az := &AzureCSTextToSpeech{}
+	d, err := az.webSocketDialer()
+	if assert.NoError(t, err) {
+		assert.Nil(t, d.Proxy, "the proxy of the environment is not used, like for REST requests")
+		assert.Equal(t, webSocketHandshakeTimeout, d.HandshakeTimeout)
+		assert.Nil(t, d.TLSClientConfig, "certificates are verified")
+	}
+
+	az.HttpProxy = "http://proxy.local:3128"
+	d, err = az.webSocketDialer()
+	if assert.NoError(t, err) && assert.NotNil(t, d.Proxy) {
+		u, err := d.Proxy(httptest.NewRequest(http.MethodGet, "https://westus.tts.speech.microsoft.com/", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, "http://proxy.local:3128", u.String())
+	}
+
+	az.HttpProxy = "://bad"
+	_, err = az.webSocketDialer()
+	assert.Error(t, err)
+}